@@ -0,0 +1,195 @@
+package annotation
+
+import (
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-genome"
+)
+
+// Where a region is relative to a gene, in order of priority when it
+// could be more than one
+const (
+	LABEL_PROMOTER   = "promoter"
+	LABEL_EXONIC     = "exonic"
+	LABEL_INTRONIC   = "intronic"
+	LABEL_DOWNSTREAM = "downstream"
+	LABEL_INTERGENIC = "intergenic"
+)
+
+var LABELS = []string{LABEL_PROMOTER,
+	LABEL_EXONIC,
+	LABEL_INTRONIC,
+	LABEL_DOWNSTREAM,
+	LABEL_INTERGENIC}
+
+// Regions within this many bases after the end of a gene are
+// downstream of it
+const DEFAULT_DOWNSTREAM_DIST uint = 3000
+
+// Labels regions by the parts of genes they overlap. Every route
+// that labels regions uses one so that the same regions get the
+// same labels whichever way they are annotated.
+type Classifier struct {
+	tssRegion  *dna.TSSRegion
+	downstream uint
+}
+
+func NewClassifier(tssRegion *dna.TSSRegion, downstream uint) *Classifier {
+	return &Classifier{tssRegion: tssRegion, downstream: downstream}
+}
+
+// Position of a label in LABELS, which is its priority
+func Rank(label string) int {
+	for i, name := range LABELS {
+		if name == label {
+			return i
+		}
+	}
+
+	return len(LABELS) - 1
+}
+
+// the transcripts of a gene, or the gene itself if it has none
+func transcripts(feature *genome.GenomicFeature) []*genome.GenomicFeature {
+	ret := make([]*genome.GenomicFeature, 0, len(feature.Children))
+
+	for _, child := range feature.Children {
+		if child.Level == genome.LEVEL_TRANSCRIPT {
+			ret = append(ret, child)
+		}
+	}
+
+	if len(ret) == 0 {
+		ret = append(ret, feature)
+	}
+
+	return ret
+}
+
+// TSS of a feature on its strand
+func tss(feature *genome.GenomicFeature) uint {
+	if feature.Strand == "-" {
+		return feature.Location.End
+	}
+
+	return feature.Location.Start
+}
+
+// Promoter of a feature on its strand
+func (classifier *Classifier) promoter(feature *genome.GenomicFeature) (uint, uint) {
+	tss := tss(feature)
+	up := classifier.tssRegion.Offset5P()
+	down := classifier.tssRegion.Offset3P()
+
+	if feature.Strand == "-" {
+		up, down = down, up
+	}
+
+	start := uint(1)
+
+	if tss > up {
+		start = tss - up
+	}
+
+	return start, tss + down
+}
+
+// Region after the end of a feature on its strand
+func (classifier *Classifier) downstreamOf(feature *genome.GenomicFeature) (uint, uint) {
+	if feature.Strand == "-" {
+		start := uint(1)
+
+		if feature.Location.Start > classifier.downstream {
+			start = feature.Location.Start - classifier.downstream
+		}
+
+		return start, feature.Location.Start - 1
+	}
+
+	return feature.Location.End + 1, feature.Location.End + classifier.downstream
+}
+
+// Extent of a gene including the promoters and downstream regions of
+// its transcripts, outside of which it cannot label a region
+func (classifier *Classifier) Span(feature *genome.GenomicFeature) (uint, uint) {
+	start := feature.Location.Start
+	end := feature.Location.End
+
+	for _, transcript := range transcripts(feature) {
+		ps, pe := classifier.promoter(transcript)
+		ds, de := classifier.downstreamOf(transcript)
+
+		start = min(start, ps, ds, transcript.Location.Start)
+		end = max(end, pe, de, transcript.Location.End)
+	}
+
+	return start, end
+}
+
+// How far either side of a region genes must be fetched from so
+// that every gene that could label it is seen
+func (classifier *Classifier) Flank() uint {
+	return max(classifier.tssRegion.Offset5P(), classifier.tssRegion.Offset3P(), classifier.downstream)
+}
+
+func overlaps(location *dna.Location, start uint, end uint) bool {
+	return start <= end && location.Start <= end && location.End >= start
+}
+
+// Where a region is relative to a gene, using the highest priority
+// label of any of its transcripts. Transcripts without exons, or
+// genes without transcripts, are treated as one long intron.
+func (classifier *Classifier) Label(location *dna.Location, feature *genome.GenomicFeature) string {
+	ret := LABEL_INTERGENIC
+
+	if location.Chr != feature.Location.Chr {
+		return ret
+	}
+
+	for _, transcript := range transcripts(feature) {
+		label := LABEL_INTERGENIC
+
+		start, end := classifier.promoter(transcript)
+		ds, de := classifier.downstreamOf(transcript)
+
+		switch {
+		case overlaps(location, start, end):
+			return LABEL_PROMOTER
+		case overlaps(location, transcript.Location.Start, transcript.Location.End):
+			label = LABEL_INTRONIC
+
+			for _, exon := range transcript.Children {
+				if exon.Level == genome.LEVEL_EXON && overlaps(location, exon.Location.Start, exon.Location.End) {
+					label = LABEL_EXONIC
+					break
+				}
+			}
+		case classifier.downstream > 0 && overlaps(location, ds, de):
+			label = LABEL_DOWNSTREAM
+		}
+
+		if Rank(label) < Rank(ret) {
+			ret = label
+		}
+	}
+
+	return ret
+}
+
+// The highest priority label of a region given the genes it is in
+func RegionLabel(annotation *genome.GeneAnnotation) string {
+	ret := LABEL_INTERGENIC
+
+	if annotation.PromLabels == "" {
+		return ret
+	}
+
+	for _, label := range strings.Split(annotation.PromLabels, FEATURE_SEP) {
+		if Rank(label) < Rank(ret) {
+			ret = label
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,115 @@
+package annotation
+
+import (
+	"testing"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-genome"
+)
+
+// A is a + strand gene with one exon at its start, B a - strand gene
+// without transcripts far enough away that the closest genes of
+// regions between them are only found by growing the window
+var testGenes = []*genome.GenomicFeature{
+	{GeneId: "A", GeneSymbol: "A", Level: genome.LEVEL_GENE, Strand: "+",
+		Location: dna.NewLocation("chr1", 10000, 20000),
+		Children: []*genome.GenomicFeature{{Level: genome.LEVEL_TRANSCRIPT, Strand: "+",
+			Location: dna.NewLocation("chr1", 10000, 20000),
+			Children: []*genome.GenomicFeature{{Level: genome.LEVEL_EXON, Location: dna.NewLocation("chr1", 10000, 12000)}}}}},
+	{GeneId: "B", GeneSymbol: "B", Level: genome.LEVEL_GENE, Strand: "-",
+		Location: dna.NewLocation("chr1", 4990000, 5001000)},
+}
+
+func testFetch(location *dna.Location) ([]*genome.GenomicFeature, error) {
+	ret := make([]*genome.GenomicFeature, 0, len(testGenes))
+
+	for _, gene := range testGenes {
+		if gene.Location.Chr == location.Chr && gene.Location.Start <= location.End && gene.Location.End >= location.Start {
+			ret = append(ret, gene)
+		}
+	}
+
+	return ret, nil
+}
+
+func TestLabel(t *testing.T) {
+	classifier := NewClassifier(dna.NewTSSRegion(2000, 1000), DEFAULT_DOWNSTREAM_DIST)
+
+	tests := []struct {
+		name     string
+		location *dna.Location
+		gene     int
+		label    string
+	}{
+		{"upstream promoter", dna.NewLocation("chr1", 8000, 8000), 0, LABEL_PROMOTER},
+		{"promoter wins over exon", dna.NewLocation("chr1", 10500, 10600), 0, LABEL_PROMOTER},
+		{"exonic", dna.NewLocation("chr1", 11500, 11600), 0, LABEL_EXONIC},
+		{"overlapping an exon", dna.NewLocation("chr1", 11900, 13000), 0, LABEL_EXONIC},
+		{"intronic", dna.NewLocation("chr1", 15000, 15100), 0, LABEL_INTRONIC},
+		{"downstream", dna.NewLocation("chr1", 22000, 22100), 0, LABEL_DOWNSTREAM},
+		{"beyond downstream", dna.NewLocation("chr1", 23001, 23100), 0, LABEL_INTERGENIC},
+		{"before the promoter", dna.NewLocation("chr1", 7000, 7999), 0, LABEL_INTERGENIC},
+		{"other chromosome", dna.NewLocation("chr2", 15000, 15100), 0, LABEL_INTERGENIC},
+		{"minus strand promoter", dna.NewLocation("chr1", 5002500, 5002600), 1, LABEL_PROMOTER},
+		{"minus strand without exons", dna.NewLocation("chr1", 4995000, 4995100), 1, LABEL_INTRONIC},
+		{"minus strand downstream", dna.NewLocation("chr1", 4988000, 4988100), 1, LABEL_DOWNSTREAM},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			label := classifier.Label(test.location, testGenes[test.gene])
+
+			if label != test.label {
+				t.Errorf("label = %s, want %s", label, test.label)
+			}
+		})
+	}
+}
+
+// Sweeping and querying the gene db must annotate regions the same
+func TestAnnotators(t *testing.T) {
+	classifier := NewClassifier(dna.NewTSSRegion(2000, 1000), DEFAULT_DOWNSTREAM_DIST)
+
+	tests := []struct {
+		name     string
+		location *dna.Location
+		label    string
+		closest  []string
+	}{
+		{"in a gene", dna.NewLocation("chr1", 15000, 15100), LABEL_INTRONIC, []string{"A", "B"}},
+		{"between distant genes", dna.NewLocation("chr1", 4000000, 4000000), LABEL_INTERGENIC, []string{"B", "A"}},
+		{"downstream of a minus strand gene", dna.NewLocation("chr1", 4988000, 4988100), LABEL_DOWNSTREAM, []string{"B", "A"}},
+		{"chromosome without genes", dna.NewLocation("chr2", 100, 200), LABEL_INTERGENIC, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db := NewDbAnnotator(testFetch, classifier, 2)
+			sweeper := NewSweeper(testFetch, classifier, 2)
+
+			for _, annotate := range []func(*dna.Location) (*genome.GeneAnnotation, error){db.Annotate, sweeper.Annotate} {
+				annotation, err := annotate(test.location)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				label := RegionLabel(annotation)
+
+				if label != test.label {
+					t.Errorf("label = %s, want %s", label, test.label)
+				}
+
+				if len(annotation.ClosestGenes) != len(test.closest) {
+					t.Fatalf("got %d closest genes, want %d", len(annotation.ClosestGenes), len(test.closest))
+				}
+
+				for i, gene := range annotation.ClosestGenes {
+					if gene.GeneId != test.closest[i] {
+						t.Errorf("closest gene %d = %s, want %s", i, gene.GeneId, test.closest[i])
+					}
+				}
+			}
+		})
+	}
+}
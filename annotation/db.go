@@ -0,0 +1,70 @@
+package annotation
+
+import (
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-genome"
+)
+
+// Genes are first fetched this far either side of the middle of a
+// region, and the window grows until it holds the closest genes
+const INITIAL_WINDOW uint = 1000000
+
+// Annotates each region from the genes around it in the gene db, for
+// regions in any order. Regions are labelled in the same way as by a
+// Sweeper so the two give the same annotations.
+type DbAnnotator struct {
+	fetch      GeneFunc
+	classifier *Classifier
+	n          int
+}
+
+func NewDbAnnotator(fetch GeneFunc, classifier *Classifier, n uint16) *DbAnnotator {
+	return &DbAnnotator{fetch: fetch, classifier: classifier, n: int(n)}
+}
+
+func (annotator *DbAnnotator) Annotate(location *dna.Location) (*genome.GeneAnnotation, error) {
+	mid := (location.Start + location.End) / 2
+
+	window := max(INITIAL_WINDOW, location.End-location.Start+annotator.classifier.Flank())
+
+	for {
+		start := uint(1)
+
+		if mid > window {
+			start = mid - window
+		}
+
+		end := mid + window
+
+		features, err := annotator.fetch(dna.NewLocation(location.Chr, start, end))
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret := newChromGenes(annotator.classifier, location.Chr, features).annotate(annotator.classifier, location, annotator.n)
+
+		// genes outside the window are further than this from the
+		// middle so cannot be closer than those found
+		reach := end - mid
+
+		if start > 1 {
+			reach = min(reach, mid-start)
+		}
+
+		if window >= MAX_CHR_LEN ||
+			(len(ret.ClosestGenes) == annotator.n && (annotator.n == 0 || uint(abs(ret.ClosestGenes[annotator.n-1].TssDist)) <= reach)) {
+			return ret, nil
+		}
+
+		window *= 4
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+
+	return x
+}
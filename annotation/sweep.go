@@ -0,0 +1,207 @@
+package annotation
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-genome"
+)
+
+// separates the values of each gene a region is in
+const FEATURE_SEP = ","
+
+// Genes are fetched a whole chromosome at a time with a location that
+// covers any chromosome
+const MAX_CHR_LEN uint = 1 << 31
+
+// Fetches the genes overlapping a location, with their transcripts
+// and exons as children if the gene db has them
+type GeneFunc func(location *dna.Location) ([]*genome.GenomicFeature, error)
+
+type gene struct {
+	feature *genome.GenomicFeature
+	tss     uint
+	// the gene and its promoter
+	start uint
+	end   uint
+}
+
+// Genes of a chromosome, or part of one, sorted by TSS, for finding
+// the closest, and by start, for finding those a region is in.
+// maxEnd[i] is the furthest end of genes 0..i by start.
+type chromGenes struct {
+	chr     string
+	byTSS   []*gene
+	byStart []*gene
+	maxEnd  []uint
+}
+
+// Annotates regions from the genes of one chromosome held in memory
+// rather than querying the gene db for each region. Regions should be
+// sorted by chromosome so that each chromosome's genes are only
+// fetched once as they are swept through.
+type Sweeper struct {
+	fetch      GeneFunc
+	classifier *Classifier
+	n          int
+	current    *chromGenes
+}
+
+func NewSweeper(fetch GeneFunc, classifier *Classifier, n uint16) *Sweeper {
+	return &Sweeper{fetch: fetch, classifier: classifier, n: int(n)}
+}
+
+func newChromGenes(classifier *Classifier, chr string, features []*genome.GenomicFeature) *chromGenes {
+	genes := chromGenes{chr: chr,
+		byTSS:   make([]*gene, 0, len(features)),
+		byStart: make([]*gene, 0, len(features)),
+		maxEnd:  make([]uint, len(features))}
+
+	for _, feature := range features {
+		start, end := classifier.Span(feature)
+
+		g := gene{feature: feature,
+			tss:   tss(feature),
+			start: start,
+			end:   end}
+
+		genes.byTSS = append(genes.byTSS, &g)
+		genes.byStart = append(genes.byStart, &g)
+	}
+
+	sort.Slice(genes.byTSS, func(i, j int) bool {
+		return genes.byTSS[i].tss < genes.byTSS[j].tss
+	})
+
+	sort.Slice(genes.byStart, func(i, j int) bool {
+		return genes.byStart[i].start < genes.byStart[j].start
+	})
+
+	var end uint
+
+	for i, g := range genes.byStart {
+		end = max(end, g.end)
+		genes.maxEnd[i] = end
+	}
+
+	return &genes
+}
+
+func (sweeper *Sweeper) load(chr string) error {
+	features, err := sweeper.fetch(dna.NewLocation(chr, 1, MAX_CHR_LEN))
+
+	if err != nil {
+		return err
+	}
+
+	sweeper.current = newChromGenes(sweeper.classifier, chr, features)
+
+	return nil
+}
+
+// distance from a gene's TSS to the middle of a region, negative
+// if upstream
+func tssDist(mid uint, g *gene) int {
+	ret := int(mid) - int(g.tss)
+
+	if g.feature.Strand == "-" {
+		ret = -ret
+	}
+
+	return ret
+}
+
+func absDist(mid uint, g *gene) uint {
+	if mid > g.tss {
+		return mid - g.tss
+	}
+
+	return g.tss - mid
+}
+
+func (sweeper *Sweeper) Annotate(location *dna.Location) (*genome.GeneAnnotation, error) {
+	if sweeper.current == nil || sweeper.current.chr != location.Chr {
+		err := sweeper.load(location.Chr)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sweeper.current.annotate(sweeper.classifier, location, sweeper.n), nil
+}
+
+// The genes a region is in, labelled by where it is in them, and the
+// n genes with the closest TSSs to its middle
+func (genes *chromGenes) annotate(classifier *Classifier, location *dna.Location, n int) *genome.GeneAnnotation {
+	mid := (location.Start + location.End) / 2
+
+	ids := make([]string, 0, 5)
+	symbols := make([]string, 0, 5)
+	labels := make([]string, 0, 5)
+	dists := make([]string, 0, 5)
+	locations := make([]string, 0, 5)
+
+	i := sort.Search(len(genes.maxEnd), func(i int) bool {
+		return genes.maxEnd[i] >= location.Start
+	})
+
+	for ; i < len(genes.byStart) && genes.byStart[i].start <= location.End; i++ {
+		g := genes.byStart[i]
+
+		if g.end < location.Start {
+			continue
+		}
+
+		label := classifier.Label(location, g.feature)
+
+		if label == LABEL_INTERGENIC {
+			continue
+		}
+
+		ids = append(ids, g.feature.GeneId)
+		symbols = append(symbols, g.feature.GeneSymbol)
+		labels = append(labels, label)
+		dists = append(dists, strconv.Itoa(tssDist(mid, g)))
+		locations = append(locations, g.feature.Location.String())
+	}
+
+	// the n closest TSSs either side of the middle of the region
+	right := sort.Search(len(genes.byTSS), func(i int) bool {
+		return genes.byTSS[i].tss >= mid
+	})
+
+	left := right - 1
+
+	closest := make([]*genome.GenomicFeature, 0, n)
+
+	for len(closest) < n && (left >= 0 || right < len(genes.byTSS)) {
+		var g *gene
+
+		if right >= len(genes.byTSS) || (left >= 0 && absDist(mid, genes.byTSS[left]) <= absDist(mid, genes.byTSS[right])) {
+			g = genes.byTSS[left]
+			left--
+		} else {
+			g = genes.byTSS[right]
+			right++
+		}
+
+		// genes are shared between regions so annotate a copy
+		feature := *g.feature
+		feature.Children = nil
+		feature.TssDist = tssDist(mid, g)
+		feature.PromLabel = classifier.Label(location, g.feature)
+
+		closest = append(closest, &feature)
+	}
+
+	return &genome.GeneAnnotation{Location: location,
+		GeneIds:      strings.Join(ids, FEATURE_SEP),
+		GeneSymbols:  strings.Join(symbols, FEATURE_SEP),
+		PromLabels:   strings.Join(labels, FEATURE_SEP),
+		TSSDists:     strings.Join(dists, FEATURE_SEP),
+		Locations:    strings.Join(locations, FEATURE_SEP),
+		ClosestGenes: closest}
+}
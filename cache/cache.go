@@ -0,0 +1,403 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/metrics"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-web"
+	"github.com/antonybholmes/go-web/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const REDIS_PREFIX = "edb:cache:"
+
+// entries pulled from redis are only held in memory briefly so
+// that other instances see invalidations quickly
+const DEFAULT_MEMORY_TTL = 5 * time.Minute
+
+// how often module data is checked for changes
+const VERSION_CHECK_INTERVAL = 30 * time.Second
+
+// Headers that belong to a single response so are never replayed
+var uncachedHeaders = map[string]struct{}{"Content-Length": {},
+	"Date":         {},
+	"Set-Cookie":   {},
+	"X-Request-Id": {}}
+
+const (
+	RESULT_HIT          = "hit"
+	RESULT_MISS         = "miss"
+	RESULT_NOT_MODIFIED = "not_modified"
+)
+
+type dataVersion struct {
+	modified time.Time
+	checked  time.Time
+}
+
+type Cache struct {
+	store Store
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	versions map[string]*dataVersion
+}
+
+var instance *Cache
+
+// Set up the response cache. Passing a redis client shares the
+// cache between instances. Until this is called, or if the cache
+// is disabled, the middleware does nothing.
+func InitCache(cfg *config.Config, rdb *redis.Client) {
+	if !cfg.Cache.Enabled {
+		instance = nil
+		return
+	}
+
+	memory := NewMemoryStore(cfg.Cache.MaxEntries)
+
+	var store Store = memory
+
+	if cfg.Cache.Redis && rdb != nil {
+		store = &TieredStore{memory: memory, redis: NewRedisStore(rdb, REDIS_PREFIX)}
+	}
+
+	instance = &Cache{store: store,
+		ttl:      cfg.CacheTTL(),
+		versions: make(map[string]*dataVersion)}
+}
+
+// Cache the responses of a module route. Responses are keyed on the
+// path, query, output format and body and are dropped when any file
+// under dataPath changes. Responses of protected routes can depend on
+// who is asking so are also keyed on the user. Clients get an ETag
+// and Last-Modified so they can revalidate with a conditional request.
+func Middleware(module string, dataPath string, protected bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cache := instance
+
+		if cache == nil {
+			c.Next()
+			return
+		}
+
+		user := ""
+
+		if protected {
+			user = userId(c)
+
+			// never share a protected response
+			if user == "" {
+				c.Next()
+				return
+			}
+		}
+
+		key, err := requestKey(c, user)
+
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		modified := cache.version(dataPath)
+
+		entry, ok := cache.store.Get(c.Request.Context(), key)
+
+		if ok && entry.Version == modified.UnixNano() {
+			serve(c, module, entry, RESULT_HIT)
+			return
+		}
+
+		// headers set before the handler, such as quotas, are
+		// per request
+		before := c.Writer.Header().Clone()
+
+		w := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		c.Next()
+
+		c.Writer = w.ResponseWriter
+
+		if w.status == 0 && w.buf.Len() == 0 {
+			// nothing written, e.g. an error left for the
+			// error handler
+			return
+		}
+
+		if w.status != http.StatusOK || len(c.Errors) > 0 {
+			c.Writer.WriteHeader(w.status)
+			c.Writer.Write(w.buf.Bytes())
+			return
+		}
+
+		body := bytes.Clone(w.buf.Bytes())
+		hash := sha256.Sum256(body)
+
+		entry = &Entry{Header: handlerHeaders(before, c.Writer.Header()),
+			Body:         body,
+			ETag:         "\"" + hex.EncodeToString(hash[:16]) + "\"",
+			LastModified: modified.Unix(),
+			Version:      modified.UnixNano()}
+
+		cache.store.Set(c.Request.Context(), key, entry, cache.ttl)
+
+		serve(c, module, entry, RESULT_MISS)
+	}
+}
+
+// Headers the handler added or changed
+func handlerHeaders(before http.Header, after http.Header) http.Header {
+	ret := make(http.Header)
+
+	for k, v := range after {
+		if _, ok := uncachedHeaders[k]; ok {
+			continue
+		}
+
+		if slices.Equal(before[k], v) {
+			continue
+		}
+
+		ret[k] = slices.Clone(v)
+	}
+
+	return ret
+}
+
+func serve(c *gin.Context, module string, entry *Entry, result string) {
+	for k, v := range entry.Header {
+		c.Writer.Header()[k] = v
+	}
+
+	c.Header("ETag", entry.ETag)
+	c.Header("Last-Modified", time.Unix(entry.LastModified, 0).UTC().Format(http.TimeFormat))
+	// clients may keep responses but must check they are current
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("Vary", "Accept")
+	c.Header("X-Cache", result)
+
+	if notModified(c.Request, entry) {
+		metrics.CacheResult(module, RESULT_NOT_MODIFIED)
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+
+	metrics.CacheResult(module, result)
+
+	c.Data(http.StatusOK, entry.Header.Get("Content-Type"), entry.Body)
+	c.Abort()
+}
+
+func notModified(req *http.Request, entry *Entry) bool {
+	etag := req.Header.Get("If-None-Match")
+
+	// If-None-Match takes precedence when both are sent
+	if etag != "" {
+		return etag == entry.ETag || etag == "*"
+	}
+
+	since := req.Header.Get("If-Modified-Since")
+
+	if since == "" {
+		return false
+	}
+
+	t, err := http.ParseTime(since)
+
+	if err != nil {
+		return false
+	}
+
+	return entry.LastModified <= t.Unix()
+}
+
+// The signed in user, set by the auth middleware of protected routes
+func userId(c *gin.Context) string {
+	user, ok := c.Get(web.SESSION_USER)
+
+	if !ok {
+		return ""
+	}
+
+	switch u := user.(type) {
+	case *auth.TokenClaims:
+		return u.UserId
+	case *auth.AuthUser:
+		return u.PublicId
+	default:
+		return ""
+	}
+}
+
+// Key on everything that affects the response. JSON bodies are
+// normalized so that formatting and key order do not matter.
+func requestKey(c *gin.Context, user string) (string, error) {
+	var body []byte
+
+	if c.Request.Body != nil {
+		data, err := io.ReadAll(c.Request.Body)
+
+		if err != nil {
+			return "", err
+		}
+
+		// restore for the handler
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+
+		body = normalizeBody(data)
+	}
+
+	h := sha256.New()
+
+	h.Write([]byte(c.Request.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Request.URL.Path))
+	h.Write([]byte{0})
+	// Encode sorts by key
+	h.Write([]byte(c.Request.URL.Query().Encode()))
+	h.Write([]byte{0})
+	h.Write([]byte(tabular.ParseFormat(c)))
+	h.Write([]byte{0})
+	h.Write([]byte(user))
+	h.Write([]byte{0})
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func normalizeBody(data []byte) []byte {
+	var v any
+
+	err := json.Unmarshal(data, &v)
+
+	if err != nil {
+		return data
+	}
+
+	// maps are marshaled with sorted keys
+	ret, err := json.Marshal(v)
+
+	if err != nil {
+		return data
+	}
+
+	return ret
+}
+
+// Returns the latest modification time of the files under path.
+// The result is reused for a short time to avoid walking the data
+// on every request.
+func (cache *Cache) version(path string) time.Time {
+	cache.mu.Lock()
+	v, ok := cache.versions[path]
+	cache.mu.Unlock()
+
+	now := time.Now()
+
+	if ok && now.Sub(v.checked) < VERSION_CHECK_INTERVAL {
+		return v.modified
+	}
+
+	modified := lastModified(path)
+
+	cache.mu.Lock()
+	cache.versions[path] = &dataVersion{modified: modified, checked: now}
+	cache.mu.Unlock()
+
+	return modified
+}
+
+func lastModified(path string) time.Time {
+	var ret time.Time
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return ret
+	}
+
+	if !info.IsDir() {
+		return info.ModTime()
+	}
+
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		info, err := d.Info()
+
+		if err == nil && info.ModTime().After(ret) {
+			ret = info.ModTime()
+		}
+
+		return nil
+	})
+
+	return ret
+}
+
+// Holds the response back so it can be cached before it is sent
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf    bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.WriteHeaderNow()
+
+	return w.buf.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+
+	return w.buf.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.buf.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.status != 0
+}
+
+func (w *bufferedWriter) Flush() {}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// A cached response
+type Entry struct {
+	// headers the handler set, such as Content-Type and
+	// Content-Disposition
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	ETag         string      `json:"etag"`
+	LastModified int64       `json:"lastModified"`
+	// data version the response was generated from
+	Version int64 `json:"version"`
+}
+
+type Store interface {
+	Get(ctx context.Context, key string) (*Entry, bool)
+	Set(ctx context.Context, key string, entry *Entry, ttl time.Duration)
+}
+
+type memoryItem struct {
+	key     string
+	entry   *Entry
+	expires time.Time
+}
+
+// In process LRU store with a cap on the number of entries
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	items      map[string]*list.Element
+	order      *list.List
+}
+
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{maxEntries: max(maxEntries, 1),
+		items: make(map[string]*list.Element),
+		order: list.New()}
+}
+
+func (store *MemoryStore) Get(ctx context.Context, key string) (*Entry, bool) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	elem, ok := store.items[key]
+
+	if !ok {
+		return nil, false
+	}
+
+	item := elem.Value.(*memoryItem)
+
+	if time.Now().After(item.expires) {
+		store.order.Remove(elem)
+		delete(store.items, key)
+		return nil, false
+	}
+
+	store.order.MoveToFront(elem)
+
+	return item.entry, true
+}
+
+func (store *MemoryStore) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	item := &memoryItem{key: key, entry: entry, expires: time.Now().Add(ttl)}
+
+	if elem, ok := store.items[key]; ok {
+		elem.Value = item
+		store.order.MoveToFront(elem)
+		return
+	}
+
+	store.items[key] = store.order.PushFront(item)
+
+	for store.order.Len() > store.maxEntries {
+		oldest := store.order.Back()
+		store.order.Remove(oldest)
+		delete(store.items, oldest.Value.(*memoryItem).key)
+	}
+}
+
+// Shares cached responses between server instances. Redis
+// handles expiry.
+type RedisStore struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func NewRedisStore(rdb *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{rdb: rdb, prefix: prefix}
+}
+
+func (store *RedisStore) Get(ctx context.Context, key string) (*Entry, bool) {
+	data, err := store.rdb.Get(ctx, store.prefix+key).Bytes()
+
+	if err != nil {
+		return nil, false
+	}
+
+	var entry Entry
+
+	err = json.Unmarshal(data, &entry)
+
+	if err != nil {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+func (store *RedisStore) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) {
+	data, err := json.Marshal(entry)
+
+	if err != nil {
+		return
+	}
+
+	// the cache is best effort so a redis outage only costs
+	// a recompute
+	err = store.rdb.Set(ctx, store.prefix+key, data, ttl).Err()
+
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Error().Msgf("error caching %s: %s", key, err)
+	}
+}
+
+// Checks memory first, then redis, and populates memory from
+// redis hits
+type TieredStore struct {
+	memory *MemoryStore
+	redis  *RedisStore
+}
+
+func (store *TieredStore) Get(ctx context.Context, key string) (*Entry, bool) {
+	entry, ok := store.memory.Get(ctx, key)
+
+	if ok {
+		return entry, true
+	}
+
+	entry, ok = store.redis.Get(ctx, key)
+
+	if ok {
+		store.memory.Set(ctx, key, entry, DEFAULT_MEMORY_TTL)
+	}
+
+	return entry, ok
+}
+
+func (store *TieredStore) Set(ctx context.Context, key string, entry *Entry, ttl time.Duration) {
+	store.memory.Set(ctx, key, entry, min(ttl, DEFAULT_MEMORY_TTL))
+	store.redis.Set(ctx, key, entry, ttl)
+}
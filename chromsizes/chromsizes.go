@@ -0,0 +1,48 @@
+package chromsizes
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A chromosome and its length in bases
+type Size struct {
+	Name string
+	Len  uint
+}
+
+// Read a UCSC style chrom.sizes file of names and lengths
+func Read(file string) ([]*Size, error) {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	ret := make([]*Size, 0, 50)
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		tokens := strings.Fields(scanner.Text())
+
+		if len(tokens) < 2 || strings.HasPrefix(tokens[0], "#") {
+			continue
+		}
+
+		n, err := strconv.ParseUint(tokens[1], 10, 0)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s is an invalid length", file, tokens[1])
+		}
+
+		ret = append(ret, &Size{Name: tokens[0], Len: uint(n)})
+	}
+
+	return ret, scanner.Err()
+}
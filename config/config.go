@@ -0,0 +1,508 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Name of the env variable that can be used to point the server at
+// a different config file, e.g. for staging
+const CONFIG_FILE_ENV = "CONFIG_FILE"
+
+const DEFAULT_CONFIG_FILE = "config.yaml"
+
+const DEFAULT_DATA_DIR = "data/modules"
+
+const DEFAULT_ADDR = "0.0.0.0:8080"
+
+const DEFAULT_SHUTDOWN_TIMEOUT_SECS = 30
+
+const DEFAULT_JOBS_FILE = "data/jobs.db"
+
+const DEFAULT_OLIGOS_DIR = "data/oligos"
+
+const DEFAULT_ENZYMES_FILE = "data/enzymes/emboss_e"
+
+const DEFAULT_FRAGMENTS_DIR = "data/fragments"
+
+const DEFAULT_SWAGGER_UI_URL = "https://unpkg.com/swagger-ui-dist@5"
+
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+type ServerConfig struct {
+	// required so it can listen externally within docker container
+	Addr string    `yaml:"addr"`
+	TLS  TLSConfig `yaml:"tls"`
+	// how long to wait for in flight requests to finish when
+	// the server is asked to stop
+	ShutdownTimeoutSecs int `yaml:"shutdownTimeoutSecs"`
+	// ips or cidrs of proxies whose X-Forwarded-For headers are
+	// believed. None by default so clients cannot pick their
+	// own ip, which rate limits are keyed on.
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+type CorsConfig struct {
+	AllowOrigins []string `yaml:"allowOrigins"`
+	AllowMethods []string `yaml:"allowMethods"`
+	AllowHeaders []string `yaml:"allowHeaders"`
+	// how long browsers can cache a preflight response
+	MaxAgeHours int `yaml:"maxAgeHours"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+type JobsConfig struct {
+	// number of jobs that can run at once
+	Workers int `yaml:"workers"`
+	// jobs waiting for a worker beyond this are rejected
+	QueueSize int `yaml:"queueSize"`
+	// queued plus running jobs allowed per user
+	MaxPerUser int `yaml:"maxPerUser"`
+	// how long finished jobs and their results are kept
+	RetentionHours int `yaml:"retentionHours"`
+	// sqlite db holding job state and results
+	File string `yaml:"file"`
+}
+
+type CacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// max responses held in memory
+	MaxEntries int `yaml:"maxEntries"`
+	TTLMins    int `yaml:"ttlMins"`
+	// also store responses in redis so they are shared
+	// between instances
+	Redis bool `yaml:"redis"`
+}
+
+// Token bucket settings
+type RateLimit struct {
+	// requests allowed per second on average
+	Rate float64 `yaml:"rate"`
+	// requests allowed at once before the rate applies
+	Burst int `yaml:"burst"`
+}
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// share limits between instances using redis
+	Redis bool `yaml:"redis"`
+	// limits per route group, e.g. modules or auth. Groups not
+	// listed are not limited.
+	Groups map[string]*RateLimit `yaml:"groups"`
+	// requests per day by role. Users get the largest quota of
+	// their roles and 0 means unlimited. Clients that are not
+	// signed in use the guest quota.
+	DailyQuotas map[string]int `yaml:"dailyQuotas"`
+}
+
+type TracingConfig struct {
+	// otlp, stdout, file or none. Request ids are generated
+	// even when spans are not exported.
+	Exporter string `yaml:"exporter"`
+	// otlp http collector, e.g. localhost:4318. The standard
+	// OTEL_EXPORTER_OTLP_* env variables are also honoured.
+	Endpoint string `yaml:"endpoint"`
+	// send to the collector over http rather than https
+	Insecure bool `yaml:"insecure"`
+	// where the file exporter writes spans
+	File string `yaml:"file"`
+	// fraction of new traces that are exported
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+type DocsConfig struct {
+	// where the /docs page loads the swagger-ui-dist css and js
+	// from, e.g. a copy hosted alongside the server
+	SwaggerUIUrl string `yaml:"swaggerUiUrl"`
+}
+
+type MetricsConfig struct {
+	// assemblies used as metric labels. Requests for any other
+	// assembly are counted together so that random urls cannot
+	// create new series.
+	Assemblies []string `yaml:"assemblies"`
+}
+
+type OligosConfig struct {
+	// where the genome indexes used for oligo search are kept
+	Dir string `yaml:"dir"`
+	// assemblies to index at startup rather than on their
+	// first search
+	Prebuild []string `yaml:"prebuild"`
+}
+
+type EnzymesConfig struct {
+	// REBASE enzymes in EMBOSS format
+	File string `yaml:"file"`
+	// where genome wide fragment maps are kept once made
+	FragmentsDir string `yaml:"fragmentsDir"`
+}
+
+type ModuleConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Location of the module data. Relative paths are resolved
+	// against the data dir
+	Path string `yaml:"path"`
+}
+
+type Config struct {
+	DataDir    string          `yaml:"dataDir"`
+	Server     ServerConfig    `yaml:"server"`
+	Cors       CorsConfig      `yaml:"cors"`
+	Redis      RedisConfig     `yaml:"redis"`
+	Jobs       JobsConfig      `yaml:"jobs"`
+	Cache      CacheConfig     `yaml:"cache"`
+	RateLimits RateLimitConfig `yaml:"rateLimits"`
+	Tracing    TracingConfig   `yaml:"tracing"`
+	Metrics    MetricsConfig   `yaml:"metrics"`
+	Docs       DocsConfig      `yaml:"docs"`
+	Oligos     OligosConfig    `yaml:"oligos"`
+	Enzymes    EnzymesConfig   `yaml:"enzymes"`
+	Modules    Modules         `yaml:"modules"`
+}
+
+type Modules map[string]*ModuleConfig
+
+// Create a config matching the settings the server has always
+// used so that a missing config file does not change behaviour
+func Default() *Config {
+	return &Config{
+		DataDir: DEFAULT_DATA_DIR,
+		Server: ServerConfig{Addr: DEFAULT_ADDR,
+			ShutdownTimeoutSecs: DEFAULT_SHUTDOWN_TIMEOUT_SECS},
+		Cors: CorsConfig{
+			AllowOrigins: []string{
+				"http://localhost:3000",
+				"http://localhost:8000",
+				"https://edb.rdf-lab.org",
+				"https://edb-client-astro.pages.dev",
+				"https://edb-client-next.pages.dev",
+				"https://edb-client-next.vercel.app"},
+			AllowMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "X-CSRF-Token"},
+			MaxAgeHours:  12,
+		},
+		Redis: RedisConfig{Username: "edb"},
+		Jobs: JobsConfig{Workers: 4,
+			QueueSize:      100,
+			MaxPerUser:     5,
+			RetentionHours: 24,
+			File:           DEFAULT_JOBS_FILE},
+		Cache: CacheConfig{Enabled: true,
+			MaxEntries: 1000,
+			TTLMins:    60},
+		RateLimits: RateLimitConfig{Enabled: true,
+			Groups: map[string]*RateLimit{
+				"auth":    {Rate: 0.2, Burst: 10},
+				"modules": {Rate: 20, Burst: 50},
+				"jobs":    {Rate: 1, Burst: 10},
+				"utils":   {Rate: 2, Burst: 10},
+			},
+			DailyQuotas: map[string]int{
+				"guest": 5000,
+				"user":  20000,
+				"rdf":   0,
+			}},
+		Tracing: TracingConfig{Exporter: "none",
+			File:        "traces.json",
+			SampleRatio: 1},
+		Metrics: MetricsConfig{Assemblies: []string{"hg19", "hg38", "grch37", "grch38", "mm9", "mm10", "mm39"}},
+		Docs:    DocsConfig{SwaggerUIUrl: DEFAULT_SWAGGER_UI_URL},
+		Oligos:  OligosConfig{Dir: DEFAULT_OLIGOS_DIR},
+		Enzymes: EnzymesConfig{File: DEFAULT_ENZYMES_FILE,
+			FragmentsDir: DEFAULT_FRAGMENTS_DIR},
+		Modules: Modules{
+			"dna":       {Enabled: true, Path: "dna"},
+			"genome":    {Enabled: true, Path: "genome"},
+			"gex":       {Enabled: true, Path: "gex"},
+			"scrna":     {Enabled: true, Path: "scrna"},
+			"mutations": {Enabled: true, Path: "mutations"},
+			"geneconv":  {Enabled: true, Path: "geneconv/geneconv.db"},
+			"motifs":    {Enabled: true, Path: "motifs/motifs.db"},
+			"pathway":   {Enabled: true, Path: "pathway/pathway-v2.db"},
+			"seqs":      {Enabled: true, Path: "seqs/"},
+			"cytobands": {Enabled: true, Path: "cytobands/"},
+			"beds":      {Enabled: true, Path: "beds/"},
+			"hubs":      {Enabled: true, Path: "hubs/"},
+		},
+	}
+}
+
+// Load the config. The file named by CONFIG_FILE (or config.yaml) is
+// layered on top of the defaults and env variables, which will have
+// already been populated from consts.env, are applied last so that
+// individual settings can be changed per deployment.
+func Load() (*Config, error) {
+	file := os.Getenv(CONFIG_FILE_ENV)
+
+	required := file != ""
+
+	if !required {
+		file = DEFAULT_CONFIG_FILE
+	}
+
+	cfg := Default()
+
+	data, err := os.ReadFile(file)
+
+	switch {
+	case err == nil:
+		err = yaml.Unmarshal(data, cfg)
+
+		if err != nil {
+			return nil, fmt.Errorf("error parsing config %s: %w", file, err)
+		}
+	case os.IsNotExist(err) && !required:
+		// no config so stick with the defaults
+	default:
+		return nil, fmt.Errorf("error reading config %s: %w", file, err)
+	}
+
+	err = cfg.applyEnv()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Decode module settings on top of any existing entries so that a
+// config file only needs to list the fields it wants to change.
+// Modules not seen before are enabled by default.
+func (modules *Modules) UnmarshalYAML(value *yaml.Node) error {
+	if *modules == nil {
+		*modules = make(Modules)
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		name := value.Content[i].Value
+
+		m, ok := (*modules)[name]
+
+		if !ok || m == nil {
+			m = &ModuleConfig{Enabled: true}
+		}
+
+		err := value.Content[i+1].Decode(m)
+
+		if err != nil {
+			return fmt.Errorf("module %s: %w", name, err)
+		}
+
+		(*modules)[name] = m
+	}
+
+	return nil
+}
+
+// Returns the config for a module or nil if the module is unknown
+func (cfg *Config) Module(name string) *ModuleConfig {
+	return cfg.Modules[name]
+}
+
+// Returns true if the module is enabled. Modules missing from the
+// config are enabled so new modules work without a config change.
+func (cfg *Config) IsEnabled(name string) bool {
+	m := cfg.Module(name)
+
+	return m == nil || m.Enabled
+}
+
+// Returns the path to a module's data, resolved against the data dir.
+// Modules without a path default to a dir named after the module.
+// Trailing slashes are preserved as some caches expect a directory.
+func (cfg *Config) ModulePath(name string) string {
+	path := name
+
+	m := cfg.Module(name)
+
+	if m != nil && m.Path != "" {
+		path = m.Path
+	}
+
+	if !filepath.IsAbs(path) {
+		trailingSlash := strings.HasSuffix(path, "/")
+
+		path = filepath.Join(cfg.DataDir, path)
+
+		if trailingSlash {
+			path += "/"
+		}
+	}
+
+	return path
+}
+
+func (cfg *Config) ShutdownTimeout() time.Duration {
+	return time.Duration(cfg.Server.ShutdownTimeoutSecs) * time.Second
+}
+
+func (cfg *Config) JobRetention() time.Duration {
+	return time.Duration(cfg.Jobs.RetentionHours) * time.Hour
+}
+
+func (cfg *Config) CacheTTL() time.Duration {
+	return time.Duration(cfg.Cache.TTLMins) * time.Minute
+}
+
+func (cfg *Config) IsTLS() bool {
+	return cfg.Server.TLS.CertFile != "" && cfg.Server.TLS.KeyFile != ""
+}
+
+func (cfg *Config) applyEnv() error {
+	envString("DATA_DIR", &cfg.DataDir)
+	envString("SERVER_ADDR", &cfg.Server.Addr)
+	envString("TLS_CERT_FILE", &cfg.Server.TLS.CertFile)
+	envString("TLS_KEY_FILE", &cfg.Server.TLS.KeyFile)
+	envList("TRUSTED_PROXIES", &cfg.Server.TrustedProxies)
+	envList("CORS_ALLOW_ORIGINS", &cfg.Cors.AllowOrigins)
+	envString("REDIS_ADDR", &cfg.Redis.Addr)
+	envString("REDIS_USERNAME", &cfg.Redis.Username)
+	envString("REDIS_PASSWORD", &cfg.Redis.Password)
+
+	err := envInt("REDIS_DB", &cfg.Redis.DB)
+
+	if err != nil {
+		return err
+	}
+
+	envString("JOBS_FILE", &cfg.Jobs.File)
+
+	err = envBool("CACHE_ENABLED", &cfg.Cache.Enabled)
+
+	if err != nil {
+		return err
+	}
+
+	err = envBool("CACHE_REDIS", &cfg.Cache.Redis)
+
+	if err != nil {
+		return err
+	}
+
+	envString("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	envString("TRACING_ENDPOINT", &cfg.Tracing.Endpoint)
+	envList("METRICS_ASSEMBLIES", &cfg.Metrics.Assemblies)
+	envString("DOCS_SWAGGER_UI_URL", &cfg.Docs.SwaggerUIUrl)
+
+	err = envBool("RATE_LIMITS_ENABLED", &cfg.RateLimits.Enabled)
+
+	if err != nil {
+		return err
+	}
+
+	err = envBool("RATE_LIMITS_REDIS", &cfg.RateLimits.Redis)
+
+	if err != nil {
+		return err
+	}
+
+	err = envInt("JOBS_WORKERS", &cfg.Jobs.Workers)
+
+	if err != nil {
+		return err
+	}
+
+	// modules can be toggled with MODULE_<NAME>_ENABLED and
+	// MODULE_<NAME>_PATH
+	for name, m := range cfg.Modules {
+		if m == nil {
+			m = &ModuleConfig{}
+			cfg.Modules[name] = m
+		}
+
+		prefix := "MODULE_" + strings.ToUpper(name)
+
+		envString(prefix+"_PATH", &m.Path)
+
+		err := envBool(prefix+"_ENABLED", &m.Enabled)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func envString(name string, v *string) {
+	s := os.Getenv(name)
+
+	if s != "" {
+		*v = s
+	}
+}
+
+func envList(name string, v *[]string) {
+	s := os.Getenv(name)
+
+	if s == "" {
+		return
+	}
+
+	tokens := strings.Split(s, ",")
+
+	ret := make([]string, 0, len(tokens))
+
+	for _, token := range tokens {
+		token = strings.TrimSpace(token)
+
+		if token != "" {
+			ret = append(ret, token)
+		}
+	}
+
+	*v = ret
+}
+
+func envInt(name string, v *int) error {
+	s := os.Getenv(name)
+
+	if s == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+
+	if err != nil {
+		return fmt.Errorf("%s is not a valid integer: %s", name, s)
+	}
+
+	*v = n
+
+	return nil
+}
+
+func envBool(name string, v *bool) error {
+	s := os.Getenv(name)
+
+	if s == "" {
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+
+	if err != nil {
+		return fmt.Errorf("%s is not a valid boolean: %s", name, s)
+	}
+
+	*v = b
+
+	return nil
+}
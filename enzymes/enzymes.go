@@ -0,0 +1,165 @@
+package enzymes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrNotInitialized = errors.New("no restriction enzymes are available")
+
+// A restriction enzyme from REBASE. Cuts are given as in REBASE,
+// after that many bases from the start of the site, with negative
+// values counting back from the base before the site.
+type Enzyme struct {
+	Name string `json:"name"`
+	// IUPAC recognition site
+	Site string `json:"site"`
+	// cut on the strand the site is read from and the other strand
+	Cut      int  `json:"cut"`
+	OtherCut int  `json:"otherCut"`
+	Blunt    bool `json:"blunt"`
+	// which bases can match at each position of the site
+	pattern []byte
+	// true if the site reads the same on both strands
+	palindrome bool
+}
+
+type Catalogue struct {
+	enzymes []*Enzyme
+	names   map[string]*Enzyme
+}
+
+var instance *Catalogue
+
+// Load the enzyme catalogue. A missing file is logged rather than
+// stopping the server since only restriction routes need it.
+func InitCache(file string) {
+	catalogue, err := ReadRebase(file)
+
+	if err != nil {
+		log.Error().Msgf("restriction enzymes %s: %s", file, err)
+		return
+	}
+
+	log.Info().Msgf("loaded %d restriction enzymes from %s", len(catalogue.enzymes), file)
+
+	instance = catalogue
+}
+
+func List() ([]*Enzyme, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+
+	return instance.enzymes, nil
+}
+
+// Find an enzyme by name, ignoring case
+func Get(name string) (*Enzyme, error) {
+	if instance == nil {
+		return nil, ErrNotInitialized
+	}
+
+	enzyme, ok := instance.names[strings.ToLower(name)]
+
+	if !ok {
+		return nil, fmt.Errorf("%s is not a known enzyme", name)
+	}
+
+	return enzyme, nil
+}
+
+func ReadRebase(file string) (*Catalogue, error) {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return ParseRebase(f)
+}
+
+// Parse REBASE enzymes in EMBOSS format (emboss_e.###), which has
+// tab separated lines of name, site, site length, number of cuts,
+// blunt, and up to 4 cut positions. Enzymes whose cuts are unknown
+// are left out and only the first pair of cuts is used.
+func ParseRebase(r io.Reader) (*Catalogue, error) {
+	ret := Catalogue{enzymes: make([]*Enzyme, 0, 1000), names: make(map[string]*Enzyme)}
+
+	scanner := bufio.NewScanner(r)
+
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		tokens := strings.Fields(text)
+
+		if len(tokens) < 7 {
+			return nil, fmt.Errorf("line %d: expected at least 7 columns", line)
+		}
+
+		values := make([]int, 0, 4)
+
+		for _, t := range tokens[2:7] {
+			n, err := strconv.Atoi(t)
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s is not a number", line, t)
+			}
+
+			values = append(values, n)
+		}
+
+		// no known cut
+		if values[1] == 0 {
+			continue
+		}
+
+		enzyme, err := NewEnzyme(tokens[0], tokens[1], values[3], values[4])
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		enzyme.Blunt = values[2] == 1
+
+		// isoschizomers can be listed more than once
+		_, ok := ret.names[strings.ToLower(enzyme.Name)]
+
+		if ok {
+			continue
+		}
+
+		ret.enzymes = append(ret.enzymes, enzyme)
+		ret.names[strings.ToLower(enzyme.Name)] = enzyme
+	}
+
+	err := scanner.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ret.enzymes, func(i, j int) bool {
+		return strings.ToLower(ret.enzymes[i].Name) < strings.ToLower(ret.enzymes[j].Name)
+	})
+
+	return &ret, nil
+}
@@ -0,0 +1,185 @@
+package enzymes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	STRAND_POS = "+"
+	STRAND_NEG = "-"
+)
+
+const (
+	BASE_A byte = 1 << iota
+	BASE_C
+	BASE_G
+	BASE_T
+)
+
+// Bases each IUPAC code stands for
+var IUPAC = map[byte]byte{
+	'A': BASE_A,
+	'C': BASE_C,
+	'G': BASE_G,
+	'T': BASE_T,
+	'R': BASE_A | BASE_G,
+	'Y': BASE_C | BASE_T,
+	'S': BASE_C | BASE_G,
+	'W': BASE_A | BASE_T,
+	'K': BASE_G | BASE_T,
+	'M': BASE_A | BASE_C,
+	'B': BASE_C | BASE_G | BASE_T,
+	'D': BASE_A | BASE_G | BASE_T,
+	'H': BASE_A | BASE_C | BASE_T,
+	'V': BASE_A | BASE_C | BASE_G,
+	'N': BASE_A | BASE_C | BASE_G | BASE_T,
+}
+
+// complement of each IUPAC code
+var iupacComp = map[byte]byte{
+	'A': 'T', 'C': 'G', 'G': 'C', 'T': 'A',
+	'R': 'Y', 'Y': 'R', 'S': 'S', 'W': 'W',
+	'K': 'M', 'M': 'K', 'B': 'V', 'D': 'H',
+	'H': 'D', 'V': 'B', 'N': 'N',
+}
+
+// A recognition site in a sequence. Start is its 0-based offset. Cut
+// and OtherCut are 0-based offsets of the + and - strand cuts, i.e.
+// the number of bases before each cut, which can be outside the
+// sequence for enzymes that cut away from their site.
+type Site struct {
+	Start    int    `json:"start"`
+	Strand   string `json:"strand"`
+	Cut      int    `json:"cut"`
+	OtherCut int    `json:"otherCut"`
+}
+
+func NewEnzyme(name string, site string, cut int, otherCut int) (*Enzyme, error) {
+	site = strings.ToUpper(site)
+
+	ret := Enzyme{Name: name,
+		Site:     site,
+		Cut:      cut,
+		OtherCut: otherCut,
+		pattern:  make([]byte, len(site))}
+
+	rc := make([]byte, len(site))
+
+	for i := 0; i < len(site); i++ {
+		bases, ok := IUPAC[site[i]]
+
+		if !ok {
+			return nil, fmt.Errorf("%s is not a valid site", site)
+		}
+
+		ret.pattern[i] = bases
+		rc[len(site)-1-i] = iupacComp[site[i]]
+	}
+
+	ret.palindrome = string(rc) == site
+
+	return &ret, nil
+}
+
+// REBASE counts cuts from 1 at the start of the site and from -1 at
+// the base before it, so convert to the bases from the site start
+func boundary(cut int) int {
+	if cut > 0 {
+		return cut
+	}
+
+	return cut + 1
+}
+
+func baseBits(b byte) byte {
+	switch b {
+	case 'A', 'a':
+		return BASE_A
+	case 'C', 'c':
+		return BASE_C
+	case 'G', 'g':
+		return BASE_G
+	case 'T', 't':
+		return BASE_T
+	default:
+		// N never matches
+		return 0
+	}
+}
+
+// Find the sites of an enzyme on both strands of a sequence, in
+// order of their cuts on the + strand
+func (enzyme *Enzyme) Sites(seq string) []*Site {
+	ret := make([]*Site, 0, 10)
+
+	l := len(enzyme.pattern)
+
+	cut := boundary(enzyme.Cut)
+	otherCut := boundary(enzyme.OtherCut)
+
+	for p := 0; p+l <= len(seq); p++ {
+		fwd := true
+		rev := !enzyme.palindrome
+
+		for i := 0; i < l && (fwd || rev); i++ {
+			if fwd && baseBits(seq[p+i])&enzyme.pattern[i] == 0 {
+				fwd = false
+			}
+
+			// the site read on the - strand is the complement of
+			// the sequence read backwards
+			if rev && complementBits(baseBits(seq[p+l-1-i]))&enzyme.pattern[i] == 0 {
+				rev = false
+			}
+		}
+
+		if fwd {
+			ret = append(ret, &Site{Start: p, Strand: STRAND_POS, Cut: p + cut, OtherCut: p + otherCut})
+		}
+
+		if rev {
+			// on the - strand the enzyme's own cut is on the - strand
+			ret = append(ret, &Site{Start: p, Strand: STRAND_NEG, Cut: p + l - otherCut, OtherCut: p + l - cut})
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Cut < ret[j].Cut
+	})
+
+	return ret
+}
+
+func complementBits(b byte) byte {
+	switch b {
+	case BASE_A:
+		return BASE_T
+	case BASE_C:
+		return BASE_G
+	case BASE_G:
+		return BASE_C
+	case BASE_T:
+		return BASE_A
+	default:
+		return 0
+	}
+}
+
+// Fragment boundaries, as bases from the start of a sequence of
+// length n, made by cutting it at sites. Cuts outside the sequence
+// or at its ends are ignored.
+func Fragments(sites []*Site, n int) []int {
+	ret := make([]int, 0, len(sites)+2)
+
+	ret = append(ret, 0)
+
+	for _, site := range sites {
+		if site.Cut > ret[len(ret)-1] && site.Cut < n {
+			ret = append(ret, site.Cut)
+		}
+	}
+
+	return append(ret, n)
+}
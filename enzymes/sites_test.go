@@ -0,0 +1,178 @@
+package enzymes
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// EMBOSS format lines of name, site, length, cuts, blunt and cut
+// positions. NegI is made up to cut before its site.
+const testRebase = `# comment
+EcoRI	GAATTC	6	2	0	1	5	0	0
+EcoRV	GATATC	6	2	1	3	3	0	0
+BsaI	GGTCTC	6	2	0	7	11	0	0
+NegI	ACTGG	5	2	0	-3	-1	0	0
+AcyI	GRCGYC	6	2	0	2	4	0	0
+NoCut	ACGT	4	0	0	0	0	0	0
+ecori	GAATTC	6	2	0	1	5	0	0
+`
+
+func testCatalogue(t *testing.T) *Catalogue {
+	t.Helper()
+
+	catalogue, err := ParseRebase(strings.NewReader(testRebase))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return catalogue
+}
+
+func TestParseRebase(t *testing.T) {
+	catalogue := testCatalogue(t)
+
+	names := make([]string, 0, len(catalogue.enzymes))
+
+	for _, enzyme := range catalogue.enzymes {
+		names = append(names, enzyme.Name)
+	}
+
+	// sorted ignoring case, without enzymes with no known cut or
+	// repeated names
+	want := []string{"AcyI", "BsaI", "EcoRI", "EcoRV", "NegI"}
+
+	if !slices.Equal(names, want) {
+		t.Errorf("enzymes = %v, want %v", names, want)
+	}
+
+	if !catalogue.names["ecorv"].Blunt || catalogue.names["ecori"].Blunt {
+		t.Error("EcoRV should be blunt and EcoRI not")
+	}
+}
+
+func TestParseRebaseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"too few columns", "EcoRI\tGAATTC\t6\t2\t0\t1"},
+		{"not a number", "EcoRI\tGAATTC\t6\t2\t0\tx\t5"},
+		{"invalid site", "BadI\tGAXTTC\t6\t2\t0\t1\t5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseRebase(strings.NewReader(test.line))
+
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestSites(t *testing.T) {
+	catalogue := testCatalogue(t)
+
+	tests := []struct {
+		name   string
+		enzyme string
+		seq    string
+		sites  []Site
+	}{
+		{name: "palindrome is found once",
+			enzyme: "EcoRI",
+			seq:    "AAGAATTCAA",
+			sites:  []Site{{Start: 2, Strand: STRAND_POS, Cut: 3, OtherCut: 7}}},
+		{name: "blunt",
+			enzyme: "EcoRV",
+			seq:    "AAGATATCAA",
+			sites:  []Site{{Start: 2, Strand: STRAND_POS, Cut: 5, OtherCut: 5}}},
+		{name: "lowercase bases match",
+			enzyme: "EcoRI",
+			seq:    "aagaattcaa",
+			sites:  []Site{{Start: 2, Strand: STRAND_POS, Cut: 3, OtherCut: 7}}},
+		{name: "N never matches",
+			enzyme: "EcoRI",
+			seq:    "AAGANTTCAA",
+			sites:  []Site{}},
+		{name: "cuts outside a + strand site",
+			enzyme: "BsaI",
+			seq:    "AGGTCTCAAAAAAA",
+			sites:  []Site{{Start: 1, Strand: STRAND_POS, Cut: 8, OtherCut: 12}}},
+		{name: "minus strand site cuts before it",
+			enzyme: "BsaI",
+			seq:    "TTTTTTGAGACCTT",
+			sites:  []Site{{Start: 6, Strand: STRAND_NEG, Cut: 1, OtherCut: 5}}},
+		{name: "cut off the start of the sequence",
+			enzyme: "BsaI",
+			seq:    "TTGAGACCTT",
+			sites:  []Site{{Start: 2, Strand: STRAND_NEG, Cut: -3, OtherCut: 1}}},
+		{name: "negative REBASE cuts are before the site",
+			enzyme: "NegI",
+			seq:    "TTTTACTGGTTT",
+			sites:  []Site{{Start: 4, Strand: STRAND_POS, Cut: 2, OtherCut: 4}}},
+		{name: "negative REBASE cuts on the minus strand are after the site",
+			enzyme: "NegI",
+			seq:    "TTTCCAGTTTTT",
+			sites:  []Site{{Start: 3, Strand: STRAND_NEG, Cut: 8, OtherCut: 10}}},
+		{name: "degenerate site",
+			enzyme: "AcyI",
+			seq:    "GACGTCTTGGCGCC",
+			sites: []Site{{Start: 0, Strand: STRAND_POS, Cut: 2, OtherCut: 4},
+				{Start: 8, Strand: STRAND_POS, Cut: 10, OtherCut: 12}}},
+		{name: "site at the end of the sequence",
+			enzyme: "EcoRI",
+			seq:    "GAATTC",
+			sites:  []Site{{Start: 0, Strand: STRAND_POS, Cut: 1, OtherCut: 5}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sites := catalogue.names[strings.ToLower(test.enzyme)].Sites(test.seq)
+
+			if len(sites) != len(test.sites) {
+				t.Fatalf("got %d sites, want %d", len(sites), len(test.sites))
+			}
+
+			for i, site := range sites {
+				if *site != test.sites[i] {
+					t.Errorf("site %d = %+v, want %+v", i, *site, test.sites[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFragments(t *testing.T) {
+	tests := []struct {
+		name string
+		cuts []int
+		n    int
+		want []int
+	}{
+		{"no sites", []int{}, 10, []int{0, 10}},
+		{"one cut", []int{4}, 10, []int{0, 4, 10}},
+		{"cuts outside the sequence", []int{-3, 4, 12}, 10, []int{0, 4, 10}},
+		{"cuts at the ends", []int{0, 4, 10}, 10, []int{0, 4, 10}},
+		{"repeated cuts", []int{4, 4, 6}, 10, []int{0, 4, 6, 10}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sites := make([]*Site, 0, len(test.cuts))
+
+			for _, cut := range test.cuts {
+				sites = append(sites, &Site{Cut: cut})
+			}
+
+			fragments := Fragments(sites, test.n)
+
+			if !slices.Equal(fragments, test.want) {
+				t.Errorf("fragments = %v, want %v", fragments, test.want)
+			}
+		})
+	}
+}
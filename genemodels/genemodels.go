@@ -0,0 +1,274 @@
+package genemodels
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/antonybholmes/go-genome"
+)
+
+const (
+	FORMAT_GTF   = "gtf"
+	FORMAT_GFF3  = "gff3"
+	FORMAT_BED12 = "bed12"
+)
+
+const (
+	MIME_GTF  = "text/x-gtf"
+	MIME_GFF3 = "text/x-gff3"
+)
+
+// Name written in the source column of GTF and GFF3
+const SOURCE = "edb"
+
+// The transcripts of a gene, or none if only genes are wanted
+func transcripts(gene *genome.GenomicFeature, level genome.Level) []*genome.GenomicFeature {
+	ret := make([]*genome.GenomicFeature, 0, len(gene.Children))
+
+	if level == genome.LEVEL_GENE {
+		return ret
+	}
+
+	for _, child := range gene.Children {
+		if child.Level == genome.LEVEL_TRANSCRIPT {
+			ret = append(ret, child)
+		}
+	}
+
+	return ret
+}
+
+// The exons of a transcript in order of position, or none if only
+// genes and transcripts are wanted
+func exons(transcript *genome.GenomicFeature, level genome.Level) []*genome.GenomicFeature {
+	ret := make([]*genome.GenomicFeature, 0, len(transcript.Children))
+
+	if level != genome.LEVEL_EXON {
+		return ret
+	}
+
+	for _, child := range transcript.Children {
+		if child.Level == genome.LEVEL_EXON {
+			ret = append(ret, child)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Location.Start < ret[j].Location.Start
+	})
+
+	return ret
+}
+
+func strand(feature *genome.GenomicFeature) string {
+	if feature.Strand == "" {
+		return "."
+	}
+
+	return feature.Strand
+}
+
+// The first 8 columns of a GTF or GFF3 line
+func columns(w *bufio.Writer, feature *genome.GenomicFeature, featureType string) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t.\t%s\t.\t",
+		feature.Location.Chr,
+		SOURCE,
+		featureType,
+		feature.Location.Start,
+		feature.Location.End,
+		strand(feature))
+}
+
+func gtfAttributes(w *bufio.Writer, gene *genome.GenomicFeature, transcript *genome.GenomicFeature, exon int) {
+	fmt.Fprintf(w, "gene_id \"%s\";", gene.GeneId)
+
+	if transcript != nil {
+		fmt.Fprintf(w, " transcript_id \"%s\";", transcript.TranscriptId)
+	}
+
+	if gene.GeneSymbol != "" {
+		fmt.Fprintf(w, " gene_name \"%s\";", gene.GeneSymbol)
+	}
+
+	if gene.GeneType != "" {
+		fmt.Fprintf(w, " gene_type \"%s\";", gene.GeneType)
+	}
+
+	if exon > 0 {
+		fmt.Fprintf(w, " exon_number %d;", exon)
+	}
+
+	if transcript != nil && transcript.IsCanonical {
+		w.WriteString(" tag \"Ensembl_canonical\";")
+	}
+
+	w.WriteByte('\n')
+}
+
+// Write genes with their transcripts and exons, down to a level, as
+// GTF in the style of GENCODE. Exons are numbered in transcript order.
+func WriteGTF(w io.Writer, genes []*genome.GenomicFeature, level genome.Level) error {
+	bw := bufio.NewWriter(w)
+
+	for _, gene := range genes {
+		columns(bw, gene, "gene")
+		gtfAttributes(bw, gene, nil, 0)
+
+		for _, transcript := range transcripts(gene, level) {
+			columns(bw, transcript, "transcript")
+			gtfAttributes(bw, gene, transcript, 0)
+
+			es := exons(transcript, level)
+
+			for ei, exon := range es {
+				number := ei + 1
+
+				if transcript.Strand == "-" {
+					number = len(es) - ei
+				}
+
+				columns(bw, exon, "exon")
+				gtfAttributes(bw, gene, transcript, number)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// GFF3 reserves ;=,& and tabs etc. in attribute values
+func gff3Escape(s string) string {
+	return strings.NewReplacer("%", "%25",
+		";", "%3B",
+		"=", "%3D",
+		"&", "%26",
+		",", "%2C",
+		"\t", "%09",
+		"\n", "%0A").Replace(s)
+}
+
+type attribute struct {
+	key   string
+	value string
+}
+
+func gff3Attributes(w *bufio.Writer, attributes []*attribute) {
+	first := true
+
+	for _, a := range attributes {
+		if a.value == "" {
+			continue
+		}
+
+		if !first {
+			w.WriteByte(';')
+		}
+
+		first = false
+
+		fmt.Fprintf(w, "%s=%s", a.key, gff3Escape(a.value))
+	}
+
+	w.WriteByte('\n')
+}
+
+// Write genes with their transcripts and exons, down to a level, as
+// GFF3 with transcripts linked to genes and exons to transcripts by
+// their Parent attributes
+func WriteGFF3(w io.Writer, genes []*genome.GenomicFeature, level genome.Level) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("##gff-version 3\n")
+
+	for _, gene := range genes {
+		geneId := "gene:" + gene.GeneId
+
+		columns(bw, gene, "gene")
+		gff3Attributes(bw, []*attribute{{"ID", geneId},
+			{"Name", gene.GeneSymbol},
+			{"gene_id", gene.GeneId},
+			{"gene_type", gene.GeneType}})
+
+		for _, transcript := range transcripts(gene, level) {
+			transcriptId := "transcript:" + transcript.TranscriptId
+
+			canonical := ""
+
+			if transcript.IsCanonical {
+				canonical = "Ensembl_canonical"
+			}
+
+			columns(bw, transcript, "transcript")
+			gff3Attributes(bw, []*attribute{{"ID", transcriptId},
+				{"Parent", geneId},
+				{"transcript_id", transcript.TranscriptId},
+				{"tag", canonical}})
+
+			for ei, exon := range exons(transcript, level) {
+				columns(bw, exon, "exon")
+				gff3Attributes(bw, []*attribute{{"ID", fmt.Sprintf("exon:%s.%d", transcript.TranscriptId, ei+1)},
+					{"Parent", transcriptId}})
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Write a BED12 line per transcript, or per gene if transcripts are not
+// wanted or a gene has none. Lines are named by transcript id and have
+// a single block unless exons are wanted. There is no CDS so the whole
+// feature is drawn thick.
+func WriteBED12(w io.Writer, genes []*genome.GenomicFeature, level genome.Level) error {
+	bw := bufio.NewWriter(w)
+
+	for _, gene := range genes {
+		features := transcripts(gene, level)
+
+		if len(features) == 0 {
+			features = append(features, gene)
+		}
+
+		for _, feature := range features {
+			name := feature.TranscriptId
+
+			if name == "" {
+				name = gene.GeneId
+			}
+
+			// BED is 0-based and half open
+			start := feature.Location.Start - 1
+			end := feature.Location.End
+
+			sizes := make([]string, 0, 10)
+			starts := make([]string, 0, 10)
+
+			for _, exon := range exons(feature, level) {
+				sizes = append(sizes, fmt.Sprint(exon.Location.End-exon.Location.Start+1))
+				starts = append(starts, fmt.Sprint(exon.Location.Start-1-start))
+			}
+
+			if len(sizes) == 0 {
+				sizes = append(sizes, fmt.Sprint(end-start))
+				starts = append(starts, "0")
+			}
+
+			fmt.Fprintf(bw, "%s\t%d\t%d\t%s\t0\t%s\t%d\t%d\t0\t%d\t%s,\t%s,\n",
+				feature.Location.Chr,
+				start,
+				end,
+				name,
+				strand(feature),
+				start,
+				end,
+				len(sizes),
+				strings.Join(sizes, ","),
+				strings.Join(starts, ","))
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,237 @@
+package great
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/chromsizes"
+)
+
+// GREAT's default basal domain of 5kb upstream and 1kb downstream
+// of a TSS, extended up to 1Mb to the nearest basal domains
+const (
+	DEFAULT_BASAL_UPSTREAM   uint = 5000
+	DEFAULT_BASAL_DOWNSTREAM uint = 1000
+	DEFAULT_EXTENSION        uint = 1000000
+)
+
+type Gene struct {
+	Id     string `json:"id"`
+	Symbol string `json:"symbol"`
+	Chr    string `json:"chr"`
+	TSS    uint   `json:"tss"`
+	Strand string `json:"strand"`
+}
+
+// The regulatory domain of a gene, 1-based and inclusive
+type Domain struct {
+	Gene  *Gene
+	Start uint
+	End   uint
+}
+
+// Domains of one chromosome sorted by start. maxEnd[i] is the
+// furthest end of domains 0..i since domains can overlap.
+type chromDomains struct {
+	domains []*Domain
+	maxEnd  []uint
+}
+
+type Domains struct {
+	chroms map[string]*chromDomains
+	// domains of each gene symbol, in upper case
+	symbols map[string][]*Domain
+	// bases of the genome, for the chance of a region falling in
+	// a set of domains
+	genomeLen uint
+}
+
+// A gene a region is associated with and the distance from its TSS
+// to the middle of the region, negative if upstream
+type Association struct {
+	Gene *Gene `json:"gene"`
+	Dist int   `json:"dist"`
+}
+
+func basalDomain(gene *Gene, basal *dna.TSSRegion, size uint) (uint, uint) {
+	up := basal.Offset5P()
+	down := basal.Offset3P()
+
+	if gene.Strand == "-" {
+		up, down = down, up
+	}
+
+	start := uint(1)
+
+	if gene.TSS > up {
+		start = gene.TSS - up
+	}
+
+	return start, min(gene.TSS+down, size)
+}
+
+// Make the basal plus extension regulatory domains of genes. Each gene
+// has a basal domain around its TSS, whatever its neighbours, which is
+// extended in each direction until it reaches the basal domain of the
+// next gene, but by no more than extension bases. Genes on chromosomes
+// that are not in sizes are left out.
+func NewDomains(genes []*Gene, sizes []*chromsizes.Size, basal *dna.TSSRegion, extension uint) *Domains {
+	ret := Domains{chroms: make(map[string]*chromDomains), symbols: make(map[string][]*Domain)}
+
+	chromGenes := make(map[string][]*Gene)
+
+	for _, gene := range genes {
+		chromGenes[gene.Chr] = append(chromGenes[gene.Chr], gene)
+	}
+
+	for _, size := range sizes {
+		ret.genomeLen += size.Len
+
+		genes := chromGenes[size.Name]
+
+		if len(genes) == 0 {
+			continue
+		}
+
+		sort.Slice(genes, func(i, j int) bool {
+			return genes[i].TSS < genes[j].TSS
+		})
+
+		n := len(genes)
+
+		starts := make([]uint, n)
+		ends := make([]uint, n)
+
+		for i, gene := range genes {
+			starts[i], ends[i] = basalDomain(gene, basal, size.Len)
+		}
+
+		// furthest basal end of the genes before each gene and the
+		// nearest basal start of the genes after it
+		prevEnd := make([]uint, n)
+		nextStart := make([]uint, n)
+
+		for i := 1; i < n; i++ {
+			prevEnd[i] = max(prevEnd[i-1], ends[i-1])
+		}
+
+		nextStart[n-1] = size.Len + 1
+
+		for i := n - 2; i >= 0; i-- {
+			nextStart[i] = min(nextStart[i+1], starts[i+1])
+		}
+
+		blocks := chromDomains{domains: make([]*Domain, 0, n), maxEnd: make([]uint, n)}
+
+		for i, gene := range genes {
+			start := prevEnd[i] + 1
+
+			if gene.TSS > extension {
+				start = max(start, gene.TSS-extension)
+			}
+
+			end := min(nextStart[i]-1, gene.TSS+extension, size.Len)
+
+			domain := Domain{Gene: gene,
+				Start: min(start, starts[i]),
+				End:   max(end, ends[i])}
+
+			blocks.domains = append(blocks.domains, &domain)
+
+			symbol := strings.ToUpper(gene.Symbol)
+			ret.symbols[symbol] = append(ret.symbols[symbol], &domain)
+		}
+
+		sort.Slice(blocks.domains, func(i, j int) bool {
+			return blocks.domains[i].Start < blocks.domains[j].Start
+		})
+
+		var end uint
+
+		for i, domain := range blocks.domains {
+			end = max(end, domain.End)
+			blocks.maxEnd[i] = end
+		}
+
+		ret.chroms[size.Name] = &blocks
+	}
+
+	return &ret
+}
+
+func (domains *Domains) GenomeLen() uint {
+	return domains.genomeLen
+}
+
+// Genes whose domains contain the middle of a location
+func (domains *Domains) Associate(location *dna.Location) []*Association {
+	ret := make([]*Association, 0, 2)
+
+	blocks, ok := domains.chroms[location.Chr]
+
+	if !ok {
+		return ret
+	}
+
+	mid := (location.Start + location.End) / 2
+
+	i := sort.Search(len(blocks.maxEnd), func(i int) bool {
+		return blocks.maxEnd[i] >= mid
+	})
+
+	for ; i < len(blocks.domains) && blocks.domains[i].Start <= mid; i++ {
+		domain := blocks.domains[i]
+
+		if domain.End < mid {
+			continue
+		}
+
+		dist := int(mid) - int(domain.Gene.TSS)
+
+		if domain.Gene.Strand == "-" {
+			dist = -dist
+		}
+
+		ret = append(ret, &Association{Gene: domain.Gene, Dist: dist})
+	}
+
+	return ret
+}
+
+// Bases covered by a set of domains, counting bases in more than
+// one domain once
+func coverage(domains []*Domain) uint {
+	sorted := make([]*Domain, len(domains))
+	copy(sorted, domains)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Gene.Chr != sorted[j].Gene.Chr {
+			return sorted[i].Gene.Chr < sorted[j].Gene.Chr
+		}
+
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	var ret uint
+
+	var current *Domain
+
+	for _, domain := range sorted {
+		if current == nil || domain.Gene.Chr != current.Gene.Chr || domain.Start > current.End {
+			if current != nil {
+				ret += current.End - current.Start + 1
+			}
+
+			current = &Domain{Gene: domain.Gene, Start: domain.Start, End: domain.End}
+		} else {
+			current.End = max(current.End, domain.End)
+		}
+	}
+
+	if current != nil {
+		ret += current.End - current.Start + 1
+	}
+
+	return ret
+}
@@ -0,0 +1,90 @@
+package great
+
+import (
+	"testing"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/chromsizes"
+)
+
+// A is near the start of chr1, B is on the - strand and C is near
+// the end
+var testGenes = []*Gene{{Id: "A", Symbol: "A", Chr: "chr1", TSS: 3000, Strand: "+"},
+	{Id: "C", Symbol: "C", Chr: "chr1", TSS: 98000, Strand: "+"},
+	{Id: "B", Symbol: "B", Chr: "chr1", TSS: 50000, Strand: "-"},
+	{Id: "D", Symbol: "D", Chr: "chrUn", TSS: 100, Strand: "+"}}
+
+var testSizes = []*chromsizes.Size{{Name: "chr1", Len: 100000}, {Name: "chr2", Len: 50000}}
+
+type testAssociation struct {
+	id   string
+	dist int
+}
+
+func TestDomains(t *testing.T) {
+	basal := dna.NewTSSRegion(DEFAULT_BASAL_UPSTREAM, DEFAULT_BASAL_DOWNSTREAM)
+
+	tests := []struct {
+		name         string
+		extension    uint
+		location     *dna.Location
+		associations []testAssociation
+	}{
+		{name: "basal domain clipped at the chromosome start",
+			extension:    DEFAULT_EXTENSION,
+			location:     dna.NewLocation("chr1", 1, 1),
+			associations: []testAssociation{{"A", -2999}}},
+		{name: "extended to the next basal domain",
+			extension:    DEFAULT_EXTENSION,
+			location:     dna.NewLocation("chr1", 48999, 48999),
+			associations: []testAssociation{{"A", 45999}, {"B", 1001}}},
+		{name: "upstream of a minus strand gene",
+			extension:    DEFAULT_EXTENSION,
+			location:     dna.NewLocation("chr1", 59999, 60001),
+			associations: []testAssociation{{"B", -10000}, {"C", -38000}}},
+		{name: "extended to the chromosome end",
+			extension:    DEFAULT_EXTENSION,
+			location:     dna.NewLocation("chr1", 100000, 100000),
+			associations: []testAssociation{{"C", 2000}}},
+		{name: "beyond a short extension",
+			extension:    2000,
+			location:     dna.NewLocation("chr1", 47000, 47000),
+			associations: []testAssociation{}},
+		{name: "basal domain of a minus strand gene",
+			extension:    2000,
+			location:     dna.NewLocation("chr1", 48000, 48000),
+			associations: []testAssociation{{"B", 2000}}},
+		{name: "chromosome without genes",
+			extension:    DEFAULT_EXTENSION,
+			location:     dna.NewLocation("chr2", 100, 100),
+			associations: []testAssociation{}},
+		{name: "chromosome without a size",
+			extension:    DEFAULT_EXTENSION,
+			location:     dna.NewLocation("chrUn", 100, 100),
+			associations: []testAssociation{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			domains := NewDomains(testGenes, testSizes, basal, test.extension)
+
+			if domains.GenomeLen() != 150000 {
+				t.Errorf("genome length = %d, want 150000", domains.GenomeLen())
+			}
+
+			associations := domains.Associate(test.location)
+
+			if len(associations) != len(test.associations) {
+				t.Fatalf("got %d associations, want %d", len(associations), len(test.associations))
+			}
+
+			for i, association := range associations {
+				got := testAssociation{association.Gene.Id, association.Dist}
+
+				if got != test.associations[i] {
+					t.Errorf("association %d = %v, want %v", i, got, test.associations[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package great
+
+import (
+	"sort"
+	"strings"
+)
+
+// A gene set such as a pathway
+type Term struct {
+	Name  string
+	Genes []string
+}
+
+type Dataset struct {
+	Name  string
+	Terms []*Term
+}
+
+// How enriched the regions are near the genes of a term. The binomial
+// test asks if more regions are in the term's domains than their share
+// of the genome would give. The hypergeometric test asks if more of
+// the term's genes have regions than expected of genes in general.
+type TermResult struct {
+	Dataset string `json:"dataset"`
+	Term    string `json:"term"`
+	// regions in the domains of the term's genes
+	Regions         int     `json:"regions"`
+	ExpectedRegions float64 `json:"expectedRegions"`
+	RegionFold      float64 `json:"regionFold"`
+	BinomP          float64 `json:"binomP"`
+	BinomFDR        float64 `json:"binomFdr"`
+	// genes of the term with a domain, and those with regions
+	TermGenes     int      `json:"termGenes"`
+	Genes         []string `json:"genes"`
+	ExpectedGenes float64  `json:"expectedGenes"`
+	GeneFold      float64  `json:"geneFold"`
+	HyperP        float64  `json:"hyperP"`
+	HyperFDR      float64  `json:"hyperFdr"`
+}
+
+func fold(observed int, expected float64) float64 {
+	if expected == 0 {
+		return 0
+	}
+
+	return float64(observed) / expected
+}
+
+// Test each term of the datasets for enrichment of the regions, given
+// as the genes each region is associated with. Terms with no genes in
+// the domains are skipped. Results are ordered by binomial and then
+// hypergeometric p-value.
+func Enrichment(domains *Domains, associations [][]*Association, datasets []*Dataset) []*TermResult {
+	ret := make([]*TermResult, 0, 1000)
+
+	// regions near each gene
+	symbolRegions := make(map[string][]int)
+
+	for ri, genes := range associations {
+		for _, association := range genes {
+			symbol := strings.ToUpper(association.Gene.Symbol)
+			regions := symbolRegions[symbol]
+
+			// a region can be near more than one gene with the
+			// same symbol
+			if len(regions) > 0 && regions[len(regions)-1] == ri {
+				continue
+			}
+
+			symbolRegions[symbol] = append(regions, ri)
+		}
+	}
+
+	// genes are only counted for the hypergeometric test if some
+	// term could include them
+	universe := make(map[string]struct{})
+
+	for _, dataset := range datasets {
+		for _, term := range dataset.Terms {
+			for _, gene := range term.Genes {
+				symbol := strings.ToUpper(gene)
+
+				_, ok := domains.symbols[symbol]
+
+				if ok {
+					universe[symbol] = struct{}{}
+				}
+			}
+		}
+	}
+
+	hitGenes := 0
+
+	for symbol := range universe {
+		_, ok := symbolRegions[symbol]
+
+		if ok {
+			hitGenes++
+		}
+	}
+
+	n := len(associations)
+	genomeLen := float64(domains.genomeLen)
+
+	// marks the regions already counted for a term
+	seen := make([]int, n)
+	stamp := 0
+
+	for _, dataset := range datasets {
+		for _, term := range dataset.Terms {
+			stamp++
+
+			symbols := make(map[string]struct{}, len(term.Genes))
+			termDomains := make([]*Domain, 0, len(term.Genes))
+
+			for _, gene := range term.Genes {
+				symbol := strings.ToUpper(gene)
+
+				_, ok := symbols[symbol]
+
+				if ok {
+					continue
+				}
+
+				geneDomains, ok := domains.symbols[symbol]
+
+				if !ok {
+					continue
+				}
+
+				symbols[symbol] = struct{}{}
+				termDomains = append(termDomains, geneDomains...)
+			}
+
+			if len(symbols) == 0 {
+				continue
+			}
+
+			result := TermResult{Dataset: dataset.Name,
+				Term:      term.Name,
+				TermGenes: len(symbols),
+				Genes:     make([]string, 0, 10)}
+
+			for symbol := range symbols {
+				regions, ok := symbolRegions[symbol]
+
+				if !ok {
+					continue
+				}
+
+				result.Genes = append(result.Genes, symbol)
+
+				for _, ri := range regions {
+					if seen[ri] != stamp {
+						seen[ri] = stamp
+						result.Regions++
+					}
+				}
+			}
+
+			sort.Strings(result.Genes)
+
+			p := 0.0
+
+			if genomeLen > 0 {
+				p = float64(coverage(termDomains)) / genomeLen
+			}
+
+			result.ExpectedRegions = p * float64(n)
+			result.RegionFold = fold(result.Regions, result.ExpectedRegions)
+			result.BinomP = BinomialUpper(result.Regions, n, p)
+
+			result.ExpectedGenes = float64(hitGenes) * float64(result.TermGenes) / float64(len(universe))
+			result.GeneFold = fold(len(result.Genes), result.ExpectedGenes)
+			result.HyperP = HypergeometricUpper(len(result.Genes), len(universe), result.TermGenes, hitGenes)
+
+			ret = append(ret, &result)
+		}
+	}
+
+	binomP := make([]float64, len(ret))
+	hyperP := make([]float64, len(ret))
+
+	for i, result := range ret {
+		binomP[i] = result.BinomP
+		hyperP[i] = result.HyperP
+	}
+
+	binomFDR := FDR(binomP)
+	hyperFDR := FDR(hyperP)
+
+	for i, result := range ret {
+		result.BinomFDR = binomFDR[i]
+		result.HyperFDR = hyperFDR[i]
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].BinomP != ret[j].BinomP {
+			return ret[i].BinomP < ret[j].BinomP
+		}
+
+		return ret[i].HyperP < ret[j].HyperP
+	})
+
+	return ret
+}
@@ -0,0 +1,168 @@
+package great
+
+import (
+	"math"
+	"sort"
+)
+
+// log of n choose k
+func logChoose(n int, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+
+	return a - b - c
+}
+
+// Continued fraction of the incomplete beta function, from Numerical
+// Recipes
+func betacf(a float64, b float64, x float64) float64 {
+	const maxIter = 200
+	const eps = 3e-16
+	const fpmin = 1e-300
+
+	qab := a + b
+	qap := a + 1
+	qam := a - 1
+
+	c := 1.0
+	d := 1 - qab*x/qap
+
+	if math.Abs(d) < fpmin {
+		d = fpmin
+	}
+
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+
+		d = 1 + aa*d
+
+		if math.Abs(d) < fpmin {
+			d = fpmin
+		}
+
+		c = 1 + aa/c
+
+		if math.Abs(c) < fpmin {
+			c = fpmin
+		}
+
+		d = 1 / d
+		h *= d * c
+
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+
+		d = 1 + aa*d
+
+		if math.Abs(d) < fpmin {
+			d = fpmin
+		}
+
+		c = 1 + aa/c
+
+		if math.Abs(c) < fpmin {
+			c = fpmin
+		}
+
+		d = 1 / d
+		del := d * c
+		h *= del
+
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+
+	return h
+}
+
+// Regularized incomplete beta function I_x(a, b)
+func betai(a float64, b float64, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+
+	if x >= 1 {
+		return 1
+	}
+
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+
+	bt := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+
+	if x < (a+1)/(a+b+2) {
+		return bt * betacf(a, b, x) / a
+	}
+
+	return 1 - bt*betacf(b, a, 1-x)/b
+}
+
+// P(X >= k) for X ~ Binomial(n, p)
+func BinomialUpper(k int, n int, p float64) float64 {
+	if k <= 0 {
+		return 1
+	}
+
+	if k > n {
+		return 0
+	}
+
+	return betai(float64(k), float64(n-k+1), p)
+}
+
+// P(X >= k) when drawing n of N items, K of which are successes
+func HypergeometricUpper(k int, N int, K int, n int) float64 {
+	if k <= 0 {
+		return 1
+	}
+
+	if k > min(K, n) {
+		return 0
+	}
+
+	total := logChoose(N, n)
+
+	var ret float64
+
+	for i := k; i <= min(K, n); i++ {
+		ret += math.Exp(logChoose(K, i) + logChoose(N-K, n-i) - total)
+	}
+
+	return min(ret, 1)
+}
+
+// Benjamini-Hochberg adjusted p-values, in the order given
+func FDR(pvalues []float64) []float64 {
+	n := len(pvalues)
+
+	idx := make([]int, n)
+
+	for i := range idx {
+		idx[i] = i
+	}
+
+	sort.Slice(idx, func(i, j int) bool {
+		return pvalues[idx[i]] < pvalues[idx[j]]
+	})
+
+	ret := make([]float64, n)
+
+	q := 1.0
+
+	for r := n - 1; r >= 0; r-- {
+		i := idx[r]
+
+		q = min(q, pvalues[i]*float64(n)/float64(r+1))
+		ret[i] = q
+	}
+
+	return ret
+}
@@ -0,0 +1,96 @@
+package great
+
+import (
+	"math"
+	"testing"
+)
+
+const epsilon = 1e-9
+
+func TestBinomialUpper(t *testing.T) {
+	tests := []struct {
+		name string
+		k    int
+		n    int
+		p    float64
+		want float64
+	}{
+		{"none needed", 0, 10, 0.5, 1},
+		{"more than trials", 11, 10, 0.5, 0},
+		{"at least half of fair coins", 5, 10, 0.5, 638.0 / 1024},
+		{"all of fair coins", 10, 10, 0.5, 1.0 / 1024},
+		{"at least one rare event", 1, 20, 0.1, 1 - math.Pow(0.9, 20)},
+		{"impossible event", 1, 10, 0, 0},
+		{"certain event", 10, 10, 1, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := BinomialUpper(test.k, test.n, test.p)
+
+			if math.Abs(p-test.want) > epsilon {
+				t.Errorf("BinomialUpper(%d, %d, %v) = %v, want %v", test.k, test.n, test.p, p, test.want)
+			}
+		})
+	}
+}
+
+func TestHypergeometricUpper(t *testing.T) {
+	tests := []struct {
+		name string
+		k    int
+		N    int
+		K    int
+		n    int
+		want float64
+	}{
+		{"none needed", 0, 10, 5, 5, 1},
+		{"more than the successes", 6, 10, 5, 8, 0},
+		{"more than drawn", 6, 10, 8, 5, 0},
+		{"every success drawn", 5, 10, 5, 5, 1.0 / 252},
+		{"at least one success", 1, 10, 5, 5, 251.0 / 252},
+		// C(4,2)C(6,1)/C(10,3) + C(4,3)/C(10,3)
+		{"at least two of three", 2, 10, 4, 3, (36.0 + 4) / 120},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := HypergeometricUpper(test.k, test.N, test.K, test.n)
+
+			if math.Abs(p-test.want) > epsilon {
+				t.Errorf("HypergeometricUpper(%d, %d, %d, %d) = %v, want %v", test.k, test.N, test.K, test.n, p, test.want)
+			}
+		})
+	}
+}
+
+func TestFDR(t *testing.T) {
+	tests := []struct {
+		name    string
+		pvalues []float64
+		want    []float64
+	}{
+		{"empty", []float64{}, []float64{}},
+		{"one", []float64{0.03}, []float64{0.03}},
+		{"kept in the order given", []float64{0.01, 0.04, 0.03, 0.005}, []float64{0.02, 0.04, 0.04, 0.02}},
+		{"monotone", []float64{0.04, 0.01, 0.011}, []float64{0.04, 0.0165, 0.0165}},
+		{"capped at 1", []float64{0.9, 0.8}, []float64{0.9, 0.9}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := FDR(test.pvalues)
+
+			if len(q) != len(test.want) {
+				t.Fatalf("got %d values, want %d", len(q), len(test.want))
+			}
+
+			for i := range q {
+				if math.Abs(q[i]-test.want[i]) > epsilon {
+					t.Errorf("FDR = %v, want %v", q, test.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,468 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	STATUS_QUEUED    = "queued"
+	STATUS_RUNNING   = "running"
+	STATUS_DONE      = "done"
+	STATUS_FAILED    = "failed"
+	STATUS_CANCELLED = "cancelled"
+)
+
+// how often expired jobs are removed
+const CLEANUP_INTERVAL = 10 * time.Minute
+
+// max length of an error body kept when a job fails
+const MAX_ERROR_LEN = 1024
+
+var (
+	ErrQueueFull    = errors.New("job queue is full, try again later")
+	ErrTooManyJobs  = errors.New("too many jobs in progress")
+	ErrJobFinished  = errors.New("job has already finished")
+	ErrShuttingDown = errors.New("server is shutting down")
+)
+
+type Job struct {
+	Id     string `json:"id"`
+	UserId string `json:"-"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	// fraction complete between 0 and 1, if the handler
+	// reports it
+	Progress    float64   `json:"progress"`
+	Error       string    `json:"error,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func (job *Job) IsFinished() bool {
+	return job.Status == STATUS_DONE ||
+		job.Status == STATUS_FAILED ||
+		job.Status == STATUS_CANCELLED
+}
+
+// a job waiting for or being run by a worker
+type task struct {
+	mu  sync.Mutex
+	job *Job
+	req *http.Request
+	// who submitted the job, as set by the auth middleware
+	user   any
+	cancel context.CancelFunc
+}
+
+// copy of the job that is safe to hand out
+func (t *task) snapshot() *Job {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job := *t.job
+
+	return &job
+}
+
+type jobKey struct{}
+
+// Runs module requests in the background on a bounded pool of
+// workers. Requests are replayed through the router so jobs go
+// through the same auth and handlers as a normal request.
+type JobManager struct {
+	handler    http.Handler
+	store      *Store
+	queue      chan *task
+	workers    int
+	maxPerUser int
+	retention  time.Duration
+
+	mu      sync.Mutex
+	active  map[string]*task
+	perUser map[string]int
+	closed  bool
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewJobManager(handler http.Handler, cfg *config.Config) (*JobManager, error) {
+	store, err := NewStore(cfg.Jobs.File)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &JobManager{handler: handler,
+		store:      store,
+		queue:      make(chan *task, max(cfg.Jobs.QueueSize, 1)),
+		workers:    max(cfg.Jobs.Workers, 1),
+		maxPerUser: cfg.Jobs.MaxPerUser,
+		retention:  cfg.JobRetention(),
+		active:     make(map[string]*task),
+		perUser:    make(map[string]int),
+		ctx:        ctx,
+		cancel:     cancel}, nil
+}
+
+// Start the workers and the cleanup of expired jobs
+func (manager *JobManager) Start() {
+	for range manager.workers {
+		manager.wg.Add(1)
+
+		go func() {
+			defer manager.wg.Done()
+
+			for t := range manager.queue {
+				manager.run(t)
+			}
+		}()
+	}
+
+	manager.wg.Add(1)
+
+	go func() {
+		defer manager.wg.Done()
+
+		ticker := time.NewTicker(CLEANUP_INTERVAL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-manager.ctx.Done():
+				return
+			case <-ticker.C:
+				manager.cleanup()
+			}
+		}
+	}()
+}
+
+// Cancel running jobs and wait for the workers to stop
+func (manager *JobManager) Close() error {
+	manager.mu.Lock()
+
+	if manager.closed {
+		manager.mu.Unlock()
+		return nil
+	}
+
+	manager.closed = true
+	close(manager.queue)
+	manager.mu.Unlock()
+
+	manager.cancel()
+	manager.wg.Wait()
+
+	return manager.store.Close()
+}
+
+// Queue a request to be run as a job on behalf of a user. The user
+// is what the auth middleware set for the submit request, which
+// includes their roles, and is used to authorize the job rather
+// than the caller's credentials since a token may expire while the
+// job is queued.
+func (manager *JobManager) Submit(userId string, user any, req *http.Request) (*Job, error) {
+	now := time.Now()
+
+	job := &Job{Id: uuid.NewString(),
+		UserId:    userId,
+		Method:    req.Method,
+		Path:      req.URL.RequestURI(),
+		Status:    STATUS_QUEUED,
+		CreatedAt: now,
+		UpdatedAt: now}
+
+	ctx, cancel := context.WithCancel(manager.ctx)
+
+	t := &task{job: job, user: user, cancel: cancel}
+
+	t.req = req.WithContext(context.WithValue(ctx, jobKey{}, t))
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	if manager.closed {
+		cancel()
+		return nil, ErrShuttingDown
+	}
+
+	if manager.maxPerUser > 0 && manager.perUser[userId] >= manager.maxPerUser {
+		cancel()
+		return nil, ErrTooManyJobs
+	}
+
+	err := manager.store.Add(job)
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	select {
+	case manager.queue <- t:
+	default:
+		cancel()
+		manager.store.Delete(userId, job.Id)
+		return nil, ErrQueueFull
+	}
+
+	manager.active[job.Id] = t
+	manager.perUser[userId]++
+
+	return t.snapshot(), nil
+}
+
+// Returns the latest state of a job, including the progress of
+// running jobs
+func (manager *JobManager) Job(userId string, id string) (*Job, error) {
+	manager.mu.Lock()
+	t, ok := manager.active[id]
+	manager.mu.Unlock()
+
+	if ok {
+		job := t.snapshot()
+
+		if job.UserId == userId {
+			return job, nil
+		}
+
+		return nil, ErrJobNotFound
+	}
+
+	return manager.store.Job(userId, id)
+}
+
+func (manager *JobManager) Jobs(userId string) ([]*Job, error) {
+	jobs, err := manager.store.Jobs(userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	for i, job := range jobs {
+		if t, ok := manager.active[job.Id]; ok {
+			jobs[i] = t.snapshot()
+		}
+	}
+
+	return jobs, nil
+}
+
+func (manager *JobManager) Result(userId string, id string) (string, []byte, error) {
+	return manager.store.Result(userId, id)
+}
+
+// Stop a queued or running job
+func (manager *JobManager) Cancel(userId string, id string) error {
+	manager.mu.Lock()
+	t, ok := manager.active[id]
+	manager.mu.Unlock()
+
+	if !ok || t.snapshot().UserId != userId {
+		_, err := manager.store.Job(userId, id)
+
+		if err != nil {
+			return err
+		}
+
+		return ErrJobFinished
+	}
+
+	t.cancel()
+
+	return nil
+}
+
+// Remove a finished job and its result
+func (manager *JobManager) Delete(userId string, id string) error {
+	job, err := manager.Job(userId, id)
+
+	if err != nil {
+		return err
+	}
+
+	if !job.IsFinished() {
+		return fmt.Errorf("job is %s, cancel it first", job.Status)
+	}
+
+	return manager.store.Delete(userId, id)
+}
+
+func (manager *JobManager) run(t *task) {
+	defer manager.finish(t)
+
+	ctx := t.req.Context()
+
+	if ctx.Err() != nil {
+		manager.update(t, STATUS_CANCELLED, "")
+		return
+	}
+
+	manager.update(t, STATUS_RUNNING, "")
+
+	w := newResultWriter()
+
+	manager.handler.ServeHTTP(w, t.req)
+
+	switch {
+	case ctx.Err() != nil:
+		manager.update(t, STATUS_CANCELLED, "")
+	case w.status >= http.StatusBadRequest:
+		body := w.buf.String()
+
+		if len(body) > MAX_ERROR_LEN {
+			body = body[:MAX_ERROR_LEN]
+		}
+
+		manager.update(t, STATUS_FAILED, fmt.Sprintf("%d %s: %s", w.status, http.StatusText(w.status), body))
+	default:
+		t.mu.Lock()
+		t.job.Status = STATUS_DONE
+		t.job.Progress = 1
+		t.job.ContentType = w.Header().Get("Content-Type")
+		t.job.UpdatedAt = time.Now()
+		job := *t.job
+		t.mu.Unlock()
+
+		err := manager.store.SaveResult(&job, w.buf.Bytes())
+
+		if err != nil {
+			log.Error().Msgf("job %s: error saving result: %s", job.Id, err)
+			manager.update(t, STATUS_FAILED, "error saving result")
+		}
+	}
+}
+
+func (manager *JobManager) update(t *task, status string, errMsg string) {
+	t.mu.Lock()
+	t.job.Status = status
+	t.job.Error = errMsg
+	t.job.UpdatedAt = time.Now()
+	job := *t.job
+	t.mu.Unlock()
+
+	err := manager.store.UpdateStatus(&job)
+
+	if err != nil {
+		log.Error().Msgf("job %s: error updating status: %s", job.Id, err)
+	}
+}
+
+func (manager *JobManager) finish(t *task) {
+	t.cancel()
+
+	job := t.snapshot()
+
+	manager.mu.Lock()
+	delete(manager.active, job.Id)
+	manager.perUser[job.UserId]--
+
+	if manager.perUser[job.UserId] <= 0 {
+		delete(manager.perUser, job.UserId)
+	}
+
+	manager.mu.Unlock()
+}
+
+func (manager *JobManager) cleanup() {
+	n, err := manager.store.DeleteExpired(time.Now().Add(-manager.retention))
+
+	if err != nil {
+		log.Error().Msgf("error removing expired jobs: %s", err)
+		return
+	}
+
+	if n > 0 {
+		log.Info().Msgf("removed %d expired jobs", n)
+	}
+}
+
+// Returns true if the request is being run as a job so handlers
+// can lift limits meant to keep interactive requests fast
+func IsJob(c *gin.Context) bool {
+	return c.Request.Context().Value(jobKey{}) != nil
+}
+
+// Authenticate jobs as the user who submitted them and other
+// requests with the given middleware, e.g. one that checks a JWT.
+// Middleware after this, such as role checks, sees the same user
+// either way.
+func Authenticate(authenticate gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, ok := c.Request.Context().Value(jobKey{}).(*task)
+
+		if ok && t.user != nil {
+			c.Set(web.SESSION_USER, t.user)
+			return
+		}
+
+		authenticate(c)
+	}
+}
+
+// Report how far through a job a handler is. Does nothing for
+// requests that are not jobs.
+func SetProgress(c *gin.Context, done int, total int) {
+	t, ok := c.Request.Context().Value(jobKey{}).(*task)
+
+	if !ok || total <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	t.job.Progress = min(float64(done)/float64(total), 1)
+	t.mu.Unlock()
+}
+
+// Collects the response of a job in memory
+type resultWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+	status int
+}
+
+func newResultWriter() *resultWriter {
+	return &resultWriter{header: make(http.Header)}
+}
+
+func (w *resultWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *resultWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.buf.Write(b)
+}
+
+func (w *resultWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// streaming handlers flush as they go
+func (w *resultWriter) Flush() {}
@@ -0,0 +1,260 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-web"
+	"github.com/antonybholmes/go-web/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// only module requests can be run as jobs
+const JOB_PATH_PREFIX = "/modules/"
+
+// headers copied from the submit request. Credentials are not
+// copied since jobs are authorized as the user who submitted them.
+var forwardHeaders = []string{"Accept"}
+
+type JobReq struct {
+	// defaults to POST
+	Method string `json:"method"`
+	// path of the module route including any query,
+	// e.g. /modules/genome/annotate/grch38?n=5
+	Path string          `json:"path"`
+	Body json.RawMessage `json:"body"`
+}
+
+func userId(c *gin.Context) (string, error) {
+	user, ok := c.Get(web.SESSION_USER)
+
+	if !ok {
+		return "", fmt.Errorf("no user")
+	}
+
+	switch u := user.(type) {
+	case *auth.TokenClaims:
+		return u.UserId, nil
+	case *auth.AuthUser:
+		return u.PublicId, nil
+	default:
+		return "", fmt.Errorf("no user")
+	}
+}
+
+func errorResp(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": err.Error()})
+	case errors.Is(err, ErrTooManyJobs):
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"status": http.StatusTooManyRequests, "message": err.Error()})
+	case errors.Is(err, ErrQueueFull), errors.Is(err, ErrShuttingDown):
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"status": http.StatusServiceUnavailable, "message": err.Error()})
+	default:
+		c.Error(err)
+	}
+}
+
+func (manager *JobManager) SubmitRoute(c *gin.Context) {
+	id, err := userId(c)
+
+	if err != nil {
+		web.UnauthorizedResp(c, err.Error())
+		return
+	}
+
+	var req JobReq
+
+	err = c.ShouldBindJSON(&req)
+
+	if err != nil {
+		web.BadReqResp(c, err.Error())
+		return
+	}
+
+	method := strings.ToUpper(req.Method)
+
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	if method != http.MethodGet && method != http.MethodPost {
+		web.BadReqResp(c, "method must be GET or POST")
+		return
+	}
+
+	if !strings.HasPrefix(req.Path, JOB_PATH_PREFIX) {
+		web.BadReqResp(c, fmt.Sprintf("path must start with %s", JOB_PATH_PREFIX))
+		return
+	}
+
+	jobReq, err := http.NewRequest(method, req.Path, bytes.NewReader(req.Body))
+
+	if err != nil {
+		web.BadReqResp(c, err.Error())
+		return
+	}
+
+	jobReq.Header.Set("Content-Type", "application/json")
+
+	for _, header := range forwardHeaders {
+		for _, v := range c.Request.Header.Values(header) {
+			jobReq.Header.Add(header, v)
+		}
+	}
+
+	jobReq.RemoteAddr = c.Request.RemoteAddr
+
+	// job spans join the trace of the submit request
+	tracing.Inject(c.Request.Context(), jobReq.Header)
+
+	user, _ := c.Get(web.SESSION_USER)
+
+	job, err := manager.Submit(id, user, jobReq)
+
+	if err != nil {
+		errorResp(c, err)
+		return
+	}
+
+	web.MakeDataResp(c, "", job)
+}
+
+func (manager *JobManager) JobsRoute(c *gin.Context) {
+	user, err := userId(c)
+
+	if err != nil {
+		web.UnauthorizedResp(c, err.Error())
+		return
+	}
+
+	jobs, err := manager.Jobs(user)
+
+	if err != nil {
+		errorResp(c, err)
+		return
+	}
+
+	web.MakeDataResp(c, "", jobs)
+}
+
+func (manager *JobManager) JobRoute(c *gin.Context) {
+	user, err := userId(c)
+
+	if err != nil {
+		web.UnauthorizedResp(c, err.Error())
+		return
+	}
+
+	job, err := manager.Job(user, c.Param("id"))
+
+	if err != nil {
+		errorResp(c, err)
+		return
+	}
+
+	web.MakeDataResp(c, "", job)
+}
+
+// Returns the response of a finished job exactly as the module
+// route produced it. Add ?download=true to save it as a file.
+func (manager *JobManager) ResultRoute(c *gin.Context) {
+	user, err := userId(c)
+
+	if err != nil {
+		web.UnauthorizedResp(c, err.Error())
+		return
+	}
+
+	id := c.Param("id")
+
+	contentType, result, err := manager.Result(user, id)
+
+	if err != nil {
+		errorResp(c, err)
+		return
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"job-%s%s\"", id, extension(contentType)))
+	}
+
+	c.Data(http.StatusOK, contentType, result)
+}
+
+func (manager *JobManager) CancelRoute(c *gin.Context) {
+	user, err := userId(c)
+
+	if err != nil {
+		web.UnauthorizedResp(c, err.Error())
+		return
+	}
+
+	err = manager.Cancel(user, c.Param("id"))
+
+	if errors.Is(err, ErrJobFinished) {
+		web.BadReqResp(c, err.Error())
+		return
+	}
+
+	if err != nil {
+		errorResp(c, err)
+		return
+	}
+
+	web.MakeOkResp(c, "job cancelled")
+}
+
+func (manager *JobManager) DeleteRoute(c *gin.Context) {
+	user, err := userId(c)
+
+	if err != nil {
+		web.UnauthorizedResp(c, err.Error())
+		return
+	}
+
+	err = manager.Delete(user, c.Param("id"))
+
+	if errors.Is(err, ErrJobNotFound) {
+		errorResp(c, err)
+		return
+	}
+
+	if err != nil {
+		web.BadReqResp(c, err.Error())
+		return
+	}
+
+	web.MakeOkResp(c, "job deleted")
+}
+
+func extension(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil {
+		return ""
+	}
+
+	switch mediaType {
+	case "application/json":
+		return ".json"
+	case "application/x-ndjson":
+		return ".ndjson"
+	case "text/csv":
+		return ".csv"
+	case "text/tab-separated-values", "text/plain":
+		return ".tsv"
+	default:
+		return ""
+	}
+}
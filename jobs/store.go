@@ -0,0 +1,223 @@
+package jobs
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const CREATE_JOBS_TABLE_SQL = `CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	path TEXT NOT NULL,
+	status TEXT NOT NULL,
+	progress REAL NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	content_type TEXT NOT NULL DEFAULT '',
+	result BLOB,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL);
+CREATE INDEX IF NOT EXISTS jobs_user_id_idx ON jobs (user_id, created_at);`
+
+const INSERT_JOB_SQL = `INSERT INTO jobs (id, user_id, method, path, status, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+const UPDATE_STATUS_SQL = `UPDATE jobs SET status = ?, progress = ?, error = ?, updated_at = ? WHERE id = ?`
+
+const UPDATE_RESULT_SQL = `UPDATE jobs SET status = ?, progress = 1, content_type = ?, result = ?, updated_at = ? WHERE id = ?`
+
+const SELECT_JOB_SQL = `SELECT id, user_id, method, path, status, progress, error, content_type, created_at, updated_at
+	FROM jobs WHERE id = ? AND user_id = ?`
+
+const SELECT_JOBS_SQL = `SELECT id, user_id, method, path, status, progress, error, content_type, created_at, updated_at
+	FROM jobs WHERE user_id = ? ORDER BY created_at DESC`
+
+const SELECT_RESULT_SQL = `SELECT content_type, result FROM jobs WHERE id = ? AND user_id = ? AND status = ?`
+
+const DELETE_JOB_SQL = `DELETE FROM jobs WHERE id = ? AND user_id = ?`
+
+const DELETE_EXPIRED_SQL = `DELETE FROM jobs WHERE status IN (?, ?, ?) AND updated_at < ?`
+
+// jobs interrupted by a restart cannot be resumed
+const FAIL_UNFINISHED_SQL = `UPDATE jobs SET status = ?, error = ?, updated_at = ? WHERE status IN (?, ?)`
+
+var ErrJobNotFound = errors.New("job not found")
+
+// Persists jobs and their results so they survive restarts and
+// can be fetched after the request that created them
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(file string) (*Store, error) {
+	db, err := sql.Open("sqlite3", file+"?_journal_mode=WAL&_busy_timeout=5000")
+
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite only supports one writer
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(CREATE_JOBS_TABLE_SQL)
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	_, err = db.Exec(FAIL_UNFINISHED_SQL,
+		STATUS_FAILED,
+		"server restarted",
+		time.Now().Unix(),
+		STATUS_QUEUED,
+		STATUS_RUNNING)
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+func (store *Store) Add(job *Job) error {
+	_, err := store.db.Exec(INSERT_JOB_SQL,
+		job.Id,
+		job.UserId,
+		job.Method,
+		job.Path,
+		job.Status,
+		job.CreatedAt.Unix(),
+		job.UpdatedAt.Unix())
+
+	return err
+}
+
+func (store *Store) UpdateStatus(job *Job) error {
+	_, err := store.db.Exec(UPDATE_STATUS_SQL,
+		job.Status,
+		job.Progress,
+		job.Error,
+		job.UpdatedAt.Unix(),
+		job.Id)
+
+	return err
+}
+
+func (store *Store) SaveResult(job *Job, result []byte) error {
+	_, err := store.db.Exec(UPDATE_RESULT_SQL,
+		job.Status,
+		job.ContentType,
+		result,
+		job.UpdatedAt.Unix(),
+		job.Id)
+
+	return err
+}
+
+func (store *Store) Job(userId string, id string) (*Job, error) {
+	job, err := scanJob(store.db.QueryRow(SELECT_JOB_SQL, id, userId))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+
+	return job, err
+}
+
+func (store *Store) Jobs(userId string) ([]*Job, error) {
+	rows, err := store.db.Query(SELECT_JOBS_SQL, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ret := make([]*Job, 0, 10)
+
+	for rows.Next() {
+		job, err := scanJob(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, job)
+	}
+
+	return ret, rows.Err()
+}
+
+// Returns the content type and body of a finished job
+func (store *Store) Result(userId string, id string) (string, []byte, error) {
+	var contentType string
+	var result []byte
+
+	err := store.db.QueryRow(SELECT_RESULT_SQL, id, userId, STATUS_DONE).Scan(&contentType, &result)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, ErrJobNotFound
+	}
+
+	return contentType, result, err
+}
+
+func (store *Store) Delete(userId string, id string) error {
+	_, err := store.db.Exec(DELETE_JOB_SQL, id, userId)
+
+	return err
+}
+
+// Remove finished jobs last updated before t
+func (store *Store) DeleteExpired(t time.Time) (int64, error) {
+	res, err := store.db.Exec(DELETE_EXPIRED_SQL,
+		STATUS_DONE,
+		STATUS_FAILED,
+		STATUS_CANCELLED,
+		t.Unix())
+
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*Job, error) {
+	var job Job
+	var createdAt int64
+	var updatedAt int64
+
+	err := row.Scan(&job.Id,
+		&job.UserId,
+		&job.Method,
+		&job.Path,
+		&job.Status,
+		&job.Progress,
+		&job.Error,
+		&job.ContentType,
+		&createdAt,
+		&updatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	job.CreatedAt = time.Unix(createdAt, 0)
+	job.UpdatedAt = time.Unix(updatedAt, 0)
+
+	return &job, nil
+}
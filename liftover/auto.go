@@ -0,0 +1,88 @@
+package liftover
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// Routes with an :assembly accept ?liftover=<assembly> to say their
+// locations are in another assembly
+const AUTO_LIFTOVER_PARAM = "liftover"
+
+// Read ?minMatch= as a fraction from 0 to 1
+func ParseMinMatch(c *gin.Context) (float64, error) {
+	v := c.Query("minMatch")
+
+	if v == "" {
+		return DEFAULT_MIN_MATCH, nil
+	}
+
+	minMatch, err := strconv.ParseFloat(v, 64)
+
+	if err != nil || minMatch < 0 || minMatch > 1 {
+		return 0, fmt.Errorf("%s is an invalid minMatch", v)
+	}
+
+	return minMatch, nil
+}
+
+// Lift locations to a route's :assembly if ?liftover= names the
+// assembly they are in, otherwise nil is returned. Locations that
+// do not map to one place are an error since anything found for
+// them would be for the wrong part of the genome.
+func AutoLift(c *gin.Context, locations []*dna.Location) ([]*Mapping, error) {
+	from := c.Query(AUTO_LIFTOVER_PARAM)
+	to := c.Param("assembly")
+
+	if from == "" || to == "" {
+		return nil, nil
+	}
+
+	minMatch, err := ParseMinMatch(c)
+
+	if err != nil {
+		return nil, err
+	}
+
+	span := tracing.Start(c, "liftover.Lift")
+	results, err := Lift(from, to, locations, minMatch)
+	tracing.End(span, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*Mapping, 0, len(results))
+
+	for _, result := range results {
+		if result.Status != STATUS_MAPPED {
+			return nil, fmt.Errorf("%s could not be lifted from %s to %s: %s", result.Location, from, to, result.Reason)
+		}
+
+		ret = append(ret, result.Mapped[0])
+	}
+
+	return ret, nil
+}
+
+// Same as AutoLift for routes that only need locations, which are
+// returned unchanged if there is nothing to lift
+func AutoLiftLocations(c *gin.Context, locations []*dna.Location) ([]*dna.Location, error) {
+	mapped, err := AutoLift(c, locations)
+
+	if err != nil || mapped == nil {
+		return locations, err
+	}
+
+	ret := make([]*dna.Location, len(mapped))
+
+	for mi, m := range mapped {
+		ret[mi] = m.Location
+	}
+
+	return ret, nil
+}
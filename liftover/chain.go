@@ -0,0 +1,224 @@
+package liftover
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An ungapped block of a chain. Coordinates are 0-based and half
+// open. Query coordinates are on the query strand, as in the chain
+// file, so they count from the end of the chromosome on the - strand.
+type block struct {
+	tStart uint
+	tEnd   uint
+	qStart uint
+	chain  *chain
+}
+
+type chain struct {
+	id      int
+	qName   string
+	qSize   uint
+	qStrand string
+}
+
+// Blocks of one reference chromosome sorted by start. maxEnd[i] is
+// the furthest end of blocks 0..i so that overlapping blocks can be
+// found with a binary search even if chains overlap.
+type chromBlocks struct {
+	blocks []*block
+	maxEnd []uint
+}
+
+// The chains for lifting from one assembly to another
+type Chains struct {
+	chroms map[string]*chromBlocks
+}
+
+// Read a UCSC chain file, which can be gzipped
+func ReadChains(file string) (*Chains, error) {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var r io.Reader = f
+
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer gz.Close()
+
+		r = gz
+	}
+
+	return ParseChains(r)
+}
+
+func ParseChains(r io.Reader) (*Chains, error) {
+	ret := Chains{chroms: make(map[string]*chromBlocks)}
+
+	scanner := bufio.NewScanner(r)
+
+	line := 0
+
+	var current *chain
+	var blocks *chromBlocks
+	var t, q uint
+
+	parse := func(s string) (uint, error) {
+		n, err := strconv.ParseUint(s, 10, 0)
+
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %s is not a number", line, s)
+		}
+
+		return uint(n), nil
+	}
+
+	for scanner.Scan() {
+		line++
+
+		tokens := strings.Fields(scanner.Text())
+
+		if len(tokens) == 0 || strings.HasPrefix(tokens[0], "#") {
+			continue
+		}
+
+		if tokens[0] == "chain" {
+			// chain score tName tSize tStrand tStart tEnd qName qSize
+			// qStrand qStart qEnd id
+			if len(tokens) < 13 {
+				return nil, fmt.Errorf("line %d: a chain header needs 13 fields", line)
+			}
+
+			tStart, err := parse(tokens[5])
+
+			if err != nil {
+				return nil, err
+			}
+
+			qSize, err := parse(tokens[8])
+
+			if err != nil {
+				return nil, err
+			}
+
+			qStart, err := parse(tokens[10])
+
+			if err != nil {
+				return nil, err
+			}
+
+			id, err := strconv.Atoi(tokens[12])
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s is an invalid id", line, tokens[12])
+			}
+
+			current = &chain{id: id, qName: tokens[7], qSize: qSize, qStrand: tokens[9]}
+
+			var ok bool
+
+			blocks, ok = ret.chroms[tokens[2]]
+
+			if !ok {
+				blocks = &chromBlocks{blocks: make([]*block, 0, 1000)}
+				ret.chroms[tokens[2]] = blocks
+			}
+
+			t = tStart
+			q = qStart
+
+			continue
+		}
+
+		if current == nil {
+			return nil, fmt.Errorf("line %d: alignment data before a chain header", line)
+		}
+
+		// size [dt dq], the last block of a chain has no gaps
+		size, err := parse(tokens[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		blocks.blocks = append(blocks.blocks, &block{tStart: t, tEnd: t + size, qStart: q, chain: current})
+
+		t += size
+		q += size
+
+		if len(tokens) >= 3 {
+			dt, err := parse(tokens[1])
+
+			if err != nil {
+				return nil, err
+			}
+
+			dq, err := parse(tokens[2])
+
+			if err != nil {
+				return nil, err
+			}
+
+			t += dt
+			q += dq
+		} else {
+			current = nil
+		}
+	}
+
+	err := scanner.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, blocks := range ret.chroms {
+		sort.Slice(blocks.blocks, func(i, j int) bool {
+			return blocks.blocks[i].tStart < blocks.blocks[j].tStart
+		})
+
+		blocks.maxEnd = make([]uint, len(blocks.blocks))
+
+		var end uint
+
+		for i, b := range blocks.blocks {
+			end = max(end, b.tEnd)
+			blocks.maxEnd[i] = end
+		}
+	}
+
+	return &ret, nil
+}
+
+// Blocks overlapping 0-based [start, end)
+func (blocks *chromBlocks) overlapping(start uint, end uint) []*block {
+	ret := make([]*block, 0, 10)
+
+	i := sort.Search(len(blocks.maxEnd), func(i int) bool {
+		return blocks.maxEnd[i] > start
+	})
+
+	for ; i < len(blocks.blocks) && blocks.blocks[i].tStart < end; i++ {
+		if blocks.blocks[i].tEnd > start {
+			ret = append(ret, blocks.blocks[i])
+		}
+	}
+
+	return ret
+}
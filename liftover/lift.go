@@ -0,0 +1,140 @@
+package liftover
+
+import (
+	"math"
+	"sort"
+
+	"github.com/antonybholmes/go-dna"
+)
+
+const (
+	STATUS_MAPPED = "mapped"
+	STATUS_SPLIT  = "split"
+	STATUS_FAILED = "failed"
+)
+
+// Fraction of bases that must map, as with the UCSC liftOver
+// -minMatch default
+const DEFAULT_MIN_MATCH = 0.95
+
+type Mapping struct {
+	Location *dna.Location `json:"location"`
+	// strand of the new assembly the location maps to
+	Strand string `json:"strand"`
+	// fraction of the original bases that map here
+	Match float64 `json:"match"`
+}
+
+// A location maps if enough of its bases lift through one chain, or
+// is split if they do through several. Every place its bases go is
+// listed, best first, so that slivers through other chains can be
+// seen, but only those with enough bases count.
+type Result struct {
+	Location *dna.Location `json:"location"`
+	Status   string        `json:"status"`
+	Mapped   []*Mapping    `json:"mapped"`
+	Reason   string        `json:"reason,omitempty"`
+}
+
+type piece struct {
+	chain *chain
+	start uint
+	end   uint
+	bases uint
+}
+
+// Lift a location. Like UCSC liftOver, a location that maps through
+// one chain becomes the span of its mapped bases, which can include
+// small insertions in the new assembly.
+func (chains *Chains) Lift(location *dna.Location, minMatch float64) *Result {
+	ret := Result{Location: location, Mapped: make([]*Mapping, 0, 1)}
+
+	blocks, ok := chains.chroms[location.Chr]
+
+	if !ok {
+		ret.Status = STATUS_FAILED
+		ret.Reason = "chromosome not in chain file"
+		return &ret
+	}
+
+	start := max(location.Start, 1) - 1
+	end := location.End
+
+	if end <= start {
+		ret.Status = STATUS_FAILED
+		ret.Reason = "empty location"
+		return &ret
+	}
+
+	pieces := make(map[*chain]*piece)
+
+	for _, b := range blocks.overlapping(start, end) {
+		s := max(start, b.tStart)
+		e := min(end, b.tEnd)
+
+		qs := b.qStart + s - b.tStart
+		qe := qs + e - s
+
+		// - strand coordinates count from the end
+		if b.chain.qStrand == "-" {
+			qs, qe = b.chain.qSize-qe, b.chain.qSize-qs
+		}
+
+		p, ok := pieces[b.chain]
+
+		if !ok {
+			pieces[b.chain] = &piece{chain: b.chain, start: qs, end: qe, bases: e - s}
+			continue
+		}
+
+		p.start = min(p.start, qs)
+		p.end = max(p.end, qe)
+		p.bases += e - s
+	}
+
+	if len(pieces) == 0 {
+		ret.Status = STATUS_FAILED
+		ret.Reason = "deleted in new assembly"
+		return &ret
+	}
+
+	sorted := make([]*piece, 0, len(pieces))
+
+	for _, p := range pieces {
+		sorted = append(sorted, p)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].bases != sorted[j].bases {
+			return sorted[i].bases > sorted[j].bases
+		}
+
+		return sorted[i].chain.id < sorted[j].chain.id
+	})
+
+	length := float64(end - start)
+
+	for _, p := range sorted {
+		ret.Mapped = append(ret.Mapped, &Mapping{Location: dna.NewLocation(p.chain.qName, p.start+1, p.end),
+			Strand: p.chain.qStrand,
+			Match:  math.Round(float64(p.bases)/length*10000) / 10000})
+	}
+
+	if float64(sorted[0].bases)/length < minMatch {
+		ret.Status = STATUS_FAILED
+		ret.Reason = "partially deleted in new assembly"
+		return &ret
+	}
+
+	// only split if other chains also carry enough of the
+	// location, as with liftOver -multiple
+	if len(sorted) > 1 && float64(sorted[1].bases)/length >= minMatch {
+		ret.Status = STATUS_SPLIT
+		ret.Reason = "split in new assembly"
+		return &ret
+	}
+
+	ret.Status = STATUS_MAPPED
+
+	return &ret
+}
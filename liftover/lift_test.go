@@ -0,0 +1,144 @@
+package liftover
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antonybholmes/go-dna"
+)
+
+// chr1 has a deletion at 200-250 and a stretch that maps to the -
+// strand of chr5, chr2 maps equally well to two places, chr3 has a 5
+// base sliver through a second chain and chr4 maps at its end
+const testChains = `chain 1000 chr1 1000 + 0 500 chr1 2000 + 100 550 1
+200 50 0
+250
+
+chain 900 chr1 1000 + 600 700 chr5 1000 - 100 200 2
+100
+
+chain 800 chr2 1000 + 0 100 chr2 1000 + 0 100 3
+100
+
+chain 800 chr2 1000 + 0 100 chr7 1000 + 500 600 4
+100
+
+chain 700 chr3 1000 + 0 100 chr3 1000 + 0 100 5
+100
+
+chain 10 chr3 1000 + 95 100 chrUn 100 + 0 5 6
+5
+
+chain 600 chr4 100 + 90 100 chr4 100 + 0 10 7
+10
+`
+
+type testMapping struct {
+	location string
+	strand   string
+	match    float64
+}
+
+func TestLift(t *testing.T) {
+	chains, err := ParseChains(strings.NewReader(testChains))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		location *dna.Location
+		minMatch float64
+		status   string
+		mapped   []testMapping
+	}{
+		{name: "mapped",
+			location: dna.NewLocation("chr1", 1, 200),
+			status:   STATUS_MAPPED,
+			mapped:   []testMapping{{"chr1:101-300", "+", 1}}},
+		{name: "across a deletion",
+			location: dna.NewLocation("chr1", 151, 300),
+			status:   STATUS_FAILED,
+			mapped:   []testMapping{{"chr1:251-350", "+", 0.6667}}},
+		{name: "across a deletion with a lower minMatch",
+			location: dna.NewLocation("chr1", 151, 300),
+			minMatch: 0.5,
+			status:   STATUS_MAPPED,
+			mapped:   []testMapping{{"chr1:251-350", "+", 0.6667}}},
+		{name: "deleted",
+			location: dna.NewLocation("chr1", 201, 250),
+			status:   STATUS_FAILED,
+			mapped:   []testMapping{}},
+		{name: "minus strand",
+			location: dna.NewLocation("chr1", 601, 650),
+			status:   STATUS_MAPPED,
+			mapped:   []testMapping{{"chr5:851-900", "-", 1}}},
+		{name: "split between chains",
+			location: dna.NewLocation("chr2", 1, 100),
+			status:   STATUS_SPLIT,
+			mapped:   []testMapping{{"chr2:1-100", "+", 1}, {"chr7:501-600", "+", 1}}},
+		{name: "sliver through another chain",
+			location: dna.NewLocation("chr3", 1, 100),
+			status:   STATUS_MAPPED,
+			mapped:   []testMapping{{"chr3:1-100", "+", 1}, {"chrUn:1-5", "+", 0.05}}},
+		{name: "chromosome end",
+			location: dna.NewLocation("chr4", 91, 100),
+			status:   STATUS_MAPPED,
+			mapped:   []testMapping{{"chr4:1-10", "+", 1}}},
+		{name: "chromosome not in chains",
+			location: dna.NewLocation("chrX", 1, 100),
+			status:   STATUS_FAILED,
+			mapped:   []testMapping{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			minMatch := test.minMatch
+
+			if minMatch == 0 {
+				minMatch = DEFAULT_MIN_MATCH
+			}
+
+			result := chains.Lift(test.location, minMatch)
+
+			if result.Status != test.status {
+				t.Errorf("status = %s, want %s (%s)", result.Status, test.status, result.Reason)
+			}
+
+			if len(result.Mapped) != len(test.mapped) {
+				t.Fatalf("got %d mappings, want %d", len(result.Mapped), len(test.mapped))
+			}
+
+			for i, mapping := range result.Mapped {
+				got := testMapping{mapping.Location.String(), mapping.Strand, mapping.Match}
+
+				if got != test.mapped[i] {
+					t.Errorf("mapping %d = %v, want %v", i, got, test.mapped[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseChainsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain string
+	}{
+		{"short header", "chain 1000 chr1 1000 + 0 500\n100\n"},
+		{"data before a header", "100\n"},
+		{"invalid block size", "chain 1000 chr1 1000 + 0 500 chr1 2000 + 100 550 1\nx\n"},
+		{"invalid id", "chain 1000 chr1 1000 + 0 500 chr1 2000 + 100 550 x\n100\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseChains(strings.NewReader(test.chain))
+
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
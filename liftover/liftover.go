@@ -0,0 +1,175 @@
+package liftover
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrNotInitialized = errors.New("liftover is not available")
+
+// Chain files use UCSC names, so other common names are mapped to
+// them
+var ALIASES = map[string]string{
+	"grch37": "hg19",
+	"grch38": "hg38",
+	"grcm38": "mm10",
+	"grcm39": "mm39",
+}
+
+// Failures, such as a missing chain file, are remembered for this
+// long before the file is tried again
+const RETRY_INTERVAL = 5 * time.Minute
+
+// Chains for a pair of assemblies, loaded once
+type entry struct {
+	once   sync.Once
+	chains *Chains
+	err    error
+	loaded time.Time
+}
+
+// Chain files are loaded from a dir the first time a pair of
+// assemblies is used and then kept in memory
+type ChainCache struct {
+	dir     string
+	lock    sync.Mutex
+	entries map[string]*entry
+}
+
+var instance *ChainCache
+
+func InitCache(dir string) {
+	instance = &ChainCache{dir: dir, entries: make(map[string]*entry)}
+}
+
+func UCSCName(assembly string) string {
+	assembly = strings.ToLower(assembly)
+
+	name, ok := ALIASES[assembly]
+
+	if ok {
+		return name
+	}
+
+	return assembly
+}
+
+// Returns true if two assembly names refer to the same build
+func SameAssembly(from string, to string) bool {
+	return UCSCName(from) == UCSCName(to)
+}
+
+// Files such as hg19ToHg38.over.chain.gz are matched ignoring case
+func (cache *ChainCache) file(from string, to string) (string, error) {
+	name := UCSCName(from) + "to" + UCSCName(to) + ".over.chain"
+
+	entries, err := os.ReadDir(cache.dir)
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		n := strings.ToLower(entry.Name())
+
+		if n == name || n == name+".gz" {
+			return filepath.Join(cache.dir, entry.Name()), nil
+		}
+	}
+
+	return "", fmt.Errorf("no chain file to lift %s to %s", from, to)
+}
+
+// Get the chains to lift from one assembly to another. Only requests
+// for the same pair wait while its chain file is read.
+func GetChains(from string, to string) (*Chains, error) {
+	cache := instance
+
+	if cache == nil {
+		return nil, ErrNotInitialized
+	}
+
+	key := UCSCName(from) + ":" + UCSCName(to)
+
+	cache.lock.Lock()
+
+	e, ok := cache.entries[key]
+
+	if !ok {
+		e = &entry{}
+		cache.entries[key] = e
+	}
+
+	cache.lock.Unlock()
+
+	e.once.Do(func() {
+		e.chains, e.err = cache.load(from, to)
+		e.loaded = time.Now()
+	})
+
+	if e.err != nil && time.Since(e.loaded) > RETRY_INTERVAL {
+		cache.lock.Lock()
+
+		if cache.entries[key] == e {
+			delete(cache.entries, key)
+		}
+
+		cache.lock.Unlock()
+	}
+
+	return e.chains, e.err
+}
+
+func (cache *ChainCache) load(from string, to string) (*Chains, error) {
+	file, err := cache.file(from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	chains, err := ReadChains(file)
+
+	if err != nil {
+		log.Error().Msgf("chain file %s: %s", file, err)
+		return nil, err
+	}
+
+	log.Info().Msgf("loaded chain file %s", file)
+
+	return chains, nil
+}
+
+// Lift locations between assemblies
+func Lift(from string, to string, locations []*dna.Location, minMatch float64) ([]*Result, error) {
+	ret := make([]*Result, 0, len(locations))
+
+	if SameAssembly(from, to) {
+		for _, location := range locations {
+			ret = append(ret, &Result{Location: location,
+				Status: STATUS_MAPPED,
+				Mapped: []*Mapping{{Location: location, Strand: "+", Match: 1}}})
+		}
+
+		return ret, nil
+	}
+
+	chains, err := GetChains(from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, location := range locations {
+		ret = append(ret, chains.Lift(location, minMatch))
+	}
+
+	return ret, nil
+}
@@ -1,32 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
+	"time"
 
-	"github.com/antonybholmes/go-beds/bedsdbcache"
-	"github.com/antonybholmes/go-cytobands/cytobandsdbcache"
-	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/cache"
+	"github.com/antonybholmes/go-edb-server-gin/config"
 	"github.com/antonybholmes/go-edb-server-gin/consts"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	"github.com/antonybholmes/go-edb-server-gin/metrics"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/ratelimit"
 	adminroutes "github.com/antonybholmes/go-edb-server-gin/routes/admin"
 	authenticationroutes "github.com/antonybholmes/go-edb-server-gin/routes/authentication"
 	authorizationroutes "github.com/antonybholmes/go-edb-server-gin/routes/authorization"
-	bedroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/beds"
-	cytobandroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/cytobands"
-	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
-	geneconvroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/geneconv"
-	genomeroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/genome"
-	gexroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/gex"
-	hubroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/hubs"
-	motifroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/motifs"
-	mutationroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/mutation"
-	pathwayroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/pathway"
-	scrnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/scrna"
-	seqroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/seqs"
-	"github.com/antonybholmes/go-hubs/hubsdbcache"
+	"github.com/antonybholmes/go-edb-server-gin/routes/health"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/tokengen"
 	"github.com/antonybholmes/go-web/userdbcache"
@@ -36,21 +34,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"gopkg.in/natefinch/lumberjack.v2"
 
 	"github.com/antonybholmes/go-web/middleware"
 
 	utilsroutes "github.com/antonybholmes/go-edb-server-gin/routes/utils"
-	"github.com/antonybholmes/go-geneconv/geneconvdbcache"
-	"github.com/antonybholmes/go-genome/genomedbcache"
-	"github.com/antonybholmes/go-gex/gexdbcache"
 	"github.com/antonybholmes/go-mailer"
 	"github.com/antonybholmes/go-mailer/queue"
-	"github.com/antonybholmes/go-motifs/motifsdb"
-	"github.com/antonybholmes/go-mutations/mutationdbcache"
-	"github.com/antonybholmes/go-pathway/pathwaydbcache"
-	"github.com/antonybholmes/go-scrna/scrnadbcache"
-	"github.com/antonybholmes/go-seqs/seqsdbcache"
 	"github.com/antonybholmes/go-sys/env"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -70,6 +61,10 @@ type InfoResp struct {
 // var store *sqlitestorr.SqliteStore
 var store cookie.Store
 
+var cfg *config.Config
+
+var rdb *redis.Client
+
 func init() {
 
 	env.Ls()
@@ -93,39 +88,27 @@ func init() {
 
 	//mailserver.Init()
 
-	dnadbcache.InitCache("data/modules/dna")
-	genomedbcache.InitCache("data/modules/genome")
-	//microarraydb.InitDB("data/microarray")
-
-	gexdbcache.InitCache("data/modules/gex")
-
-	scrnadbcache.InitCache("data/modules/scrna")
-
-	mutationdbcache.InitCache("data/modules/mutations")
-
-	geneconvdbcache.InitCache("data/modules/geneconv/geneconv.db")
-
-	motifsdb.InitCache("data/modules/motifs/motifs.db")
+	var err error
 
-	pathwaydbcache.InitCache("data/modules/pathway/pathway-v2.db")
+	cfg, err = config.Load()
 
-	seqsdbcache.InitCache("data/modules/seqs/")
-
-	cytobandsdbcache.InitCache("data/modules/cytobands/")
-
-	bedsdbcache.InitCache("data/modules/beds/")
-
-	hubsdbcache.InitCache("data/modules/hubs/")
+	if err != nil {
+		log.Fatal().Msgf("%s", err)
+	}
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     consts.REDIS_ADDR,
-		Username: "edb",
-		Password: consts.REDIS_PASSWORD,
-		DB:       0, // use default DB
+	rdb = redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Username: cfg.Redis.Username,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
 	})
 
 	queue.Init(mailer.NewRedisEmailPublisher(rdb))
 
+	cache.InitCache(cfg, rdb)
+
+	ratelimit.InitRateLimits(cfg, rdb)
+
 	// writer := kafka.NewWriter(kafka.WriterConfig{
 	// 	Brokers:  []string{"localhost:9094"}, // Kafka broker
 	// 	Topic:    mailer.QUEUE_EMAIL_CHANNEL, // Topic name
@@ -176,6 +159,16 @@ func main() {
 		logger = zerolog.New(io.MultiWriter(zerolog.ConsoleWriter{Out: os.Stderr}, fileLogger)).With().Timestamp().Logger()
 	}
 
+	// log lines with a request context are tagged with the trace id
+	logger = logger.Hook(tracing.LogHook{})
+	log.Logger = log.Logger.Hook(tracing.LogHook{})
+
+	shutdownTracing, err := tracing.Init(cfg, consts.APP_NAME, consts.VERSION)
+
+	if err != nil {
+		logger.Fatal().Msgf("%s", err)
+	}
+
 	// all subsequent middleware is reliant on this to function
 	jwtUserMiddleWare := middleware.JwtUserMiddleware(
 		middleware.JwtClaimsRSAParser(consts.JWT_RSA_PUBLIC_KEY))
@@ -201,26 +194,31 @@ func main() {
 	//r := gin.Default()
 	r := gin.New()
 
+	// client ips come from X-Forwarded-For only if set by one of
+	// our proxies
+	err = r.SetTrustedProxies(cfg.Server.TrustedProxies)
+
+	if err != nil {
+		logger.Fatal().Msgf("%s", err)
+	}
+
 	r.Use(gin.Recovery())
-	r.Use(middleware.LoggingMiddleware(logger))
+	r.Use(tracing.Middleware())
+	metrics.SetAssemblies(cfg.Metrics.Assemblies)
+	r.Use(metrics.Middleware())
+	r.Use(tracing.AccessLogMiddleware(logger))
 	r.Use(middleware.ErrorHandlerMiddleware())
 	//r.Use(middleware.CSRFCookieMiddleware())
 
 	r.Use(cors.New(cors.Config{
 		//AllowAllOrigins: true,
-		AllowOrigins: []string{
-			"http://localhost:3000",
-			"http://localhost:8000",
-			"https://edb.rdf-lab.org",
-			"https://edb-client-astro.pages.dev",
-			"https://edb-client-next.pages.dev",
-			"https://edb-client-next.vercel.app"},
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "X-CSRF-Token"},
+		AllowOrigins: cfg.Cors.AllowOrigins,
+		AllowMethods: cfg.Cors.AllowMethods,
+		AllowHeaders: cfg.Cors.AllowHeaders,
 		//AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, "Set-Cookie"},
 		// for sharing session cookie for validating logins etc
-		AllowCredentials: true,      // Allow credentials (cookies, HTTP authentication)
-		MaxAge:           12 * 3600, // Cache preflight response for 12 hours
+		AllowCredentials: true,                                            // Allow credentials (cookies, HTTP authentication)
+		MaxAge:           time.Duration(cfg.Cors.MaxAgeHours) * time.Hour, // Cache preflight response
 	}))
 
 	store = cookie.NewStore([]byte(consts.SESSION_KEY),
@@ -251,6 +249,8 @@ func main() {
 		accessTokenMiddleware,
 		middleware.JwtIsAdminMiddleware())
 
+	openapi.SecureGroup(adminGroup, openapi.RoleSecurity("admin"))
+
 	adminGroup.GET("/roles", adminroutes.RolesRoute)
 
 	adminUsersGroup := adminGroup.Group("/users")
@@ -262,13 +262,17 @@ func main() {
 	adminUsersGroup.DELETE("/delete/:uuid", adminroutes.DeleteUserRoute)
 
 	// Allow users to sign up for an account
-	r.POST("/signup", authenticationroutes.SignupRoute)
+	// signin and signup routes share the strict auth limit to
+	// slow down password guessing
+	authRateLimit := ratelimit.Middleware("auth")
+
+	r.POST("/signup", authRateLimit, authenticationroutes.SignupRoute)
 
 	//
 	// user groups: start
 	//
 
-	authGroup := r.Group("/auth")
+	authGroup := r.Group("/auth", authRateLimit)
 
 	// auth0Group := authGroup.Group("/auth0")
 	// auth0Group.POST("/validate",
@@ -318,11 +322,13 @@ func main() {
 	)
 
 	tokenGroup := authGroup.Group("/tokens", jwtUserMiddleWare)
+	openapi.SecureGroup(tokenGroup, openapi.BearerSecurity)
 	tokenGroup.POST("/info", authorizationroutes.TokenInfoRoute)
 	tokenGroup.POST("/access", authorizationroutes.NewAccessTokenRoute)
 
 	usersGroup := authGroup.Group("/users",
 		jwtUserMiddleWare)
+	openapi.SecureGroup(usersGroup, openapi.BearerSecurity)
 
 	//usersGroup.POST("", authorizationroutes.UserRoute)
 
@@ -338,7 +344,7 @@ func main() {
 
 	sessionGroup := r.Group("/sessions")
 
-	sessionAuthGroup := sessionGroup.Group("/auth")
+	sessionAuthGroup := sessionGroup.Group("/auth", authRateLimit)
 
 	sessionOAuth2Group := sessionAuthGroup.Group("/oauth2")
 
@@ -361,11 +367,13 @@ func main() {
 		sessionRoutes.SessionPasswordlessValidateSignInRoute)
 
 	sessionGroup.POST("/api/keys/signin",
+		authRateLimit,
 		sessionRoutes.SessionApiKeySignInRoute)
 
 	sessionGroup.GET("/info",
 		sessionMiddleware,
 		sessionRoutes.SessionInfoRoute)
+	openapi.SecureRoute(http.MethodGet, "/sessions/info", openapi.SessionSecurity)
 
 	sessionGroup.GET("/csrf-token",
 		sessionRoutes.SessionCsrfTokenRoute)
@@ -374,10 +382,12 @@ func main() {
 		//csrfMiddleware,
 		sessionMiddleware,
 		authenticationroutes.SessionSignOutRoute)
+	openapi.SecureRoute(http.MethodPost, "/sessions/signout", openapi.SessionSecurity)
 
 	sessionTokensGroup := sessionGroup.Group("/tokens",
 		csrfMiddleware,
 		sessionMiddleware)
+	openapi.SecureGroup(sessionTokensGroup, openapi.SessionCSRFSecurity)
 
 	//sessionTokensGroup.POST("/access",
 	//		authenticationroutes.NewAccessTokenFromSessionRoute)
@@ -391,10 +401,12 @@ func main() {
 		csrfMiddleware,
 		sessionMiddleware,
 		sessionRoutes.SessionRefreshRoute)
+	openapi.SecureRoute(http.MethodPost, "/sessions/refresh", openapi.SessionCSRFSecurity)
 
 	sessionUserGroup := sessionGroup.Group("/user",
 		csrfMiddleware,
 		sessionMiddleware)
+	openapi.SecureGroup(sessionUserGroup, openapi.SessionCSRFSecurity)
 	sessionUserGroup.GET("", authenticationroutes.UserFromSessionRoute)
 	sessionUserGroup.POST("/update",
 		authorizationroutes.SessionUpdateUserRoute)
@@ -406,162 +418,63 @@ func main() {
 	// module groups: start
 	//
 
-	moduleGroup := r.Group("/modules")
+	moduleGroup := r.Group("/modules", ratelimit.Middleware("modules"))
 	//moduleGroup.Use(jwtMiddleWare,JwtIsAccessTokenMiddleware)
 
-	dnaGroup := moduleGroup.Group("/dna")
-	dnaGroup.POST("/:assembly", dnaroutes.DNARoute)
-	dnaGroup.GET("/genomes", dnaroutes.GenomesRoute)
-
-	genomeGroup := moduleGroup.Group("/genome")
-	genomeGroup.GET("/genomes", genomeroutes.GenomesRoute)
-	genomeGroup.POST("/within/:assembly", genomeroutes.WithinGenesRoute)
-	genomeGroup.POST("/closest/:assembly", genomeroutes.ClosestGeneRoute)
-	genomeGroup.POST("/annotate/:assembly", genomeroutes.AnnotateRoute)
-	genomeGroup.POST("/overlap/:assembly", genomeroutes.OverlappingGenesRoute)
-	genomeGroup.GET("/info/:assembly", genomeroutes.SearchForGeneByNameRoute)
-
-	// mutationsGroup := moduleGroup.Group("/mutations",
-	// 	jwtMiddleWare,
-	// 	JwtIsAccessTokenMiddleware,
-	// 	NewJwtPermissionsMiddleware("rdf"))
-
-	mutationsGroup := moduleGroup.Group("/mutations")
-	mutationsGroup.GET("/datasets/:assembly",
-		mutationroutes.MutationDatasetsRoute)
-	mutationsGroup.POST("/:assembly/:name",
-		mutationroutes.MutationsRoute)
-	mutationsGroup.POST("/maf/:assembly",
-		mutationroutes.PileupRoute)
-
-	mutationsGroup.POST("/pileup/:assembly",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		mutationroutes.PileupRoute,
-	)
-
-	gexGroup := moduleGroup.Group("/gex")
-	gexGroup.GET("/species", gexroutes.SpeciesRoute)
-	gexGroup.GET("/technologies", gexroutes.TechnologiesRoute)
-	//gexGroup.GET("/types", gexroutes.GexValueTypesRoute)
-
-	gexGroup.GET("/datasets/:species/:technology",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		gexroutes.GexDatasetsRoute)
-
-	gexGroup.POST("/exp",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		gexroutes.GexGeneExpRoute,
-	)
-
-	scrnaGroup := moduleGroup.Group("/scrna")
-	scrnaGroup.GET("/species", scrnaroutes.ScrnaSpeciesRoute)
-	scrnaGroup.GET("/assemblies/:species", scrnaroutes.ScrnaAssembliesRoute)
-	//gexGroup.GET("/types", gexroutes.GexValueTypesRoute)
-
-	scrnaGroup.GET("/datasets/:species/:assembly",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		scrnaroutes.ScrnaDatasetsRoute)
-
-	// scrnaGroup.GET("/clusters/:id",
-	// 	jwtUserMiddleWare,
-	// 	accessTokenMiddleware,
-	// 	rdfRoleMiddleware,
-	// 	scrnaroutes.ScrnaClustersRoute,
-	// )
-
-	scrnaGroup.GET("/metadata/:id",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		scrnaroutes.ScrnaMetadataRoute,
-	)
-
-	scrnaGroup.GET("/genes/:id",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		scrnaroutes.ScrnaGenesRoute,
-	)
-
-	scrnaGroup.GET("/genes/search/:id",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		scrnaroutes.ScrnaSearchGenesRoute,
-	)
-
-	scrnaGroup.POST("/gex/:id",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		scrnaroutes.ScrnaGexRoute,
-	)
-
-	hubsGroup := moduleGroup.Group("/hubs")
-	hubsGroup.GET("/:assembly",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware,
-		hubroutes.HubsRoute,
-	)
-
-	geneConvGroup := moduleGroup.Group("/geneconv")
-	geneConvGroup.POST("/convert/:from/:to", geneconvroutes.ConvertRoute)
-
-	// geneConvGroup.POST("/:species", func(c *gin.Context) {
-	// 	return geneconvroutes.GeneInfoRoute(c, "")
-	// })
+	// each module registers its own routes, protected routes
+	// are wrapped in the auth chain for the role the module
+	// requires
+	// jobs replay module requests as the user who submitted them
+	// rather than with their token, which may have expired
+	jobUserMiddleware := jobs.Authenticate(jwtUserMiddleWare)
+
+	servedModules := modules.Setup(moduleGroup, cfg, func(role string) gin.HandlersChain {
+		switch role {
+		case modules.ROLE_RDF:
+			return gin.HandlersChain{jobUserMiddleware,
+				accessTokenMiddleware,
+				rdfRoleMiddleware}
+		default:
+			return gin.HandlersChain{jobUserMiddleware,
+				accessTokenMiddleware}
+		}
+	})
 
-	motifsGroup := moduleGroup.Group("/motifs")
-	motifsGroup.GET("/datasets", motifroutes.DatasetsRoute)
-	motifsGroup.POST("/search", motifroutes.SearchRoute)
+	//
+	// module groups: end
+	//
 
-	pathwayGroup := moduleGroup.Group("/pathway")
-	pathwayGroup.GET("/genes", pathwayroutes.GenesRoute)
-	pathwayGroup.POST("/dataset", pathwayroutes.DatasetRoute)
-	pathwayGroup.GET("/datasets", pathwayroutes.DatasetsRoute)
-	pathwayGroup.POST("/overlap", pathwayroutes.PathwayOverlapRoute)
+	//
+	// Long running module requests can be submitted as jobs
+	// and polled for their results
+	//
 
-	seqsGroup := moduleGroup.Group("/seqs",
-		jwtUserMiddleWare,
-		accessTokenMiddleware,
-		rdfRoleMiddleware)
+	jobManager, err := jobs.NewJobManager(r, cfg)
 
-	seqsGroup.GET("/genomes", seqroutes.GenomeRoute)
-	seqsGroup.GET("/platforms/:assembly", seqroutes.PlatformRoute)
-	//tracksGroup.GET("/:platform/:assembly/tracks", seqroutes.TracksRoute)
-	seqsGroup.GET("/search/:assembly", seqroutes.SearchSeqRoute)
-	seqsGroup.POST("/bins", seqroutes.BinsRoute)
+	if err != nil {
+		logger.Fatal().Msgf("%s", err)
+	}
 
-	cytobandsGroup := moduleGroup.Group("/cytobands")
-	cytobandsGroup.GET("/:assembly/:chr", cytobandroutes.CytobandsRoute)
+	jobManager.Start()
 
-	bedsGroup := moduleGroup.Group("/beds",
+	jobsGroup := r.Group("/jobs",
 		jwtUserMiddleWare,
 		accessTokenMiddleware,
-		rdfRoleMiddleware)
-	bedsGroup.GET("/genomes", bedroutes.GenomeRoute)
-	bedsGroup.GET("/platforms/:assembly", bedroutes.PlatformRoute)
-	bedsGroup.GET("/search/:assembly", bedroutes.SearchBedsRoute)
-	bedsGroup.POST("/regions", bedroutes.BedRegionsRoute)
+		ratelimit.Middleware("jobs"))
+	openapi.SecureGroup(jobsGroup, openapi.BearerSecurity)
 
-	//
-	// module groups: end
-	//
+	jobsGroup.POST("", jobManager.SubmitRoute)
+	jobsGroup.GET("", jobManager.JobsRoute)
+	jobsGroup.GET("/:id", jobManager.JobRoute)
+	jobsGroup.GET("/:id/result", jobManager.ResultRoute)
+	jobsGroup.POST("/:id/cancel", jobManager.CancelRoute)
+	jobsGroup.DELETE("/:id", jobManager.DeleteRoute)
 
 	//
 	// Util routes
 	//
 
-	utilsGroup := r.Group("/utils")
+	utilsGroup := r.Group("/utils", ratelimit.Middleware("utils"))
 	//moduleGroup.Use(jwtMiddleWare,JwtIsAccessTokenMiddleware)
 
 	xlsxGroup := utilsGroup.Group("/xlsx")
@@ -577,7 +490,104 @@ func main() {
 		})
 	})
 
-	// required so it can listen externally within docker container
-	r.Run("0.0.0.0:8080") //"localhost:8080") // listen and serve on 0.0.0.0:8080 (for windows "localhost:8080")
+	//
+	// Liveness and readiness for the container orchestrator
+	//
+
+	healthRoutes := health.NewHealthRoutes()
+
+	for _, name := range modules.Enabled() {
+		healthRoutes.AddProbe("modules/"+name, health.FuncProbe(func() error {
+			return modules.Check(name)
+		}))
+	}
+
+	// the email publisher is backed by redis
+	healthRoutes.AddProbe("redis", func(ctx context.Context) error {
+		return rdb.Ping(ctx).Err()
+	})
+
+	r.GET("/healthz", healthRoutes.HealthzRoute)
+	r.GET("/readyz", healthRoutes.ReadyzRoute)
+
+	r.GET("/metrics", metrics.MetricsRoute())
+
+	//
+	// API docs, generated from the routes above on first request
+	//
+
+	openapiRoutes := openapi.NewRoutes(r,
+		&openapi.Info{Title: consts.NAME, Version: consts.VERSION},
+		consts.SESSION_NAME,
+		cfg.Docs.SwaggerUIUrl)
+
+	r.GET("/openapi.json", openapiRoutes.SpecRoute)
+	r.GET("/docs", openapiRoutes.DocsRoute)
+
+	logger.Info().Msgf("serving %d modules", len(servedModules))
+
+	// addr defaults to 0.0.0.0:8080 so it can listen externally within
+	// docker container (for windows use "localhost:8080")
+	srv := &http.Server{
+		Addr:    cfg.Server.Addr,
+		Handler: r,
+	}
+
+	go func() {
+		var err error
+
+		if cfg.IsTLS() {
+			err = srv.ListenAndServeTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal().Msgf("%s", err)
+		}
+	}()
+
+	// wait for the orchestrator to ask us to stop
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	<-ctx.Done()
+
+	logger.Info().Msg("shutting down, draining connections")
+
+	healthRoutes.SetShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout())
+	defer cancel()
+
+	err = srv.Shutdown(shutdownCtx)
+
+	if err != nil {
+		logger.Error().Msgf("forced shutdown: %s", err)
+	}
+
+	// stop jobs before the module data they use is closed
+	err = jobManager.Close()
+
+	if err != nil {
+		logger.Error().Msgf("error closing jobs: %s", err)
+	}
+
+	modules.Close()
+
+	ratelimit.Close()
+
+	err = shutdownTracing(shutdownCtx)
+
+	if err != nil {
+		logger.Error().Msgf("error flushing traces: %s", err)
+	}
+
+	err = rdb.Close()
+
+	if err != nil {
+		logger.Error().Msgf("error closing redis: %s", err)
+	}
 
+	logger.Info().Msg("server stopped")
 }
@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const NAMESPACE = "edb"
+
+// label used when a request does not match a route, so that
+// random urls cannot blow up the number of series
+const UNMATCHED_ROUTE = "unmatched"
+
+// assemblies are user supplied so anything that is not a known
+// assembly is lumped together to keep label cardinality sane
+const OTHER_ASSEMBLY = "other"
+
+// sign in methods
+const (
+	SIGNIN_PASSWORD             = "password"
+	SIGNIN_PASSWORDLESS         = "passwordless"
+	SIGNIN_SESSION_PASSWORD     = "session_password"
+	SIGNIN_SESSION_PASSWORDLESS = "session_passwordless"
+	SIGNIN_API_KEY              = "api_key"
+	SIGNIN_AUTH0                = "auth0"
+	SIGNIN_CLERK                = "clerk"
+	SIGNIN_SUPABASE             = "supabase"
+)
+
+var requestLabels = []string{"method", "route", "module", "assembly", "status"}
+
+// assemblies that get their own label value, set once at startup
+var assemblies = make(map[string]struct{})
+
+var (
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests.",
+	}, requestLabels)
+
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests.",
+		// 5ms to ~40s
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, requestLabels)
+
+	ResponseSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "http_response_size_bytes",
+		Help:      "Size of HTTP responses.",
+		// 100B to ~26MB
+		Buckets: prometheus.ExponentialBuckets(100, 4, 10),
+	}, requestLabels)
+
+	SignInsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "signins_total",
+		Help:      "Number of successful sign ins by method.",
+	}, []string{"method"})
+
+	TokensIssuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "tokens_issued_total",
+		Help:      "Number of tokens issued from sessions by type.",
+	}, []string{"type"})
+
+	EmailsPublishedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "emails_published_total",
+		Help:      "Number of emails published to the email queue by type.",
+	}, []string{"type"})
+
+	CacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "cache_requests_total",
+		Help:      "Number of cacheable module requests by result (hit, miss, not_modified).",
+	}, []string{"module", "result"})
+)
+
+// Records count, latency and size of every request labelled by the
+// route template (e.g. /modules/genome/annotate/:assembly) rather
+// than the raw url
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+
+		if route == "" {
+			route = UNMATCHED_ROUTE
+		}
+
+		labels := prometheus.Labels{
+			"method":   c.Request.Method,
+			"route":    route,
+			"module":   moduleName(route),
+			"assembly": assemblyName(c),
+			"status":   strconv.Itoa(c.Writer.Status()),
+		}
+
+		RequestsTotal.With(labels).Inc()
+		RequestDuration.With(labels).Observe(time.Since(start).Seconds())
+
+		// size is -1 if nothing was written
+		size := c.Writer.Size()
+
+		if size < 0 {
+			size = 0
+		}
+
+		ResponseSize.With(labels).Observe(float64(size))
+	}
+}
+
+// Serves the metrics in the prometheus text format
+func MetricsRoute() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// Set the assemblies that are labelled by name. Call before the
+// server starts handling requests.
+func SetAssemblies(names []string) {
+	for _, name := range names {
+		assemblies[strings.ToLower(name)] = struct{}{}
+	}
+}
+
+func SignIn(method string) {
+	SignInsTotal.WithLabelValues(method).Inc()
+}
+
+func TokenIssued(tokenType string) {
+	TokensIssuedTotal.WithLabelValues(tokenType).Inc()
+}
+
+func EmailPublished(emailType string) {
+	EmailsPublishedTotal.WithLabelValues(emailType).Inc()
+}
+
+func CacheResult(module string, result string) {
+	CacheRequestsTotal.WithLabelValues(module, result).Inc()
+}
+
+// Extract the module from a route such as /modules/dna/:assembly
+func moduleName(route string) string {
+	path, ok := strings.CutPrefix(route, "/modules/")
+
+	if !ok {
+		return ""
+	}
+
+	name, _, _ := strings.Cut(path, "/")
+
+	return name
+}
+
+func assemblyName(c *gin.Context) string {
+	assembly := strings.ToLower(c.Param("assembly"))
+
+	if assembly == "" {
+		return ""
+	}
+
+	_, ok := assemblies[assembly]
+
+	if !ok {
+		return OTHER_ASSEMBLY
+	}
+
+	return assembly
+}
@@ -0,0 +1,20 @@
+package main
+
+// Data modules register their routes with the module registry
+// when imported. To add a new module, import it here and give
+// it a section in config.yaml if it needs non-default settings.
+import (
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/beds"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/cytobands"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/geneconv"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/genome"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/gex"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/hubs"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/liftover"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/motifs"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/mutation"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/pathway"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/scrna"
+	_ "github.com/antonybholmes/go-edb-server-gin/routes/modules/seqs"
+)
@@ -0,0 +1,375 @@
+package oligos
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/chromsizes"
+)
+
+// Identifies index files and their layout version
+const INDEX_MAGIC = "EDBOLIGO1"
+
+// Sequence is fetched from the dna module in blocks of this size
+// when building an index
+const BUILD_BLOCK_SIZE uint = 1000000
+
+var ErrBadIndex = errors.New("not an oligo index file")
+
+// A chromosome packed at 2 bits per base. Ns are stored as A and
+// recorded as runs of 0-based [start, end) pairs since they come in
+// a few large blocks.
+type Chrom struct {
+	Name  string
+	Len   uint
+	Seq   []uint64
+	NRuns []uint64
+}
+
+type Index struct {
+	Assembly string
+	Chroms   []*Chrom
+}
+
+// Fetches the + strand sequence of a location
+type FetchFunc func(location *dna.Location) (string, error)
+
+func NewChrom(name string, length uint) *Chrom {
+	return &Chrom{Name: name,
+		Len:   length,
+		Seq:   make([]uint64, (length+31)/32),
+		NRuns: make([]uint64, 0, 100)}
+}
+
+// Base code 0-3 for ACGT, or -1 for anything else
+func baseCode(b byte) int {
+	switch b {
+	case 'A', 'a':
+		return 0
+	case 'C', 'c':
+		return 1
+	case 'G', 'g':
+		return 2
+	case 'T', 't':
+		return 3
+	default:
+		return -1
+	}
+}
+
+// Store bases starting at the 0-based offset. Blocks must be
+// added in order so that runs of Ns can be extended.
+func (chrom *Chrom) set(offset uint, seq string) {
+	for i := 0; i < len(seq); i++ {
+		p := offset + uint(i)
+
+		if p >= chrom.Len {
+			return
+		}
+
+		code := baseCode(seq[i])
+
+		if code < 0 {
+			n := len(chrom.NRuns)
+
+			if n > 0 && chrom.NRuns[n-1] == uint64(p) {
+				chrom.NRuns[n-1]++
+			} else {
+				chrom.NRuns = append(chrom.NRuns, uint64(p), uint64(p+1))
+			}
+
+			continue
+		}
+
+		chrom.Seq[p>>5] |= uint64(code) << ((p & 31) << 1)
+	}
+}
+
+// Base code at a 0-based position
+func (chrom *Chrom) base(p uint) uint64 {
+	return (chrom.Seq[p>>5] >> ((p & 31) << 1)) & 3
+}
+
+// Returns true if the 0-based position is in a run of Ns
+func (chrom *Chrom) isN(p uint) bool {
+	// first run ending after p
+	i := sort.Search(len(chrom.NRuns)/2, func(i int) bool {
+		return chrom.NRuns[2*i+1] > uint64(p)
+	})
+
+	return i < len(chrom.NRuns)/2 && chrom.NRuns[2*i] <= uint64(p)
+}
+
+// Build an index by fetching each chromosome in blocks
+func Build(assembly string, sizes []*chromsizes.Size, fetch FetchFunc) (*Index, error) {
+	index := Index{Assembly: assembly, Chroms: make([]*Chrom, 0, len(sizes))}
+
+	for _, size := range sizes {
+		chrom := NewChrom(size.Name, size.Len)
+
+		for start := uint(1); start <= size.Len; start += BUILD_BLOCK_SIZE {
+			end := min(start+BUILD_BLOCK_SIZE-1, size.Len)
+
+			seq, err := fetch(dna.NewLocation(size.Name, start, end))
+
+			if err != nil {
+				return nil, err
+			}
+
+			chrom.set(start-1, seq)
+		}
+
+		index.Chroms = append(index.Chroms, chrom)
+	}
+
+	return &index, nil
+}
+
+// Write the index to a file. The file is written in place of any
+// existing one only once complete.
+func (index *Index) Save(file string) error {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".tmp"
+
+	f, err := os.Create(tmp)
+
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+
+	err = index.write(w)
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	err = f.Close()
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+func (index *Index) write(w io.Writer) error {
+	err := writeString(w, INDEX_MAGIC)
+
+	if err != nil {
+		return err
+	}
+
+	err = writeString(w, index.Assembly)
+
+	if err != nil {
+		return err
+	}
+
+	err = binary.Write(w, binary.LittleEndian, uint32(len(index.Chroms)))
+
+	if err != nil {
+		return err
+	}
+
+	for _, chrom := range index.Chroms {
+		err = writeString(w, chrom.Name)
+
+		if err != nil {
+			return err
+		}
+
+		err = binary.Write(w, binary.LittleEndian, uint64(chrom.Len))
+
+		if err != nil {
+			return err
+		}
+
+		err = writeWords(w, chrom.Seq)
+
+		if err != nil {
+			return err
+		}
+
+		err = binary.Write(w, binary.LittleEndian, uint64(len(chrom.NRuns)))
+
+		if err != nil {
+			return err
+		}
+
+		err = writeWords(w, chrom.NRuns)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Load(file string) (*Index, error) {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	magic, err := readString(r)
+
+	if err != nil || magic != INDEX_MAGIC {
+		return nil, ErrBadIndex
+	}
+
+	assembly, err := readString(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var n uint32
+
+	err = binary.Read(r, binary.LittleEndian, &n)
+
+	if err != nil {
+		return nil, err
+	}
+
+	index := Index{Assembly: assembly, Chroms: make([]*Chrom, 0, n)}
+
+	for range n {
+		name, err := readString(r)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var length uint64
+
+		err = binary.Read(r, binary.LittleEndian, &length)
+
+		if err != nil {
+			return nil, err
+		}
+
+		chrom := NewChrom(name, uint(length))
+
+		err = readWords(r, chrom.Seq)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var runs uint64
+
+		err = binary.Read(r, binary.LittleEndian, &runs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Ns in runs of 1 would be every other base
+		if runs > length+1 {
+			return nil, ErrBadIndex
+		}
+
+		chrom.NRuns = make([]uint64, runs)
+
+		err = readWords(r, chrom.NRuns)
+
+		if err != nil {
+			return nil, err
+		}
+
+		index.Chroms = append(index.Chroms, chrom)
+	}
+
+	return &index, nil
+}
+
+func writeString(w io.Writer, s string) error {
+	err := binary.Write(w, binary.LittleEndian, uint32(len(s)))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, s)
+
+	return err
+}
+
+func readString(r io.Reader) (string, error) {
+	var n uint32
+
+	err := binary.Read(r, binary.LittleEndian, &n)
+
+	if err != nil {
+		return "", err
+	}
+
+	// names are short so anything large means a corrupt file
+	if n > 1024 {
+		return "", ErrBadIndex
+	}
+
+	buf := make([]byte, n)
+
+	_, err = io.ReadFull(r, buf)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
+
+// binary.Read and Write buffer a whole slice, so large chromosomes
+// are done in chunks to keep memory down
+const WORD_CHUNK_SIZE = 1 << 20
+
+func writeWords(w io.Writer, words []uint64) error {
+	for i := 0; i < len(words); i += WORD_CHUNK_SIZE {
+		err := binary.Write(w, binary.LittleEndian, words[i:min(i+WORD_CHUNK_SIZE, len(words))])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readWords(r io.Reader, words []uint64) error {
+	for i := 0; i < len(words); i += WORD_CHUNK_SIZE {
+		err := binary.Read(r, binary.LittleEndian, words[i:min(i+WORD_CHUNK_SIZE, len(words))])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package oligos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/antonybholmes/go-edb-server-gin/chromsizes"
+	"github.com/rs/zerolog/log"
+)
+
+const INDEX_EXT = ".oligos"
+
+var ErrIndexBuilding = errors.New("the oligo index for this assembly is being built, try again in a few minutes")
+
+var ErrNotInitialized = errors.New("oligo search is not available")
+
+// Assembly names are used as file names so cannot contain paths
+var ValidAssembly = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// Where sequence comes from when an index has to be built
+type Source interface {
+	ChromSizes(assembly string) ([]*chromsizes.Size, error)
+	Fetcher(assembly string) (FetchFunc, error)
+}
+
+type entry struct {
+	index    *Index
+	building bool
+	err      error
+}
+
+// Indexes are loaded, or built and saved, the first time an
+// assembly is searched and then kept in memory
+type Indexes struct {
+	dir     string
+	source  Source
+	lock    sync.Mutex
+	entries map[string]*entry
+}
+
+var instance *Indexes
+
+func InitCache(dir string, source Source) {
+	instance = &Indexes{dir: dir,
+		source:  source,
+		entries: make(map[string]*entry)}
+}
+
+// Start loading or building indexes so they are ready before
+// anyone searches
+func Prebuild(assemblies []string) {
+	for _, assembly := range assemblies {
+		_, err := GetIndex(assembly)
+
+		if err != nil && err != ErrIndexBuilding {
+			log.Error().Msgf("oligo index %s: %s", assembly, err)
+		}
+	}
+}
+
+// Get the index of an assembly. If it is not in memory it is
+// loaded or built in the background and ErrIndexBuilding is
+// returned until it is ready.
+func GetIndex(assembly string) (*Index, error) {
+	indexes := instance
+
+	if indexes == nil {
+		return nil, ErrNotInitialized
+	}
+
+	if !ValidAssembly.MatchString(assembly) {
+		return nil, fmt.Errorf("%s is not a valid assembly", assembly)
+	}
+
+	indexes.lock.Lock()
+	defer indexes.lock.Unlock()
+
+	e, ok := indexes.entries[assembly]
+
+	if ok {
+		if e.building {
+			return nil, ErrIndexBuilding
+		}
+
+		if e.err != nil {
+			// report the failure once and then allow another try
+			delete(indexes.entries, assembly)
+			return nil, e.err
+		}
+
+		return e.index, nil
+	}
+
+	indexes.entries[assembly] = &entry{building: true}
+
+	go indexes.load(assembly)
+
+	return nil, ErrIndexBuilding
+}
+
+func (indexes *Indexes) file(assembly string) string {
+	return filepath.Join(indexes.dir, assembly+INDEX_EXT)
+}
+
+func (indexes *Indexes) load(assembly string) {
+	index, err := indexes.loadOrBuild(assembly)
+
+	if err != nil {
+		log.Error().Msgf("oligo index %s: %s", assembly, err)
+	}
+
+	indexes.lock.Lock()
+	defer indexes.lock.Unlock()
+
+	indexes.entries[assembly] = &entry{index: index, err: err}
+}
+
+func (indexes *Indexes) loadOrBuild(assembly string) (*Index, error) {
+	file := indexes.file(assembly)
+
+	index, err := Load(file)
+
+	if err == nil {
+		log.Info().Msgf("loaded oligo index %s", file)
+		return index, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	log.Info().Msgf("building oligo index %s", file)
+
+	sizes, err := indexes.source.ChromSizes(assembly)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fetch, err := indexes.source.Fetcher(assembly)
+
+	if err != nil {
+		return nil, err
+	}
+
+	index, err = Build(assembly, sizes, fetch)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = index.Save(file)
+
+	if err != nil {
+		// the index is still usable, it will just be built
+		// again after a restart
+		log.Error().Msgf("error saving oligo index %s: %s", file, err)
+	}
+
+	log.Info().Msgf("built oligo index %s", file)
+
+	return index, nil
+}
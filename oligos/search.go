@@ -0,0 +1,455 @@
+package oligos
+
+import (
+	"fmt"
+	"math/bits"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/antonybholmes/go-dna"
+)
+
+// Oligos are matched against a 64 bit window of the genome
+const MAX_OLIGO_LEN = 32
+
+const MIN_OLIGO_LEN = 6
+
+const MAX_MISMATCHES = 3
+
+const DEFAULT_MAX_HITS = 1000
+
+// Limits that keep a search from tying up the server
+const (
+	MAX_OLIGOS = 100
+	MAX_HITS   = 10000
+)
+
+const (
+	STRAND_BOTH = "both"
+	STRAND_POS  = "+"
+	STRAND_NEG  = "-"
+)
+
+const BASES = "ACGT"
+
+// one bit per base when two bit codes are collapsed
+const evenBits uint64 = 0x5555555555555555
+
+type Hit struct {
+	Oligo      string        `json:"oligo"`
+	Location   *dna.Location `json:"location"`
+	Strand     string        `json:"strand"`
+	Mismatches int           `json:"mismatches"`
+	// genome sequence of the hit, on the strand of the oligo
+	Seq string `json:"seq"`
+}
+
+type SearchResult struct {
+	Hits []*Hit `json:"hits"`
+	// true if there were more than the max hits
+	Truncated bool `json:"truncated"`
+}
+
+type SearchOptions struct {
+	Mismatches int
+	// both, + or -
+	Strand string
+	// only search within this location if set
+	Location *dna.Location
+	MaxHits  int
+}
+
+type pattern struct {
+	oligo  string
+	strand string
+	code   uint64
+	// which bits to compare, so that Ns in the oligo match
+	// anything
+	care uint64
+}
+
+func revComp(seq string) string {
+	ret := make([]byte, len(seq))
+
+	for i := 0; i < len(seq); i++ {
+		var b byte
+
+		switch seq[i] {
+		case 'A':
+			b = 'T'
+		case 'C':
+			b = 'G'
+		case 'G':
+			b = 'C'
+		case 'T':
+			b = 'A'
+		default:
+			b = 'N'
+		}
+
+		ret[len(seq)-1-i] = b
+	}
+
+	return string(ret)
+}
+
+// Mask of the bits holding n bases
+func windowMask(n int) uint64 {
+	if n >= MAX_OLIGO_LEN {
+		return ^uint64(0)
+	}
+
+	return (1 << (2 * n)) - 1
+}
+
+// Encode a sequence so the first base is in the highest bits,
+// matching how bases are shifted into the search window
+func encode(seq string) (uint64, uint64) {
+	var code uint64
+	var care uint64
+
+	for i := 0; i < len(seq); i++ {
+		code <<= 2
+		care <<= 2
+
+		c := baseCode(seq[i])
+
+		if c >= 0 {
+			code |= uint64(c)
+			care |= 3
+		}
+	}
+
+	return code, care
+}
+
+func compile(oligos []string, strand string) ([]*pattern, int, error) {
+	ret := make([]*pattern, 0, 2*len(oligos))
+
+	l := 0
+
+	for _, oligo := range oligos {
+		oligo = strings.ToUpper(strings.TrimSpace(oligo))
+
+		if len(oligo) < MIN_OLIGO_LEN || len(oligo) > MAX_OLIGO_LEN {
+			return nil, 0, fmt.Errorf("oligos must be %d to %d bases: %s", MIN_OLIGO_LEN, MAX_OLIGO_LEN, oligo)
+		}
+
+		if l > 0 && len(oligo) != l {
+			return nil, 0, fmt.Errorf("oligos must all be the same length")
+		}
+
+		l = len(oligo)
+
+		for i := 0; i < len(oligo); i++ {
+			if baseCode(oligo[i]) < 0 && oligo[i] != 'N' {
+				return nil, 0, fmt.Errorf("%s is not a valid oligo, use ACGT or N", oligo)
+			}
+		}
+
+		if strand != STRAND_NEG {
+			code, care := encode(oligo)
+			ret = append(ret, &pattern{oligo: oligo, strand: STRAND_POS, code: code, care: care})
+		}
+
+		if strand != STRAND_POS {
+			rc := revComp(oligo)
+
+			// palindromes would report each hit twice
+			if strand == STRAND_BOTH && rc == oligo {
+				continue
+			}
+
+			code, care := encode(rc)
+			ret = append(ret, &pattern{oligo: oligo, strand: STRAND_NEG, code: code, care: care})
+		}
+	}
+
+	return ret, l, nil
+}
+
+// Patterns indexed by segments of their sequence. With m mismatches
+// an oligo split into m+1 segments has at least one segment that
+// matches exactly, so at each position only the patterns sharing a
+// segment with the window need to be compared in full, however many
+// oligos there are.
+type seeds struct {
+	shifts []uint
+	masks  []uint64
+	tables []map[uint64][]int
+	// patterns with Ns cannot be looked up so are compared at
+	// every position
+	scan []int
+}
+
+func newSeeds(patterns []*pattern, l int, mismatches int) *seeds {
+	n := min(mismatches+1, l)
+
+	ret := seeds{shifts: make([]uint, 0, n),
+		masks:  make([]uint64, 0, n),
+		tables: make([]map[uint64][]int, 0, n),
+		scan:   make([]int, 0, len(patterns))}
+
+	for i := range n {
+		// bases [a, b) of the oligo, the first base being in
+		// the highest bits
+		a := i * l / n
+		b := (i + 1) * l / n
+
+		ret.shifts = append(ret.shifts, uint(2*(l-b)))
+		ret.masks = append(ret.masks, windowMask(b-a))
+		ret.tables = append(ret.tables, make(map[uint64][]int))
+	}
+
+	full := windowMask(l)
+
+	for pi, pat := range patterns {
+		if pat.care != full {
+			ret.scan = append(ret.scan, pi)
+			continue
+		}
+
+		for i, table := range ret.tables {
+			key := (pat.code >> ret.shifts[i]) & ret.masks[i]
+			table[key] = append(table[key], pi)
+		}
+	}
+
+	return &ret
+}
+
+// Indexes of the patterns that could match a window, in order.
+// seen[pi] is set to stamp so that a pattern found through more
+// than one segment is only returned once.
+func (s *seeds) candidates(window uint64, seen []int, stamp int, buf []int) []int {
+	buf = append(buf[:0], s.scan...)
+
+	for i, table := range s.tables {
+		for _, pi := range table[(window>>s.shifts[i])&s.masks[i]] {
+			if seen[pi] != stamp {
+				seen[pi] = stamp
+				buf = append(buf, pi)
+			}
+		}
+	}
+
+	if len(buf) > 1 {
+		sort.Ints(buf)
+	}
+
+	return buf
+}
+
+// Find where oligos occur in the genome allowing for mismatches.
+// Oligos must all be the same length and can contain Ns, which
+// match any base. Bases that are N in the genome never match.
+func (index *Index) Search(oligos []string, options *SearchOptions) (*SearchResult, error) {
+	strand := options.Strand
+
+	if strand == "" {
+		strand = STRAND_BOTH
+	}
+
+	if strand != STRAND_BOTH && strand != STRAND_POS && strand != STRAND_NEG {
+		return nil, fmt.Errorf("%s is an invalid strand", strand)
+	}
+
+	if options.Mismatches < 0 || options.Mismatches > MAX_MISMATCHES {
+		return nil, fmt.Errorf("mismatches must be 0 to %d", MAX_MISMATCHES)
+	}
+
+	if len(oligos) == 0 {
+		return nil, fmt.Errorf("must supply at least 1 oligo")
+	}
+
+	if len(oligos) > MAX_OLIGOS {
+		return nil, fmt.Errorf("at most %d oligos can be searched at once", MAX_OLIGOS)
+	}
+
+	patterns, l, err := compile(oligos, strand)
+
+	if err != nil {
+		return nil, err
+	}
+
+	maxHits := options.MaxHits
+
+	if maxHits < 1 {
+		maxHits = DEFAULT_MAX_HITS
+	}
+
+	maxHits = min(maxHits, MAX_HITS)
+
+	seeds := newSeeds(patterns, l, options.Mismatches)
+
+	// hits found on each chromosome so that chromosomes can stop
+	// once those before them have enough
+	counts := make([]atomic.Int64, len(index.Chroms))
+
+	results := make([][]*Hit, len(index.Chroms))
+
+	var wg sync.WaitGroup
+
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	for ci, chrom := range index.Chroms {
+		start := uint(0)
+		end := chrom.Len
+
+		if options.Location != nil {
+			if options.Location.Chr != chrom.Name {
+				continue
+			}
+
+			start = max(options.Location.Start, 1) - 1
+			end = min(options.Location.End, chrom.Len)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[ci] = chrom.search(patterns, seeds, l, start, end, options.Mismatches, maxHits, counts[:ci+1])
+		}()
+	}
+
+	wg.Wait()
+
+	// hits are in chromosome order so the same search is always
+	// truncated in the same place
+	ret := SearchResult{Hits: make([]*Hit, 0, 100)}
+
+	for _, hits := range results {
+		ret.Hits = append(ret.Hits, hits...)
+
+		if len(ret.Hits) > maxHits {
+			ret.Hits = ret.Hits[0:maxHits]
+			ret.Truncated = true
+			break
+		}
+	}
+
+	return &ret, nil
+}
+
+// Scan 0-based positions [start, end) for hits. counts holds the
+// hits found so far on the chromosomes before this one, followed by
+// this one's. The scan stops once more than maxHits are known to
+// come before any hit it could still find, so the hits kept are the
+// same whatever order chromosomes are scanned in.
+func (chrom *Chrom) search(patterns []*pattern,
+	seeds *seeds,
+	l int,
+	start uint,
+	end uint,
+	mismatches int,
+	maxHits int,
+	counts []atomic.Int64) []*Hit {
+
+	ret := make([]*Hit, 0, 10)
+
+	count := &counts[len(counts)-1]
+
+	mask := windowMask(l)
+
+	var window uint64
+
+	// the window is only valid once it holds l bases since
+	// the last N
+	valid := start + uint(l)
+
+	// index of the next run of Ns that could overlap the scan
+	run := 0
+
+	for run < len(chrom.NRuns) && chrom.NRuns[run+1] <= uint64(start) {
+		run += 2
+	}
+
+	seen := make([]int, len(patterns))
+	candidates := make([]int, 0, len(patterns))
+
+	for p := start; p < end; p++ {
+		if run < len(chrom.NRuns) && uint64(p) >= chrom.NRuns[run] {
+			// skip the run, the window must refill after it
+			p = uint(min(chrom.NRuns[run+1], uint64(end))) - 1
+			valid = p + uint(l) + 1
+			run += 2
+			continue
+		}
+
+		window = ((window << 2) | chrom.base(p)) & mask
+
+		if p+1 < valid {
+			continue
+		}
+
+		candidates = seeds.candidates(window, seen, int(p-start)+1, candidates)
+
+		for _, pi := range candidates {
+			pat := patterns[pi]
+
+			d := (window ^ pat.code) & pat.care
+			n := bits.OnesCount64((d | (d >> 1)) & evenBits)
+
+			if n > mismatches {
+				continue
+			}
+
+			s := p + 1 - uint(l)
+
+			seq := chrom.seq(s, uint(l))
+
+			if pat.strand == STRAND_NEG {
+				seq = revComp(seq)
+			}
+
+			ret = append(ret, &Hit{Oligo: pat.oligo,
+				Location:   dna.NewLocation(chrom.Name, s+1, p+1),
+				Strand:     pat.strand,
+				Mismatches: n,
+				Seq:        seq})
+
+			if count.Add(1) > int64(maxHits) {
+				return ret
+			}
+		}
+
+		// check now and again whether the chromosomes before
+		// this one have used up the hits
+		if p&0xffff == 0 {
+			var before int64
+
+			for i := range len(counts) - 1 {
+				before += counts[i].Load()
+			}
+
+			if before > int64(maxHits) {
+				return ret
+			}
+		}
+	}
+
+	return ret
+}
+
+// Decode n bases from a 0-based position
+func (chrom *Chrom) seq(p uint, n uint) string {
+	ret := make([]byte, n)
+
+	for i := range n {
+		if chrom.isN(p + i) {
+			ret[i] = 'N'
+		} else {
+			ret[i] = BASES[chrom.base(p+i)]
+		}
+	}
+
+	return string(ret)
+}
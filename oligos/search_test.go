@@ -0,0 +1,149 @@
+package oligos
+
+import (
+	"testing"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/chromsizes"
+)
+
+// chr1 has an EcoRI site, a sequence and its copy after a run of Ns,
+// and chr2 ends with the same sequence
+var testSeqs = map[string]string{
+	"chr1": "TTTTGAATTCTTTTCCAGTATTTTNNNNCCAGTATTTT",
+	"chr2": "GGCCAGTA",
+}
+
+func testIndex(t *testing.T) *Index {
+	t.Helper()
+
+	sizes := []*chromsizes.Size{{Name: "chr1", Len: uint(len(testSeqs["chr1"]))},
+		{Name: "chr2", Len: uint(len(testSeqs["chr2"]))}}
+
+	index, err := Build("test", sizes, func(location *dna.Location) (string, error) {
+		return testSeqs[location.Chr][location.Start-1 : location.End], nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return index
+}
+
+type testHit struct {
+	location   string
+	strand     string
+	mismatches int
+	seq        string
+}
+
+func TestSearch(t *testing.T) {
+	index := testIndex(t)
+
+	tests := []struct {
+		name      string
+		oligos    []string
+		options   SearchOptions
+		hits      []testHit
+		truncated bool
+	}{
+		{name: "palindrome is reported once",
+			oligos: []string{"GAATTC"},
+			hits:   []testHit{{"chr1:5-10", STRAND_POS, 0, "GAATTC"}}},
+		{name: "palindrome on the minus strand",
+			oligos:  []string{"GAATTC"},
+			options: SearchOptions{Strand: STRAND_NEG},
+			hits:    []testHit{{"chr1:5-10", STRAND_NEG, 0, "GAATTC"}}},
+		{name: "plus strand, including at a chromosome end",
+			oligos:  []string{"CCAGTA"},
+			options: SearchOptions{Strand: STRAND_POS},
+			hits: []testHit{{"chr1:15-20", STRAND_POS, 0, "CCAGTA"},
+				{"chr1:29-34", STRAND_POS, 0, "CCAGTA"},
+				{"chr2:3-8", STRAND_POS, 0, "CCAGTA"}}},
+		{name: "minus strand hits are given on the oligo's strand",
+			oligos: []string{"TACTGG"},
+			hits: []testHit{{"chr1:15-20", STRAND_NEG, 0, "TACTGG"},
+				{"chr1:29-34", STRAND_NEG, 0, "TACTGG"},
+				{"chr2:3-8", STRAND_NEG, 0, "TACTGG"}}},
+		{name: "mismatch",
+			oligos:  []string{"CCAGTT"},
+			options: SearchOptions{Strand: STRAND_POS, Mismatches: 1},
+			hits: []testHit{{"chr1:15-20", STRAND_POS, 1, "CCAGTA"},
+				{"chr1:29-34", STRAND_POS, 1, "CCAGTA"},
+				{"chr2:3-8", STRAND_POS, 1, "CCAGTA"}}},
+		{name: "more mismatches than allowed",
+			oligos:  []string{"CCTTTT"},
+			options: SearchOptions{Strand: STRAND_POS, Mismatches: 1, Location: dna.NewLocation("chr1", 15, 20)}},
+		{name: "N in the oligo matches any base",
+			oligos:  []string{"CCNGTA"},
+			options: SearchOptions{Strand: STRAND_POS, Location: dna.NewLocation("chr1", 1, 38)},
+			hits: []testHit{{"chr1:15-20", STRAND_POS, 0, "CCAGTA"},
+				{"chr1:29-34", STRAND_POS, 0, "CCAGTA"}}},
+		{name: "N in the genome never matches",
+			oligos:  []string{"TTNNNNCC"},
+			options: SearchOptions{Strand: STRAND_POS}},
+		{name: "hits right after a run of Ns",
+			oligos:  []string{"CCAGTA"},
+			options: SearchOptions{Strand: STRAND_POS, Location: dna.NewLocation("chr1", 25, 38)},
+			hits:    []testHit{{"chr1:29-34", STRAND_POS, 0, "CCAGTA"}}},
+		{name: "truncated in chromosome order",
+			oligos:    []string{"CCAGTA"},
+			options:   SearchOptions{Strand: STRAND_POS, MaxHits: 1},
+			hits:      []testHit{{"chr1:15-20", STRAND_POS, 0, "CCAGTA"}},
+			truncated: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := index.Search(test.oligos, &test.options)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Truncated != test.truncated {
+				t.Errorf("truncated = %v, want %v", result.Truncated, test.truncated)
+			}
+
+			if len(result.Hits) != len(test.hits) {
+				t.Fatalf("got %d hits, want %d", len(result.Hits), len(test.hits))
+			}
+
+			for i, hit := range result.Hits {
+				got := testHit{hit.Location.String(), hit.Strand, hit.Mismatches, hit.Seq}
+
+				if got != test.hits[i] {
+					t.Errorf("hit %d = %v, want %v", i, got, test.hits[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	index := testIndex(t)
+
+	tests := []struct {
+		name    string
+		oligos  []string
+		options SearchOptions
+	}{
+		{name: "no oligos"},
+		{name: "too short", oligos: []string{"ACGT"}},
+		{name: "different lengths", oligos: []string{"CCAGTA", "CCAGTAA"}},
+		{name: "invalid base", oligos: []string{"CCAGTX"}},
+		{name: "too many mismatches", oligos: []string{"CCAGTA"}, options: SearchOptions{Mismatches: MAX_MISMATCHES + 1}},
+		{name: "invalid strand", oligos: []string{"CCAGTA"}, options: SearchOptions{Strand: "x"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := index.Search(test.oligos, &test.options)
+
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
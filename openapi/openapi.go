@@ -0,0 +1,406 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const OPENAPI_VERSION = "3.0.3"
+
+// Security scheme names
+const (
+	SCHEME_BEARER  = "bearerAuth"
+	SCHEME_SESSION = "sessionCookie"
+	SCHEME_CSRF    = "csrfToken"
+)
+
+// Documentation for a handler. The route table supplies the
+// paths so docs only need to describe payloads.
+type Doc struct {
+	Summary string
+	// instance of the struct bound from the request body
+	Request any
+	// instance of the data returned by the handler
+	Response any
+	Query    []*Param
+	// set for handlers that do not reply with the usual
+	// {status, message, data} envelope
+	Raw bool
+}
+
+// Describes a query parameter
+type Param struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Auth a route requires: every scheme must be satisfied and
+// the user must have the role if one is given
+type Security struct {
+	Schemes []string
+	Role    string
+}
+
+var (
+	BearerSecurity      = &Security{Schemes: []string{SCHEME_BEARER}}
+	SessionSecurity     = &Security{Schemes: []string{SCHEME_SESSION}}
+	SessionCSRFSecurity = &Security{Schemes: []string{SCHEME_SESSION, SCHEME_CSRF}}
+)
+
+func RoleSecurity(role string) *Security {
+	return &Security{Schemes: []string{SCHEME_BEARER}, Role: role}
+}
+
+type securityRule struct {
+	method   string
+	path     string
+	prefix   bool
+	security *Security
+}
+
+var (
+	mu   sync.Mutex
+	docs = make(map[string]*Doc)
+	// docs of single routes, for handlers that serve more than
+	// one route
+	routeDocs = make(map[string]*Doc)
+	rules     = make([]*securityRule, 0, 20)
+)
+
+func QueryParam(name string, description string) *Param {
+	return &Param{Name: name,
+		In:          "query",
+		Description: description,
+		Schema:      &Schema{Type: "string"}}
+}
+
+// Attach docs to a handler. Handlers are matched to routes by
+// function name, the same name gin reports in its route table.
+func Describe(handler gin.HandlerFunc, doc *Doc) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	docs[handlerName(handler)] = doc
+}
+
+// Attach docs to a single route. These take precedence over the
+// handler's docs so a handler serving several routes, e.g. GET and
+// POST of the same path, can describe each one.
+func DescribeRoute(method string, path string, doc *Doc) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	routeDocs[routeKey(method, path)] = doc
+}
+
+func routeKey(method string, path string) string {
+	return method + " " + path
+}
+
+// Mark a single route as requiring auth
+func SecureRoute(method string, path string, security *Security) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	rules = append(rules, &securityRule{method: method, path: path, security: security})
+}
+
+// Mark every route in a group as requiring auth
+func SecureGroup(group *gin.RouterGroup, security *Security) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	rules = append(rules, &securityRule{path: group.BasePath(), prefix: true, security: security})
+}
+
+func handlerName(handler gin.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+}
+
+// most specific rule wins so that a route can override its group
+func findSecurity(method string, path string) *Security {
+	mu.Lock()
+	defer mu.Unlock()
+
+	var ret *Security
+	best := -1
+
+	for _, rule := range rules {
+		if rule.prefix {
+			if (path == rule.path || strings.HasPrefix(path, rule.path+"/")) && len(rule.path) > best {
+				ret = rule.security
+				best = len(rule.path)
+			}
+		} else if rule.method == method && rule.path == path {
+			return rule.security
+		}
+	}
+
+	return ret
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	OperationId string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Param              `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	// role a user must have on top of being authenticated
+	RequiredRole string `json:"x-required-role,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       *Info                            `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components *Components                      `json:"components"`
+}
+
+// Build the spec from the routes registered with gin so that it
+// always matches what the server actually serves
+func Build(info *Info, routes gin.RoutesInfo, sessionCookie string) *Document {
+	gen := newSchemaGen()
+
+	doc := &Document{
+		OpenAPI: OPENAPI_VERSION,
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+		Components: &Components{
+			Schemas: gen.schemas,
+			SecuritySchemes: map[string]*SecurityScheme{
+				SCHEME_BEARER:  {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				SCHEME_SESSION: {Type: "apiKey", In: "cookie", Name: sessionCookie},
+				SCHEME_CSRF:    {Type: "apiKey", In: "header", Name: "X-CSRF-Token"},
+			},
+		},
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path == routes[j].Path {
+			return routes[i].Method < routes[j].Method
+		}
+
+		return routes[i].Path < routes[j].Path
+	})
+
+	for _, route := range routes {
+		path, params := convertPath(route.Path)
+
+		op := &Operation{
+			OperationId: operationId(route.Method, route.Path),
+			Tags:        []string{tag(route.Path)},
+			Parameters:  params,
+			Responses: map[string]*Response{
+				"default": {Description: "error"},
+			},
+		}
+
+		mu.Lock()
+		handlerDoc, ok := routeDocs[routeKey(route.Method, route.Path)]
+
+		if !ok {
+			handlerDoc = docs[route.Handler]
+		}
+
+		mu.Unlock()
+
+		resp := &Response{Description: "success"}
+
+		if handlerDoc != nil {
+			op.Summary = handlerDoc.Summary
+			op.Parameters = append(op.Parameters, handlerDoc.Query...)
+
+			if handlerDoc.Request != nil {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content: map[string]*MediaType{
+						"application/json": {Schema: gen.schema(reflect.TypeOf(handlerDoc.Request))},
+					},
+				}
+			}
+
+			if handlerDoc.Response != nil {
+				schema := gen.schema(reflect.TypeOf(handlerDoc.Response))
+
+				if !handlerDoc.Raw {
+					schema = dataEnvelope(schema)
+				}
+
+				resp.Content = map[string]*MediaType{"application/json": {Schema: schema}}
+			}
+		}
+
+		op.Responses["200"] = resp
+
+		security := findSecurity(route.Method, route.Path)
+
+		if security != nil {
+			req := make(map[string][]string)
+
+			for _, scheme := range security.Schemes {
+				req[scheme] = []string{}
+			}
+
+			op.Security = []map[string][]string{req}
+			op.RequiredRole = security.Role
+			op.Responses["401"] = &Response{Description: "unauthorized"}
+		}
+
+		item, ok := doc.Paths[path]
+
+		if !ok {
+			item = make(map[string]*Operation)
+			doc.Paths[path] = item
+		}
+
+		item[strings.ToLower(route.Method)] = op
+	}
+
+	return doc
+}
+
+// responses from web.MakeDataResp wrap the data
+func dataEnvelope(data *Schema) *Schema {
+	return &Schema{Type: "object",
+		Properties: map[string]*Schema{
+			"status":  {Type: "integer"},
+			"message": {Type: "string"},
+			"data":    data,
+		}}
+}
+
+// convert gin's /:assembly to /{assembly}
+func convertPath(path string) (string, []*Param) {
+	tokens := strings.Split(path, "/")
+	params := make([]*Param, 0, len(tokens))
+
+	for i, token := range tokens {
+		if strings.HasPrefix(token, ":") || strings.HasPrefix(token, "*") {
+			name := token[1:]
+			tokens[i] = "{" + name + "}"
+
+			params = append(params, &Param{Name: name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"}})
+		}
+	}
+
+	return strings.Join(tokens, "/"), params
+}
+
+// group routes by their first path element, or the module name
+// for module routes
+func tag(path string) string {
+	tokens := strings.Split(strings.Trim(path, "/"), "/")
+
+	if len(tokens) > 1 && tokens[0] == "modules" {
+		return tokens[1]
+	}
+
+	return tokens[0]
+}
+
+func operationId(method string, path string) string {
+	replacer := strings.NewReplacer("/", "_", ":", "", "*", "", "-", "_")
+
+	return strings.ToLower(method) + replacer.Replace(path)
+}
+
+type Routes struct {
+	info          *Info
+	engine        *gin.Engine
+	sessionCookie string
+	once          sync.Once
+	doc           *Document
+	page          []byte
+}
+
+// swaggerUI is where the swagger-ui-dist css and js are loaded
+// from, e.g. a CDN or a copy served by the same host
+func NewRoutes(engine *gin.Engine, info *Info, sessionCookie string, swaggerUI string) *Routes {
+	return &Routes{engine: engine,
+		info:          info,
+		sessionCookie: sessionCookie,
+		page:          []byte(fmt.Sprintf(DOCS_HTML, strings.TrimSuffix(swaggerUI, "/")))}
+}
+
+// Serve the spec, built on first request once all routes
+// have been registered
+func (routes *Routes) SpecRoute(c *gin.Context) {
+	routes.once.Do(func() {
+		routes.doc = Build(routes.info, routes.engine.Routes(), routes.sessionCookie)
+	})
+
+	c.JSON(http.StatusOK, routes.doc)
+}
+
+// Serve a browsable page for the spec
+func (routes *Routes) DocsRoute(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", routes.page)
+}
+
+// Swagger UI page, formatted with the location of its assets
+const DOCS_HTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>API Docs</title>
+  <link rel="stylesheet" href="%[1]s/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="%[1]s/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,150 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A JSON schema as used by OpenAPI 3
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generates schemas from Go types using their json tags. Named
+// structs are added to the component schemas and referenced so
+// that shared types such as dna.Location are only described once.
+type schemaGen struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{schemas: make(map[string]*Schema)}
+}
+
+func (gen *schemaGen) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// []byte is encoded as base64 by encoding/json
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: gen.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: gen.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return gen.structSchema(t)
+		}
+
+		name := schemaName(t)
+
+		_, ok := gen.schemas[name]
+
+		if !ok {
+			// reserve the name first so recursive types terminate
+			gen.schemas[name] = &Schema{Type: "object"}
+			gen.schemas[name] = gen.structSchema(t)
+		}
+
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		// interfaces and anything else can hold any value
+		return &Schema{}
+	}
+}
+
+func (gen *schemaGen) structSchema(t reflect.Type) *Schema {
+	ret := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	gen.addFields(ret, t)
+
+	return ret
+}
+
+func (gen *schemaGen) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		// embedded structs without a name have their fields
+		// promoted as encoding/json does
+		if field.Anonymous && name == "" {
+			ft := field.Type
+
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				gen.addFields(schema, ft)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = gen.schema(field.Type)
+	}
+}
+
+// e.g. dna.Location
+func schemaName(t reflect.Type) string {
+	pkg := t.PkgPath()
+
+	if i := strings.LastIndex(pkg, "/"); i != -1 {
+		pkg = pkg[i+1:]
+	}
+
+	name := t.Name()
+
+	// generic instantiations contain characters not allowed in
+	// component names
+	name = strings.NewReplacer("[", "_", "]", "", "/", "_", "*", "", ",", "_", " ", "").Replace(name)
+
+	if pkg == "" {
+		return name
+	}
+
+	return pkg + "." + name
+}
@@ -0,0 +1,358 @@
+package primers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+)
+
+// Only the best candidates on each side are paired up, which
+// keeps design fast for long templates
+const MAX_CANDIDATES = 200
+
+const MAX_PRODUCT_SIZE = 5000
+
+// Limits on primers and their products. Zero values are replaced
+// with the defaults, which follow common qPCR guidelines.
+type Constraints struct {
+	MinProductSize int     `json:"minProductSize"`
+	MaxProductSize int     `json:"maxProductSize"`
+	OptProductSize int     `json:"optProductSize"`
+	MinLen         int     `json:"minLen"`
+	MaxLen         int     `json:"maxLen"`
+	OptLen         int     `json:"optLen"`
+	MinTm          float64 `json:"minTm"`
+	MaxTm          float64 `json:"maxTm"`
+	OptTm          float64 `json:"optTm"`
+	MaxTmDiff      float64 `json:"maxTmDiff"`
+	MinGC          float64 `json:"minGC"`
+	MaxGC          float64 `json:"maxGC"`
+	// complementarity scores, see Complementarity
+	MaxSelfAny int `json:"maxSelfAny"`
+	MaxSelfEnd int `json:"maxSelfEnd"`
+	MaxPairAny int `json:"maxPairAny"`
+	MaxPairEnd int `json:"maxPairEnd"`
+	// longest run of one base, e.g. AAAAA
+	MaxPolyX int `json:"maxPolyX"`
+	// allow primers over repeat masked bases
+	AllowRepeats bool `json:"allowRepeats"`
+	// monovalent salt in mM and primer in nM, for Tm
+	NaMM     float64 `json:"naMM"`
+	PrimerNM float64 `json:"primerNM"`
+	// pairs to return per target
+	N int `json:"n"`
+}
+
+type Primer struct {
+	Location *dna.Location `json:"location"`
+	Strand   string        `json:"strand"`
+	Seq      string        `json:"seq"`
+	Len      int           `json:"len"`
+	Tm       float64       `json:"tm"`
+	GC       float64       `json:"gc"`
+	SelfAny  int           `json:"selfAny"`
+	SelfEnd  int           `json:"selfEnd"`
+	penalty  float64
+}
+
+type PrimerPair struct {
+	Rank        int           `json:"rank"`
+	Left        *Primer       `json:"left"`
+	Right       *Primer       `json:"right"`
+	Product     *dna.Location `json:"product"`
+	ProductSize int           `json:"productSize"`
+	TmDiff      float64       `json:"tmDiff"`
+	PairAny     int           `json:"pairAny"`
+	PairEnd     int           `json:"pairEnd"`
+	// lower is better
+	Penalty float64 `json:"penalty"`
+}
+
+func DefaultConstraints() *Constraints {
+	return &Constraints{MinProductSize: 70,
+		MaxProductSize: 200,
+		OptProductSize: 120,
+		MinLen:         18,
+		MaxLen:         25,
+		OptLen:         20,
+		MinTm:          57,
+		MaxTm:          63,
+		OptTm:          60,
+		MaxTmDiff:      3,
+		MinGC:          40,
+		MaxGC:          60,
+		MaxSelfAny:     8,
+		MaxSelfEnd:     3,
+		MaxPairAny:     8,
+		MaxPairEnd:     3,
+		MaxPolyX:       4,
+		NaMM:           50,
+		PrimerNM:       50,
+		N:              5}
+}
+
+// Fill in any constraints that were not set and check the rest
+// make sense
+func (c *Constraints) Complete() error {
+	d := DefaultConstraints()
+
+	setInt := func(v *int, def int) {
+		if *v == 0 {
+			*v = def
+		}
+	}
+
+	setFloat := func(v *float64, def float64) {
+		if *v == 0 {
+			*v = def
+		}
+	}
+
+	setInt(&c.MinProductSize, d.MinProductSize)
+	setInt(&c.MaxProductSize, d.MaxProductSize)
+	setInt(&c.MinLen, d.MinLen)
+	setInt(&c.MaxLen, d.MaxLen)
+	setInt(&c.MaxSelfAny, d.MaxSelfAny)
+	setInt(&c.MaxSelfEnd, d.MaxSelfEnd)
+	setInt(&c.MaxPairAny, d.MaxPairAny)
+	setInt(&c.MaxPairEnd, d.MaxPairEnd)
+	setInt(&c.MaxPolyX, d.MaxPolyX)
+	setInt(&c.N, d.N)
+	setFloat(&c.MinTm, d.MinTm)
+	setFloat(&c.MaxTm, d.MaxTm)
+	setFloat(&c.MaxTmDiff, d.MaxTmDiff)
+	setFloat(&c.MinGC, d.MinGC)
+	setFloat(&c.MaxGC, d.MaxGC)
+	setFloat(&c.NaMM, d.NaMM)
+	setFloat(&c.PrimerNM, d.PrimerNM)
+
+	// optimums default to the middle of their ranges
+	setInt(&c.OptProductSize, (c.MinProductSize+c.MaxProductSize)/2)
+	setInt(&c.OptLen, (c.MinLen+c.MaxLen)/2)
+	setFloat(&c.OptTm, (c.MinTm+c.MaxTm)/2)
+
+	// zero values were replaced above so anything left below one
+	// was negative
+	if c.MinProductSize < 1 || c.OptProductSize < 1 {
+		return fmt.Errorf("product sizes must be positive")
+	}
+
+	if c.MinLen < 1 || c.OptLen < 1 {
+		return fmt.Errorf("primer lengths must be positive")
+	}
+
+	if c.MinTm < 0 || c.OptTm < 0 || c.MaxTmDiff < 0 {
+		return fmt.Errorf("Tm limits cannot be negative")
+	}
+
+	if c.MinGC < 0 || c.MaxGC > 100 {
+		return fmt.Errorf("GC must be a range within 0 to 100")
+	}
+
+	if c.NaMM < 0 || c.PrimerNM < 0 {
+		return fmt.Errorf("salt and primer concentrations cannot be negative")
+	}
+
+	if c.N < 1 {
+		return fmt.Errorf("n must be at least 1")
+	}
+
+	if c.MinProductSize > c.MaxProductSize || c.MaxProductSize > MAX_PRODUCT_SIZE {
+		return fmt.Errorf("product size must be a range of at most %d", MAX_PRODUCT_SIZE)
+	}
+
+	if c.MinLen > c.MaxLen || c.MaxLen > 36 {
+		return fmt.Errorf("primer length must be a range of at most 36")
+	}
+
+	if c.MinTm > c.MaxTm {
+		return fmt.Errorf("min Tm is greater than max Tm")
+	}
+
+	if c.MinGC > c.MaxGC {
+		return fmt.Errorf("min GC is greater than max GC")
+	}
+
+	return nil
+}
+
+func maxPolyX(seq string) int {
+	ret := 0
+	n := 0
+
+	for i := 0; i < len(seq); i++ {
+		if i > 0 && seq[i] == seq[i-1] {
+			n++
+		} else {
+			n = 1
+		}
+
+		ret = max(ret, n)
+	}
+
+	return ret
+}
+
+// Check a primer sequence, as it would be ordered, against the
+// constraints, returning nil if it fails
+func (c *Constraints) primer(seq string) *Primer {
+	// repeat masked bases are lowercase
+	if !c.AllowRepeats && strings.ToUpper(seq) != seq {
+		return nil
+	}
+
+	seq = strings.ToUpper(seq)
+
+	if strings.Contains(seq, "N") || maxPolyX(seq) > c.MaxPolyX {
+		return nil
+	}
+
+	gc := GC(seq)
+
+	if gc < c.MinGC || gc > c.MaxGC {
+		return nil
+	}
+
+	tm := Tm(seq, c.NaMM, c.PrimerNM)
+
+	if tm < c.MinTm || tm > c.MaxTm {
+		return nil
+	}
+
+	selfAny := Complementarity(seq, seq, false)
+	selfEnd := Complementarity(seq, seq, true)
+
+	if selfAny > c.MaxSelfAny || selfEnd > c.MaxSelfEnd {
+		return nil
+	}
+
+	penalty := math.Abs(tm-c.OptTm) +
+		math.Abs(float64(len(seq)-c.OptLen)) +
+		math.Abs(gc-(c.MinGC+c.MaxGC)/2)/10 +
+		float64(selfAny)/4 +
+		float64(selfEnd)/2
+
+	return &Primer{Seq: seq,
+		Len:     len(seq),
+		Tm:      math.Round(tm*100) / 100,
+		GC:      math.Round(gc*100) / 100,
+		SelfAny: selfAny,
+		SelfEnd: selfEnd,
+		penalty: penalty}
+}
+
+func best(primers []*Primer) []*Primer {
+	sort.SliceStable(primers, func(i, j int) bool {
+		return primers[i].penalty < primers[j].penalty
+	})
+
+	return primers[0:min(len(primers), MAX_CANDIDATES)]
+}
+
+// Design primer pairs whose product covers a target. The template
+// is the + strand sequence starting at the 1-based templateStart on
+// chr and the target is given as 0-based offsets [targetStart,
+// targetEnd) into it. Pairs are returned best first.
+func Design(template string,
+	chr string,
+	templateStart uint,
+	targetStart int,
+	targetEnd int,
+	c *Constraints) []*PrimerPair {
+
+	if targetEnd-targetStart > c.MaxProductSize {
+		return []*PrimerPair{}
+	}
+
+	lefts := make([]*Primer, 0, 100)
+	rights := make([]*Primer, 0, 100)
+
+	// left primers must end before the target and right primers
+	// start after it
+	for l := c.MinLen; l <= c.MaxLen; l++ {
+		for s := max(0, targetEnd-c.MaxProductSize); s+l <= targetStart; s++ {
+			p := c.primer(template[s : s+l])
+
+			if p == nil {
+				continue
+			}
+
+			p.Strand = "+"
+			p.Location = dna.NewLocation(chr, templateStart+uint(s), templateStart+uint(s+l)-1)
+
+			lefts = append(lefts, p)
+		}
+
+		for s := targetEnd; s+l <= min(len(template), targetStart+c.MaxProductSize); s++ {
+			p := c.primer(RevComp(template[s : s+l]))
+
+			if p == nil {
+				continue
+			}
+
+			p.Strand = "-"
+			p.Location = dna.NewLocation(chr, templateStart+uint(s), templateStart+uint(s+l)-1)
+
+			rights = append(rights, p)
+		}
+	}
+
+	lefts = best(lefts)
+	rights = best(rights)
+
+	ret := make([]*PrimerPair, 0, 100)
+
+	for _, left := range lefts {
+		for _, right := range rights {
+			size := int(right.Location.End-left.Location.Start) + 1
+
+			if size < c.MinProductSize || size > c.MaxProductSize {
+				continue
+			}
+
+			tmDiff := math.Abs(left.Tm - right.Tm)
+
+			if tmDiff > c.MaxTmDiff {
+				continue
+			}
+
+			pairAny := max(Complementarity(left.Seq, right.Seq, false), Complementarity(right.Seq, left.Seq, false))
+			pairEnd := max(Complementarity(left.Seq, right.Seq, true), Complementarity(right.Seq, left.Seq, true))
+
+			if pairAny > c.MaxPairAny || pairEnd > c.MaxPairEnd {
+				continue
+			}
+
+			penalty := left.penalty +
+				right.penalty +
+				tmDiff +
+				math.Abs(float64(size-c.OptProductSize))/10 +
+				float64(pairAny)/4 +
+				float64(pairEnd)/2
+
+			ret = append(ret, &PrimerPair{Left: left,
+				Right:       right,
+				Product:     dna.NewLocation(chr, left.Location.Start, right.Location.End),
+				ProductSize: size,
+				TmDiff:      math.Round(tmDiff*100) / 100,
+				PairAny:     pairAny,
+				PairEnd:     pairEnd,
+				Penalty:     math.Round(penalty*1000) / 1000})
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Penalty < ret[j].Penalty
+	})
+
+	ret = ret[0:min(len(ret), c.N)]
+
+	for i, pair := range ret {
+		pair.Rank = i + 1
+	}
+
+	return ret
+}
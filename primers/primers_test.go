@@ -0,0 +1,210 @@
+package primers
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// A reproducible template of mixed bases with a GC content that
+// suits the default constraints
+func testTemplate(n int) string {
+	var b strings.Builder
+
+	x := uint32(12345)
+
+	for range n {
+		x = x*1664525 + 1013904223
+		b.WriteByte("ACGT"[x>>30])
+	}
+
+	return b.String()
+}
+
+func TestGC(t *testing.T) {
+	tests := []struct {
+		seq string
+		gc  float64
+	}{
+		{"", 0},
+		{"AAAA", 0},
+		{"GCGC", 100},
+		{"ACGT", 50},
+		{"acgt", 50},
+	}
+
+	for _, test := range tests {
+		gc := GC(test.seq)
+
+		if gc != test.gc {
+			t.Errorf("GC(%q) = %v, want %v", test.seq, gc, test.gc)
+		}
+	}
+}
+
+func TestTm(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  string
+		min  float64
+		max  float64
+	}{
+		{"too short", "A", 0, 0},
+		{"typical primer", "AGCGGATAACAATTTCACACAGGA", 50, 60},
+		{"GC rich is hotter", "GCGCGGCCGCGGCGCC", 65, 90},
+		{"AT rich is cooler", "ATATTAATATTTAATA", 0, 40},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tm := Tm(test.seq, 50, 50)
+
+			if tm < test.min || tm > test.max {
+				t.Errorf("Tm(%s) = %.2f, want %v to %v", test.seq, tm, test.min, test.max)
+			}
+		})
+	}
+
+	if Tm("agcggataacaatttcacacagga", 50, 50) != Tm("AGCGGATAACAATTTCACACAGGA", 50, 50) {
+		t.Error("Tm should ignore case")
+	}
+}
+
+func TestComplementarity(t *testing.T) {
+	tests := []struct {
+		a    string
+		b    string
+		end  bool
+		want int
+	}{
+		{"AAAA", "TTTT", false, 4},
+		{"AAAA", "AAAA", false, 0},
+		// palindromes pair with themselves
+		{"GAATTC", "GAATTC", false, 6},
+		{"GAATTC", "GAATTC", true, 6},
+		// only the 3' end of a is scored
+		{"CCCCAAAA", "GGGG", true, 0},
+		{"CCCCAAAA", "GGGG", false, 4},
+		{"NNNN", "NNNN", false, 0},
+	}
+
+	for _, test := range tests {
+		n := Complementarity(test.a, test.b, test.end)
+
+		if n != test.want {
+			t.Errorf("Complementarity(%s, %s, %v) = %d, want %d", test.a, test.b, test.end, n, test.want)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints Constraints
+		ok          bool
+	}{
+		{"defaults", Constraints{}, true},
+		{"negative product size", Constraints{MinProductSize: -1}, false},
+		{"product too long", Constraints{MaxProductSize: MAX_PRODUCT_SIZE + 1}, false},
+		{"inverted lengths", Constraints{MinLen: 25, MaxLen: 18}, false},
+		{"inverted Tm", Constraints{MinTm: 65, MaxTm: 55}, false},
+		{"GC over 100", Constraints{MaxGC: 101}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.constraints.Complete()
+
+			if (err == nil) != test.ok {
+				t.Errorf("Complete() = %v, want ok %v", err, test.ok)
+			}
+		})
+	}
+}
+
+func TestDesign(t *testing.T) {
+	template := testTemplate(600)
+
+	tests := []struct {
+		name        string
+		template    string
+		targetStart int
+		targetEnd   int
+		pairs       bool
+	}{
+		{"target in the middle", template, 290, 310, true},
+		{"target longer than the product", template, 100, 500, false},
+		{"no room for a left primer", template, 0, 20, false},
+		{"no room for a right primer", template, 580, 600, false},
+		{"repeat masked template", strings.ToLower(template), 290, 310, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Constraints{}
+
+			err := c.Complete()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			const templateStart = 1001
+
+			pairs := Design(test.template, "chr1", templateStart, test.targetStart, test.targetEnd, &c)
+
+			if (len(pairs) > 0) != test.pairs {
+				t.Fatalf("got %d pairs, want pairs %v", len(pairs), test.pairs)
+			}
+
+			if len(pairs) > c.N {
+				t.Errorf("got %d pairs, want at most %d", len(pairs), c.N)
+			}
+
+			for i, pair := range pairs {
+				if pair.Rank != i+1 {
+					t.Errorf("pair %d has rank %d", i, pair.Rank)
+				}
+
+				if i > 0 && pair.Penalty < pairs[i-1].Penalty {
+					t.Errorf("pair %d is better than pair %d", i, i-1)
+				}
+
+				left := pair.Left
+				right := pair.Right
+
+				if left.Strand != "+" || right.Strand != "-" {
+					t.Errorf("pair %d has strands %s %s", i, left.Strand, right.Strand)
+				}
+
+				ls := int(left.Location.Start - templateStart)
+				rs := int(right.Location.Start - templateStart)
+
+				if left.Seq != test.template[ls:ls+left.Len] {
+					t.Errorf("left primer %s is not the template at %s", left.Seq, left.Location)
+				}
+
+				// right primers are ordered as the reverse complement
+				if right.Seq != RevComp(test.template[rs:rs+right.Len]) {
+					t.Errorf("right primer %s is not the template at %s reverse complemented", right.Seq, right.Location)
+				}
+
+				if ls+left.Len > test.targetStart || rs < test.targetEnd {
+					t.Errorf("pair %d overlaps the target", i)
+				}
+
+				if pair.ProductSize < c.MinProductSize || pair.ProductSize > c.MaxProductSize {
+					t.Errorf("pair %d has product size %d", i, pair.ProductSize)
+				}
+
+				if pair.ProductSize != int(pair.Product.End-pair.Product.Start)+1 {
+					t.Errorf("pair %d product %s is not %d bases", i, pair.Product, pair.ProductSize)
+				}
+
+				if math.Abs(left.Tm-right.Tm) > c.MaxTmDiff {
+					t.Errorf("pair %d Tms differ by more than %v", i, c.MaxTmDiff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package primers
+
+import (
+	"math"
+	"strings"
+)
+
+// gas constant in cal/K/mol
+const R = 1.987
+
+const KELVIN = 273.15
+
+// Nearest neighbor enthalpy (kcal/mol) and entropy (cal/K/mol)
+// for each 5'->3' dinucleotide stack (SantaLucia, 1998)
+type nn struct {
+	dH float64
+	dS float64
+}
+
+var NN = map[string]nn{
+	"AA": {-7.9, -22.2}, "TT": {-7.9, -22.2},
+	"AT": {-7.2, -20.4},
+	"TA": {-7.2, -21.3},
+	"CA": {-8.5, -22.7}, "TG": {-8.5, -22.7},
+	"GT": {-8.4, -22.4}, "AC": {-8.4, -22.4},
+	"CT": {-7.8, -21.0}, "AG": {-7.8, -21.0},
+	"GA": {-8.2, -22.2}, "TC": {-8.2, -22.2},
+	"CG": {-10.6, -27.2},
+	"GC": {-9.8, -24.4},
+	"GG": {-8.0, -19.9}, "CC": {-8.0, -19.9},
+}
+
+// initiation with a terminal G/C or A/T pair
+var initGC = nn{0.1, -2.8}
+var initAT = nn{2.3, 4.1}
+
+func complement(b byte) byte {
+	switch b {
+	case 'A':
+		return 'T'
+	case 'C':
+		return 'G'
+	case 'G':
+		return 'C'
+	case 'T':
+		return 'A'
+	default:
+		return 'N'
+	}
+}
+
+func RevComp(seq string) string {
+	ret := make([]byte, len(seq))
+
+	for i := 0; i < len(seq); i++ {
+		ret[len(seq)-1-i] = complement(seq[i])
+	}
+
+	return string(ret)
+}
+
+func terminal(b byte) nn {
+	if b == 'G' || b == 'C' {
+		return initGC
+	}
+
+	return initAT
+}
+
+// Melting temperature in C of a primer binding its complement,
+// using nearest neighbor thermodynamics with a salt correction.
+// Sodium and primer concentrations are in mM and nM.
+func Tm(seq string, naMM float64, primerNM float64) float64 {
+	seq = strings.ToUpper(seq)
+
+	if len(seq) < 2 {
+		return 0
+	}
+
+	start := terminal(seq[0])
+	end := terminal(seq[len(seq)-1])
+
+	dH := start.dH + end.dH
+	dS := start.dS + end.dS
+
+	for i := 0; i < len(seq)-1; i++ {
+		p, ok := NN[seq[i:i+2]]
+
+		if !ok {
+			// Ns have no parameters so use the average stack
+			p = nn{-8.2, -22.2}
+		}
+
+		dH += p.dH
+		dS += p.dS
+	}
+
+	// x = 4 for non self complementary primers
+	ct := primerNM * 1e-9 / 4
+
+	if seq == RevComp(seq) {
+		dS -= 1.4
+		ct = primerNM * 1e-9
+	}
+
+	dS += 0.368 * float64(len(seq)-1) * math.Log(naMM/1000)
+
+	return dH*1000/(dS+R*math.Log(ct)) - KELVIN
+}
+
+// Percentage of bases that are G or C
+func GC(seq string) float64 {
+	if len(seq) == 0 {
+		return 0
+	}
+
+	n := 0
+
+	for i := 0; i < len(seq); i++ {
+		switch seq[i] {
+		case 'G', 'C', 'g', 'c':
+			n++
+		}
+	}
+
+	return 100 * float64(n) / float64(len(seq))
+}
+
+// Score the best ungapped alignment of a against the reverse of b
+// where complementary bases score 1 and others -1. With end true
+// the alignment must include the 3' end of a, which is what
+// matters for primer dimers since that is where extension starts.
+func Complementarity(a string, b string, end bool) int {
+	best := 0
+
+	pairs := func(i int, j int) bool {
+		// b is read 3'->5' so that pairing is antiparallel
+		return a[i] != 'N' && complement(a[i]) == b[len(b)-1-j]
+	}
+
+	// offset of b relative to a
+	for offset := -(len(b) - 1); offset < len(a); offset++ {
+		score := 0
+
+		if end {
+			for i := len(a) - 1; i >= 0 && i-offset >= 0 && i-offset < len(b); i-- {
+				if pairs(i, i-offset) {
+					score++
+				} else {
+					score--
+				}
+
+				best = max(best, score)
+			}
+
+			continue
+		}
+
+		for i := max(0, offset); i < len(a) && i-offset < len(b); i++ {
+			if pairs(i, i-offset) {
+				score++
+			} else {
+				score = max(0, score-1)
+			}
+
+			best = max(best, score)
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,162 @@
+package protein
+
+import (
+	"sort"
+	"strings"
+)
+
+// Amino acid for codons with Ns or other ambiguous bases
+const UNKNOWN_AA = 'X'
+
+const STOP = '*'
+
+const START_CODON = "ATG"
+
+// The standard genetic code
+var CODONS = map[string]byte{
+	"TTT": 'F', "TTC": 'F', "TTA": 'L', "TTG": 'L',
+	"CTT": 'L', "CTC": 'L', "CTA": 'L', "CTG": 'L',
+	"ATT": 'I', "ATC": 'I', "ATA": 'I', "ATG": 'M',
+	"GTT": 'V', "GTC": 'V', "GTA": 'V', "GTG": 'V',
+	"TCT": 'S', "TCC": 'S', "TCA": 'S', "TCG": 'S',
+	"CCT": 'P', "CCC": 'P', "CCA": 'P', "CCG": 'P',
+	"ACT": 'T', "ACC": 'T', "ACA": 'T', "ACG": 'T',
+	"GCT": 'A', "GCC": 'A', "GCA": 'A', "GCG": 'A',
+	"TAT": 'Y', "TAC": 'Y', "TAA": '*', "TAG": '*',
+	"CAT": 'H', "CAC": 'H', "CAA": 'Q', "CAG": 'Q',
+	"AAT": 'N', "AAC": 'N', "AAA": 'K', "AAG": 'K',
+	"GAT": 'D', "GAC": 'D', "GAA": 'E', "GAG": 'E',
+	"TGT": 'C', "TGC": 'C', "TGA": '*', "TGG": 'W',
+	"CGT": 'R', "CGC": 'R', "CGA": 'R', "CGG": 'R',
+	"AGT": 'S', "AGC": 'S', "AGA": 'R', "AGG": 'R',
+	"GGT": 'G', "GGC": 'G', "GGA": 'G', "GGG": 'G',
+}
+
+// An open reading frame from a start codon to a stop codon. Start
+// and End are 0-based, half open offsets into the + strand of the
+// sequence searched and include the stop codon. Frames are 1 to 3
+// on the + strand and -1 to -3 on the - strand.
+type ORF struct {
+	Frame   int    `json:"frame"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Protein string `json:"protein"`
+}
+
+func RevComp(seq string) string {
+	ret := make([]byte, len(seq))
+
+	for i := 0; i < len(seq); i++ {
+		var b byte
+
+		switch seq[i] {
+		case 'A', 'a':
+			b = 'T'
+		case 'C', 'c':
+			b = 'G'
+		case 'G', 'g':
+			b = 'C'
+		case 'T', 't':
+			b = 'A'
+		default:
+			b = 'N'
+		}
+
+		ret[len(seq)-1-i] = b
+	}
+
+	return string(ret)
+}
+
+func Codon(codon string) byte {
+	aa, ok := CODONS[strings.ToUpper(codon)]
+
+	if !ok {
+		return UNKNOWN_AA
+	}
+
+	return aa
+}
+
+// Translate every whole codon of a sequence, including stops
+func Translate(seq string) string {
+	ret := make([]byte, 0, len(seq)/3)
+
+	for i := 0; i+3 <= len(seq); i += 3 {
+		ret = append(ret, Codon(seq[i:i+3]))
+	}
+
+	return string(ret)
+}
+
+// Translate a sequence in a frame, 1 to 3 or -1 to -3
+func TranslateFrame(seq string, frame int) string {
+	if frame < 0 {
+		return Translate(RevComp(seq)[min(-frame-1, len(seq)):])
+	}
+
+	return Translate(seq[min(frame-1, len(seq)):])
+}
+
+// Find ORFs of at least minLen amino acids, not counting the stop,
+// in the given frames. ORFs are returned longest first. Nested ORFs
+// starting at a later ATG in the same frame are not reported.
+func FindORFs(seq string, frames []int, minLen int) []*ORF {
+	ret := make([]*ORF, 0, 10)
+
+	rc := RevComp(seq)
+
+	for _, frame := range frames {
+		s := seq
+		offset := frame - 1
+
+		if frame < 0 {
+			s = rc
+			offset = -frame - 1
+		}
+
+		// 0-based start of the current ORF in s, -1 if not in one
+		start := -1
+
+		for i := offset; i+3 <= len(s); i += 3 {
+			codon := strings.ToUpper(s[i : i+3])
+
+			if start == -1 {
+				if codon == START_CODON {
+					start = i
+				}
+
+				continue
+			}
+
+			if Codon(codon) != STOP {
+				continue
+			}
+
+			end := i + 3
+
+			if (end-start)/3-1 >= minLen {
+				orf := ORF{Frame: frame,
+					Start:   start,
+					End:     end,
+					Protein: Translate(s[start : end-3])}
+
+				// offsets are always on the + strand
+				if frame < 0 {
+					orf.Start = len(seq) - end
+					orf.End = len(seq) - start
+				}
+
+				ret = append(ret, &orf)
+			}
+
+			start = -1
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return len(ret[i].Protein) > len(ret[j].Protein)
+	})
+
+	return ret
+}
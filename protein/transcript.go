@@ -0,0 +1,252 @@
+package protein
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/variants"
+)
+
+const (
+	EFFECT_OUTSIDE       = "outside_transcript"
+	EFFECT_INTRONIC      = "intronic"
+	EFFECT_REF_MISMATCH  = "ref_mismatch"
+	EFFECT_NON_CODING    = "non_coding"
+	EFFECT_UTR           = "utr"
+	EFFECT_SYNONYMOUS    = "synonymous"
+	EFFECT_MISSENSE      = "missense"
+	EFFECT_STOP_GAINED   = "stop_gained"
+	EFFECT_STOP_LOST     = "stop_lost"
+	EFFECT_START_LOST    = "start_lost"
+	EFFECT_FRAMESHIFT    = "frameshift"
+	EFFECT_INFRAME_INDEL = "inframe_indel"
+)
+
+// Coding part of a transcript. Start and End are 0-based, half open
+// offsets into the mRNA and include the stop codon.
+type CDS struct {
+	Start     int             `json:"start"`
+	End       int             `json:"end"`
+	Locations []*dna.Location `json:"locations"`
+	Seq       string          `json:"seq"`
+	Protein   string          `json:"protein"`
+	// true if the CDS was predicted from the sequence rather
+	// than annotated
+	Predicted bool `json:"predicted"`
+}
+
+// A spliced transcript. Exons are in transcript order, so run from
+// the highest coordinates down on the - strand.
+type Transcript struct {
+	Id     string          `json:"id"`
+	Strand string          `json:"strand"`
+	Exons  []*dna.Location `json:"exons"`
+	MRNA   string          `json:"mrna"`
+	CDS    *CDS            `json:"cds"`
+	// offset of each exon in the mRNA
+	offsets []int
+}
+
+type Effect struct {
+	Variant string `json:"variant"`
+	Effect  string `json:"effect"`
+	// 1-based codon and amino acid, for single codon changes
+	Codon    int    `json:"codon,omitempty"`
+	RefCodon string `json:"refCodon,omitempty"`
+	AltCodon string `json:"altCodon,omitempty"`
+	// e.g. p.V600E
+	Change string `json:"change,omitempty"`
+}
+
+// Splice a transcript from its exons and their sequences, both in
+// transcript order with sequences on the transcript's strand. The
+// gene dbs have no CDS coordinates, so the CDS is taken to be the
+// longest ORF of the mRNA.
+func NewTranscript(id string, strand string, exons []*dna.Location, seqs []string) *Transcript {
+	ret := Transcript{Id: id,
+		Strand:  strand,
+		Exons:   exons,
+		MRNA:    strings.ToUpper(strings.Join(seqs, "")),
+		offsets: make([]int, len(exons))}
+
+	offset := 0
+
+	for ei, seq := range seqs {
+		ret.offsets[ei] = offset
+		offset += len(seq)
+	}
+
+	orfs := FindORFs(ret.MRNA, []int{1, 2, 3}, 1)
+
+	if len(orfs) > 0 {
+		orf := orfs[0]
+
+		ret.CDS = &CDS{Start: orf.Start,
+			End:       orf.End,
+			Locations: ret.Locations(orf.Start, orf.End),
+			Seq:       ret.MRNA[orf.Start:orf.End],
+			Protein:   orf.Protein,
+			Predicted: true}
+	}
+
+	return &ret
+}
+
+// Genomic blocks covered by 0-based, half open mRNA offsets, in
+// transcript order
+func (transcript *Transcript) Locations(start int, end int) []*dna.Location {
+	ret := make([]*dna.Location, 0, len(transcript.Exons))
+
+	for ei, exon := range transcript.Exons {
+		s := max(start, transcript.offsets[ei])
+		e := min(end, transcript.offsets[ei]+int(exon.Len()))
+
+		if s >= e {
+			continue
+		}
+
+		// offsets within the exon
+		s -= transcript.offsets[ei]
+		e -= transcript.offsets[ei]
+
+		if transcript.Strand == "-" {
+			ret = append(ret, dna.NewLocation(exon.Chr, exon.End-uint(e)+1, exon.End-uint(s)))
+		} else {
+			ret = append(ret, dna.NewLocation(exon.Chr, exon.Start+uint(s), exon.Start+uint(e)-1))
+		}
+	}
+
+	return ret
+}
+
+// Where a variant is in the mRNA, with its alleles on the
+// transcript's strand, or -1 if it is not within an exon
+func (transcript *Transcript) mrnaOffset(variant *variants.Variant) (int, string, string) {
+	for ei, exon := range transcript.Exons {
+		if variant.Chr != exon.Chr || variant.Start < exon.Start || variant.End() > exon.End {
+			continue
+		}
+
+		// insertions must be between two bases of the exon
+		if variant.Ref == "" && variant.Start == exon.Start {
+			continue
+		}
+
+		if transcript.Strand == "-" {
+			return transcript.offsets[ei] + int(exon.End-variant.End()),
+				RevComp(variant.Ref),
+				RevComp(variant.Alt)
+		}
+
+		return transcript.offsets[ei] + int(variant.Start-exon.Start), variant.Ref, variant.Alt
+	}
+
+	return -1, "", ""
+}
+
+// translate from an offset until a stop codon, which is not included
+func translateToStop(seq string, start int) string {
+	ret := make([]byte, 0, (len(seq)-start)/3)
+
+	for i := start; i+3 <= len(seq); i += 3 {
+		aa := Codon(seq[i : i+3])
+
+		if aa == STOP {
+			break
+		}
+
+		ret = append(ret, aa)
+	}
+
+	return string(ret)
+}
+
+// Predict how a variant changes the protein of a transcript
+func (transcript *Transcript) Effect(variant *variants.Variant) *Effect {
+	ret := Effect{Variant: variant.String()}
+
+	offset, ref, alt := transcript.mrnaOffset(variant)
+
+	if offset == -1 {
+		ret.Effect = EFFECT_INTRONIC
+
+		first := transcript.Exons[0]
+		last := transcript.Exons[len(transcript.Exons)-1]
+
+		if variant.Chr != first.Chr ||
+			variant.End() < min(first.Start, last.Start) ||
+			variant.Start > max(first.End, last.End) {
+			ret.Effect = EFFECT_OUTSIDE
+		}
+
+		return &ret
+	}
+
+	mrna := transcript.MRNA
+
+	if mrna[offset:offset+len(ref)] != ref {
+		ret.Effect = EFFECT_REF_MISMATCH
+		return &ret
+	}
+
+	cds := transcript.CDS
+
+	if cds == nil {
+		ret.Effect = EFFECT_NON_CODING
+		return &ret
+	}
+
+	if offset+len(ref) <= cds.Start || offset >= cds.End ||
+		(ref == "" && offset == cds.Start) {
+		ret.Effect = EFFECT_UTR
+		return &ret
+	}
+
+	altMRNA := mrna[0:offset] + alt + mrna[offset+len(ref):]
+
+	diff := len(alt) - len(ref)
+
+	switch {
+	case diff%3 != 0:
+		ret.Effect = EFFECT_FRAMESHIFT
+		return &ret
+	case offset < cds.Start+3 && altMRNA[cds.Start:cds.Start+3] != START_CODON:
+		ret.Effect = EFFECT_START_LOST
+		return &ret
+	case diff != 0:
+		ret.Effect = EFFECT_INFRAME_INDEL
+		return &ret
+	}
+
+	// same length changes of one or more codons, described by the
+	// first codon they touch
+	codon := (max(offset, cds.Start) - cds.Start) / 3
+	p := cds.Start + 3*codon
+
+	ret.Codon = codon + 1
+	ret.RefCodon = mrna[p : p+3]
+	ret.AltCodon = altMRNA[p : p+3]
+
+	refAA := Codon(ret.RefCodon)
+	altAA := Codon(ret.AltCodon)
+
+	protein := translateToStop(altMRNA, cds.Start)
+
+	switch {
+	case protein == cds.Protein:
+		ret.Effect = EFFECT_SYNONYMOUS
+		ret.Change = fmt.Sprintf("p.%c%d=", refAA, ret.Codon)
+		return &ret
+	case len(protein) < len(cds.Protein) && altAA == STOP:
+		ret.Effect = EFFECT_STOP_GAINED
+	case len(protein) > len(cds.Protein):
+		ret.Effect = EFFECT_STOP_LOST
+	default:
+		ret.Effect = EFFECT_MISSENSE
+	}
+
+	ret.Change = fmt.Sprintf("p.%c%d%c", refAA, ret.Codon, altAA)
+
+	return &ret
+}
@@ -0,0 +1,240 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/consts"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	"github.com/antonybholmes/go-web"
+	"github.com/antonybholmes/go-web/auth"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+const REDIS_PREFIX = "edb:ratelimit:"
+
+// quota used for clients that are not signed in
+const ROLE_GUEST = "guest"
+
+// quota counters are kept a little beyond the day so clocks
+// that disagree slightly do not reset them early
+const QUOTA_EXPIRES = 25 * time.Hour
+
+// Who a request is counted against
+type Client struct {
+	// e.g. user:<public id> or ip:<addr>
+	Key   string
+	Roles []string
+}
+
+type Limiter struct {
+	store  Store
+	groups map[string]*config.RateLimit
+	quotas map[string]int
+}
+
+var instance *Limiter
+
+// Set up rate limiting. Passing a redis client shares the limits
+// between instances. Until this is called, or if limits are
+// disabled, the middleware does nothing.
+func InitRateLimits(cfg *config.Config, rdb *redis.Client) {
+	Close()
+
+	if !cfg.RateLimits.Enabled {
+		instance = nil
+		return
+	}
+
+	var store Store
+
+	if cfg.RateLimits.Redis && rdb != nil {
+		store = NewRedisStore(rdb, REDIS_PREFIX)
+	} else {
+		store = NewMemoryStore()
+	}
+
+	quotas := make(map[string]int)
+
+	for role, quota := range cfg.RateLimits.DailyQuotas {
+		quotas[strings.ToLower(role)] = quota
+	}
+
+	instance = &Limiter{store: store,
+		groups: cfg.RateLimits.Groups,
+		quotas: quotas}
+}
+
+// Stop the background work of the in memory limits
+func Close() {
+	if instance == nil {
+		return
+	}
+
+	if store, ok := instance.store.(*MemoryStore); ok {
+		store.Close()
+	}
+}
+
+// Limit the requests to a route group using the settings for
+// group in the config. Requests over the limit or a client's
+// daily quota get a 429 with a Retry-After header. Jobs are not
+// limited since their submission was.
+func Middleware(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter := instance
+
+		if limiter == nil || jobs.IsJob(c) {
+			c.Next()
+			return
+		}
+
+		limit, ok := limiter.groups[group]
+
+		if !ok || limit == nil || limit.Rate <= 0 {
+			c.Next()
+			return
+		}
+
+		client := identify(c)
+
+		ctx := c.Request.Context()
+
+		allowed, wait, err := limiter.store.Allow(ctx, group+":"+client.Key, limit)
+
+		// fail open so a redis outage does not take down the api
+		if err != nil {
+			log.Ctx(ctx).Error().Msgf("rate limit: %s", err)
+			c.Next()
+			return
+		}
+
+		if !allowed {
+			tooManyRequests(c, wait, "rate limit exceeded")
+			return
+		}
+
+		quota := limiter.quota(client.Roles)
+
+		if quota > 0 {
+			now := time.Now().UTC()
+
+			n, err := limiter.store.Incr(ctx,
+				fmt.Sprintf("%s:%s", client.Key, now.Format(time.DateOnly)),
+				QUOTA_EXPIRES)
+
+			if err != nil {
+				log.Ctx(ctx).Error().Msgf("rate limit quota: %s", err)
+				c.Next()
+				return
+			}
+
+			c.Header("X-Quota-Limit", strconv.Itoa(quota))
+			c.Header("X-Quota-Remaining", strconv.FormatInt(max(int64(quota)-n, 0), 10))
+
+			if n > int64(quota) {
+				midnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+				tooManyRequests(c, midnight.Sub(now), "daily quota exceeded")
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func tooManyRequests(c *gin.Context, wait time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+
+	c.AbortWithStatusJSON(http.StatusTooManyRequests,
+		gin.H{"status": http.StatusTooManyRequests, "message": message})
+}
+
+// The largest quota of the roles. 0 means unlimited.
+func (limiter *Limiter) quota(roles []string) int {
+	ret := -1
+
+	for _, role := range roles {
+		quota, ok := limiter.quotas[strings.ToLower(role)]
+
+		if !ok {
+			continue
+		}
+
+		if quota <= 0 {
+			return 0
+		}
+
+		ret = max(ret, quota)
+	}
+
+	return max(ret, 0)
+}
+
+// Work out who is making a request. Limits are applied before
+// the auth middleware so credentials are verified here; anything
+// that cannot be verified falls back to the client ip so that
+// made up credentials cannot be used to dodge limits. The ip is
+// only taken from X-Forwarded-For when the request came through
+// one of the server's trusted proxies, otherwise clients could
+// pick a new ip for every request.
+func identify(c *gin.Context) *Client {
+	// already authenticated further up the chain
+	if user, ok := c.Get(web.SESSION_USER); ok {
+		switch u := user.(type) {
+		case *auth.TokenClaims:
+			return &Client{Key: "user:" + u.UserId, Roles: splitRoles(u.Roles)}
+		case *auth.AuthUser:
+			return &Client{Key: "user:" + u.PublicId, Roles: u.Roles}
+		}
+	}
+
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+	if ok {
+		claims := auth.TokenClaims{}
+
+		_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (any, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+
+			return consts.JWT_RSA_PUBLIC_KEY, nil
+		})
+
+		if err == nil && claims.UserId != "" {
+			return &Client{Key: "user:" + claims.UserId, Roles: splitRoles(claims.Roles)}
+		}
+	}
+
+	sess := sessions.Default(c)
+
+	if data, ok := sess.Get(web.SESSION_USER).(string); ok {
+		var user auth.AuthUser
+
+		err := json.Unmarshal([]byte(data), &user)
+
+		if err == nil && user.PublicId != "" {
+			return &Client{Key: "user:" + user.PublicId, Roles: user.Roles}
+		}
+	}
+
+	return &Client{Key: "ip:" + c.ClientIP(), Roles: []string{ROLE_GUEST}}
+}
+
+// role claims are a delimited list of roles
+func splitRoles(claim string) []string {
+	return strings.FieldsFunc(claim, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';'
+	})
+}
@@ -0,0 +1,232 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// how often idle buckets and expired quota counters are removed
+const SWEEP_INTERVAL = time.Minute
+
+// buckets idle this long have refilled so are the same as new ones
+const BUCKET_IDLE = time.Hour
+
+type Store interface {
+	// Take a token from the bucket for key, returning how long to
+	// wait if none are left
+	Allow(ctx context.Context, key string, limit *config.RateLimit) (bool, time.Duration, error)
+
+	// Count a request against a daily quota, returning the count
+	// including this request
+	Incr(ctx context.Context, key string, expires time.Duration) (int64, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type counter struct {
+	n       int64
+	expires time.Time
+}
+
+// Per instance limits
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	counters map[string]*counter
+	stop     chan struct{}
+	once     sync.Once
+}
+
+func NewMemoryStore() *MemoryStore {
+	store := MemoryStore{buckets: make(map[string]*bucket),
+		counters: make(map[string]*counter),
+		stop:     make(chan struct{})}
+
+	go func() {
+		ticker := time.NewTicker(SWEEP_INTERVAL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-store.stop:
+				return
+			case now := <-ticker.C:
+				store.sweep(now)
+			}
+		}
+	}()
+
+	return &store
+}
+
+// Stop sweeping
+func (store *MemoryStore) Close() {
+	store.once.Do(func() {
+		close(store.stop)
+	})
+}
+
+func (store *MemoryStore) Allow(ctx context.Context, key string, limit *config.RateLimit) (bool, time.Duration, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	burst := float64(limit.Burst)
+
+	b, ok := store.buckets[key]
+
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		store.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	return false, retryAfter(b.tokens, limit.Rate), nil
+}
+
+// remove idle buckets and expired quota counters
+func (store *MemoryStore) sweep(now time.Time) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for key, b := range store.buckets {
+		if now.Sub(b.last) > BUCKET_IDLE {
+			delete(store.buckets, key)
+		}
+	}
+
+	for key, c := range store.counters {
+		if now.After(c.expires) {
+			delete(store.counters, key)
+		}
+	}
+}
+
+func (store *MemoryStore) Incr(ctx context.Context, key string, expires time.Duration) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+
+	c, ok := store.counters[key]
+
+	if !ok || now.After(c.expires) {
+		c = &counter{expires: now.Add(expires)}
+		store.counters[key] = c
+	}
+
+	c.n++
+
+	return c.n, nil
+}
+
+// Token bucket in a hash so it can be updated atomically. Returns
+// 1 if allowed and the tokens left.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+
+if tokens == nil then
+	tokens = burst
+	last = now
+end
+
+tokens = math.min(burst, tokens + (now - last) * rate)
+
+local allowed = 0
+
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "last", now)
+redis.call("EXPIRE", KEYS[1], math.ceil(burst / rate) + 1)
+
+return {allowed, tostring(tokens)}
+`)
+
+// Limits shared by all instances
+type RedisStore struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+func NewRedisStore(rdb *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{rdb: rdb, prefix: prefix}
+}
+
+func (store *RedisStore) Allow(ctx context.Context, key string, limit *config.RateLimit) (bool, time.Duration, error) {
+	now := float64(time.Now().UnixMicro()) / 1e6
+
+	res, err := tokenBucketScript.Run(ctx,
+		store.rdb,
+		[]string{store.prefix + "bucket:" + key},
+		limit.Rate,
+		limit.Burst,
+		now).Slice()
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	allowed, _ := res[0].(int64)
+
+	if allowed == 1 {
+		return true, 0, nil
+	}
+
+	tokens := 0.0
+
+	if s, ok := res[1].(string); ok {
+		tokens, _ = strconv.ParseFloat(s, 64)
+	}
+
+	return false, retryAfter(tokens, limit.Rate), nil
+}
+
+func (store *RedisStore) Incr(ctx context.Context, key string, expires time.Duration) (int64, error) {
+	key = store.prefix + "quota:" + key
+
+	n, err := store.rdb.Incr(ctx, key).Result()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if n == 1 {
+		store.rdb.Expire(ctx, key, expires)
+	}
+
+	return n, nil
+}
+
+// time until the bucket has a whole token
+func retryAfter(tokens float64, rate float64) time.Duration {
+	if rate <= 0 {
+		return time.Hour
+	}
+
+	return time.Duration((1 - tokens) / rate * float64(time.Second))
+}
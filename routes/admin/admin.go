@@ -3,8 +3,8 @@ package admin
 import (
 	"github.com/antonybholmes/go-edb-server-gin/consts"
 	authenticationroutes "github.com/antonybholmes/go-edb-server-gin/routes/authentication"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-mailer"
-	"github.com/antonybholmes/go-mailer/queue"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/userdbcache"
 	"github.com/gin-gonic/gin"
@@ -26,7 +26,9 @@ func UserStatsRoute(c *gin.Context) {
 
 	c.Bind(&req)
 
+	span := tracing.Start(c, "userdbcache.NumUsers")
 	users, err := userdbcache.NumUsers()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -45,7 +47,9 @@ func UsersRoute(c *gin.Context) {
 
 	c.Bind(&req)
 
+	span := tracing.Start(c, "userdbcache.Users")
 	users, err := userdbcache.Users(req.Records, req.Offset)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -58,7 +62,9 @@ func UsersRoute(c *gin.Context) {
 
 func RolesRoute(c *gin.Context) {
 
+	span := tracing.Start(c, "userdbcache.Roles")
 	roles, err := userdbcache.Roles()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -91,20 +97,24 @@ func UpdateUserRoute(c *gin.Context) {
 
 		authUser := validator.AuthUser
 
+		span := tracing.Start(c, "userdbcache.SetUserInfo")
 		err := userdbcache.SetUserInfo(authUser,
 			validator.UserBodyReq.Username,
 			validator.UserBodyReq.FirstName,
 			validator.UserBodyReq.LastName,
 			true)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
+		span = tracing.Start(c, "userdbcache.SetEmailAddress")
 		err = userdbcache.SetEmailAddress(authUser,
 			validator.Address,
 			true)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -112,8 +122,10 @@ func UpdateUserRoute(c *gin.Context) {
 		}
 
 		if validator.UserBodyReq.Password != "" {
+			span := tracing.Start(c, "userdbcache.SetPassword")
 			err = userdbcache.SetPassword(authUser,
 				validator.UserBodyReq.Password)
+			tracing.End(span, err)
 
 			if err != nil {
 				c.Error(err)
@@ -122,12 +134,14 @@ func UpdateUserRoute(c *gin.Context) {
 			}
 		}
 
-		log.Debug().Msgf("roles %s %v", authUser.Email, validator.UserBodyReq.Roles)
+		log.Ctx(c.Request.Context()).Debug().Msgf("roles %s %v", authUser.Email, validator.UserBodyReq.Roles)
 
 		// set roles
+		span = tracing.Start(c, "userdbcache.SetUserRoles")
 		err = userdbcache.SetUserRoles(authUser,
 			validator.UserBodyReq.Roles,
 			true)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -143,6 +157,7 @@ func AddUserRoute(c *gin.Context) {
 	authenticationroutes.NewValidator(c).CheckUsernameIsWellFormed().CheckEmailIsWellFormed().Success(func(validator *authenticationroutes.Validator) {
 
 		// assume email is not verified
+		span := tracing.Start(c, "userdbcache.CreateUser")
 		authUser, err := userdbcache.Instance().CreateUser(
 			validator.UserBodyReq.Username,
 			validator.Address,
@@ -150,6 +165,7 @@ func AddUserRoute(c *gin.Context) {
 			validator.UserBodyReq.FirstName,
 			validator.UserBodyReq.LastName,
 			validator.UserBodyReq.EmailIsVerified)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -164,7 +180,7 @@ func AddUserRoute(c *gin.Context) {
 			To:        authUser.Email,
 			EmailType: mailer.QUEUE_EMAIL_TYPE_ACCOUNT_CREATED,
 			LinkUrl:   consts.APP_URL}
-		queue.PublishEmail(&email)
+		authenticationroutes.PublishEmail(c, &email)
 
 		web.MakeOkResp(c, "account created email sent")
 	})
@@ -173,7 +189,9 @@ func AddUserRoute(c *gin.Context) {
 func DeleteUserRoute(c *gin.Context) {
 	publicId := c.Param("publicId")
 
+	span := tracing.Start(c, "userdbcache.DeleteUser")
 	err := userdbcache.DeleteUser(publicId)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
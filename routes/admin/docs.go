@@ -0,0 +1,12 @@
+package admin
+
+import "github.com/antonybholmes/go-edb-server-gin/openapi"
+
+func init() {
+	openapi.Describe(UsersRoute, &openapi.Doc{Summary: "List users", Request: UserListReq{}})
+	openapi.Describe(UserStatsRoute, &openapi.Doc{Summary: "Number of users", Response: UserStatResp{}})
+	openapi.Describe(RolesRoute, &openapi.Doc{Summary: "List roles"})
+	openapi.Describe(UpdateUserRoute, &openapi.Doc{Summary: "Update a user"})
+	openapi.Describe(AddUserRoute, &openapi.Doc{Summary: "Add a user"})
+	openapi.Describe(DeleteUserRoute, &openapi.Doc{Summary: "Delete a user"})
+}
@@ -0,0 +1,17 @@
+package authentication
+
+import (
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-web/auth"
+)
+
+func init() {
+	openapi.Describe(SignupRoute, &openapi.Doc{Summary: "Sign up a new user", Request: auth.UserBodyReq{}})
+	openapi.Describe(UsernamePasswordSignInRoute, &openapi.Doc{Summary: "Sign in with a username and password", Request: auth.UserBodyReq{}})
+	openapi.Describe(PasswordlessSignInRoute, &openapi.Doc{Summary: "Sign in using a passwordless token"})
+	openapi.Describe(SendResetPasswordFromUsernameEmailRoute, &openapi.Doc{Summary: "Send a password reset email", Request: auth.UserBodyReq{}})
+	openapi.Describe(SendResetEmailEmailRoute, &openapi.Doc{Summary: "Send an email address reset email", Request: auth.UserBodyReq{}})
+	openapi.Describe(CreateTokenFromSessionRoute, &openapi.Doc{Summary: "Create a token of the given type for the session user"})
+	openapi.Describe(UserFromSessionRoute, &openapi.Doc{Summary: "Get the session user"})
+	openapi.Describe(SessionSignOutRoute, &openapi.Doc{Summary: "Sign out of the session"})
+}
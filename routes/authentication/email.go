@@ -6,6 +6,8 @@ import (
 	"net/mail"
 
 	"github.com/antonybholmes/go-edb-server-gin/consts"
+	"github.com/antonybholmes/go-edb-server-gin/metrics"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/auth"
 	"github.com/antonybholmes/go-web/tokengen"
@@ -16,6 +18,16 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Publish an email to the queue so it can be sent by the mailer,
+// counting it for the metrics endpoint
+func PublishEmail(c *gin.Context, email *mailer.QueueEmail) {
+	span := tracing.Start(c, "queue.PublishEmail")
+	queue.PublishEmail(email)
+	tracing.End(span, nil)
+
+	metrics.EmailPublished(email.EmailType)
+}
+
 // Start passwordless login by sending an email
 func SendResetEmailEmailRoute(c *gin.Context) {
 	NewValidator(c).ParseLoginRequestBody().LoadAuthUserFromToken().Success(func(validator *Validator) {
@@ -60,7 +72,7 @@ func SendResetEmailEmailRoute(c *gin.Context) {
 			Ttl:       fmt.Sprintf("%d minutes", int(consts.SHORT_TTL_MINS.Minutes())),
 			LinkUrl:   consts.URL_RESET_EMAIL,
 		}
-		queue.PublishEmail(&email)
+		PublishEmail(c, &email)
 
 		//if err != nil {
 		//	return web.ErrorReq(err)
@@ -88,16 +100,20 @@ func UpdateEmailRoute(c *gin.Context) {
 		authUser := validator.AuthUser
 		publicId := authUser.PublicId
 
+		span := tracing.Start(c, "userdbcache.SetEmailAddress")
 		err = userdbcache.SetEmailAddress(authUser,
 			validator.Address,
 			false)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
+		span = tracing.Start(c, "userdbcache.FindUserByPublicId")
 		authUser, err = userdbcache.FindUserByPublicId(publicId)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -110,7 +126,7 @@ func UpdateEmailRoute(c *gin.Context) {
 			Name:      authUser.FirstName,
 			To:        authUser.Email,
 			EmailType: mailer.QUEUE_EMAIL_TYPE_EMAIL_UPDATED}
-		queue.PublishEmail(&email)
+		PublishEmail(c, &email)
 
 		web.MakeOkResp(c, "email updated confirmation email sent")
 	})
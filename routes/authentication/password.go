@@ -4,7 +4,7 @@ import (
 	"fmt"
 
 	"github.com/antonybholmes/go-edb-server-gin/consts"
-	"github.com/antonybholmes/go-mailer/queue"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/auth"
 	"github.com/antonybholmes/go-web/tokengen"
@@ -53,7 +53,7 @@ func SendResetPasswordFromUsernameEmailRoute(c *gin.Context) {
 			EmailType: mailer.QUEUE_EMAIL_TYPE_PASSWORD_RESET,
 			Ttl:       fmt.Sprintf("%d minutes", int(consts.SHORT_TTL_MINS.Minutes())),
 			LinkUrl:   consts.URL_RESET_PASSWORD}
-		queue.PublishEmail(&email)
+		PublishEmail(c, &email)
 
 		//if err != nil {
 		//	return web.ErrorReq(err)
@@ -80,7 +80,9 @@ func UpdatePasswordRoute(c *gin.Context) {
 			return
 		}
 
+		span := tracing.Start(c, "userdbcache.SetPassword")
 		err = userdbcache.SetPassword(authUser, validator.UserBodyReq.Password)
+		tracing.End(span, err)
 
 		if err != nil {
 			web.BadReqResp(c, web.ERROR_WRONG_TOKEN_TYPE)
@@ -93,7 +95,7 @@ func UpdatePasswordRoute(c *gin.Context) {
 			Name:      authUser.FirstName,
 			To:        authUser.Email,
 			EmailType: mailer.QUEUE_EMAIL_TYPE_PASSWORD_UPDATED}
-		queue.PublishEmail(&email)
+		PublishEmail(c, &email)
 
 		web.MakeOkResp(c, "password updated confirmation email sent")
 	})
@@ -9,6 +9,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/antonybholmes/go-edb-server-gin/metrics"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/auth"
 	"github.com/antonybholmes/go-web/middleware"
@@ -140,7 +142,9 @@ func (sr *SessionRoutes) SessionUsernamePasswordSignInRoute(c *gin.Context) {
 
 	user := validator.UserBodyReq.Username
 
+	span := tracing.Start(c, "userdbcache.FindUserByUsername")
 	authUser, err := userdbcache.FindUserByUsername(user)
+	tracing.End(span, err)
 
 	if err != nil {
 		web.UserDoesNotExistResp(c)
@@ -152,7 +156,9 @@ func (sr *SessionRoutes) SessionUsernamePasswordSignInRoute(c *gin.Context) {
 		return
 	}
 
+	span = tracing.Start(c, "userdbcache.UserRoleList")
 	roles, err := userdbcache.UserRoleList(authUser)
+	tracing.End(span, err)
 
 	if err != nil {
 		web.ForbiddenResp(c, "could not get user roles")
@@ -205,6 +211,8 @@ func (sr *SessionRoutes) SessionUsernamePasswordSignInRoute(c *gin.Context) {
 		return
 	}
 
+	metrics.SignIn(metrics.SIGNIN_SESSION_PASSWORD)
+
 	web.MakeCsrfTokenResp(c)
 	//return c.NoContent(http.StatusOK)
 }
@@ -217,7 +225,9 @@ func (sr *SessionRoutes) SessionApiKeySignInRoute(c *gin.Context) {
 		return
 	}
 
+	span := tracing.Start(c, "userdbcache.FindUserByApiKey")
 	authUser, err := userdbcache.FindUserByApiKey(validator.UserBodyReq.ApiKey)
+	tracing.End(span, err)
 
 	if err != nil {
 		web.UserDoesNotExistResp(c)
@@ -229,7 +239,9 @@ func (sr *SessionRoutes) SessionApiKeySignInRoute(c *gin.Context) {
 		return
 	}
 
+	span = tracing.Start(c, "userdbcache.UserRoleList")
 	roles, err := userdbcache.UserRoleList(authUser)
+	tracing.End(span, err)
 
 	if err != nil {
 		web.ForbiddenResp(c, "could not get user roles")
@@ -252,6 +264,8 @@ func (sr *SessionRoutes) SessionApiKeySignInRoute(c *gin.Context) {
 
 	//MakeCsrfTokenResp(c, token)
 
+	metrics.SignIn(metrics.SIGNIN_API_KEY)
+
 	web.MakeOkResp(c, "user has been signed in")
 
 	// resp, err := readSession(c)
@@ -267,7 +281,7 @@ func (sr *SessionRoutes) SessionSignInUsingAuth0Route(c *gin.Context) {
 	user, ok := c.Get(web.SESSION_USER)
 
 	for key := range c.Keys {
-		log.Debug().Msgf("key %s", key)
+		log.Ctx(c.Request.Context()).Debug().Msgf("key %s", key)
 	}
 
 	if !ok {
@@ -293,21 +307,23 @@ func (sr *SessionRoutes) SessionSignInUsingAuth0Route(c *gin.Context) {
 		return
 	}
 
+	span := tracing.Start(c, "userdbcache.CreateUserFromOAuth2")
 	authUser, err := userdbcache.CreateUserFromOAuth2(tokenClaims.Name, email)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	sr.sessionSignInUsingOAuth2(c, authUser)
+	sr.sessionSignInUsingOAuth2(c, authUser, metrics.SIGNIN_AUTH0)
 }
 
 func (sr *SessionRoutes) SessionSignInUsingClerkRoute(c *gin.Context) {
 	user, ok := c.Get(web.SESSION_USER)
 
 	for key := range c.Keys {
-		log.Debug().Msgf("key %s", key)
+		log.Ctx(c.Request.Context()).Debug().Msgf("key %s", key)
 	}
 
 	if !ok {
@@ -325,21 +341,23 @@ func (sr *SessionRoutes) SessionSignInUsingClerkRoute(c *gin.Context) {
 		return
 	}
 
+	span := tracing.Start(c, "userdbcache.CreateUserFromOAuth2")
 	authUser, err := userdbcache.CreateUserFromOAuth2(tokenClaims.Name, email)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	sr.sessionSignInUsingOAuth2(c, authUser)
+	sr.sessionSignInUsingOAuth2(c, authUser, metrics.SIGNIN_CLERK)
 }
 
 func (sr *SessionRoutes) SessionSignInUsingSupabaseRoute(c *gin.Context) {
 	user, ok := c.Get(web.SESSION_USER)
 
 	for key := range c.Keys {
-		log.Debug().Msgf("key %s", key)
+		log.Ctx(c.Request.Context()).Debug().Msgf("key %s", key)
 	}
 
 	if !ok {
@@ -357,19 +375,23 @@ func (sr *SessionRoutes) SessionSignInUsingSupabaseRoute(c *gin.Context) {
 		return
 	}
 
+	span := tracing.Start(c, "userdbcache.CreateUserFromOAuth2")
 	authUser, err := userdbcache.CreateUserFromOAuth2(tokenClaims.Email, email)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	sr.sessionSignInUsingOAuth2(c, authUser)
+	sr.sessionSignInUsingOAuth2(c, authUser, metrics.SIGNIN_SUPABASE)
 }
 
-func (sr *SessionRoutes) sessionSignInUsingOAuth2(c *gin.Context, authUser *auth.AuthUser) {
+func (sr *SessionRoutes) sessionSignInUsingOAuth2(c *gin.Context, authUser *auth.AuthUser, method string) {
 
+	span := tracing.Start(c, "userdbcache.UserRoleList")
 	roles, err := userdbcache.UserRoleList(authUser)
+	tracing.End(span, err)
 
 	if err != nil {
 		web.BadReqResp(c, "user roles not found")
@@ -392,6 +414,8 @@ func (sr *SessionRoutes) sessionSignInUsingOAuth2(c *gin.Context, authUser *auth
 
 	//log.Debug().Msgf("token %s", token)
 
+	metrics.SignIn(method)
+
 	web.MakeCsrfTokenResp(c)
 
 	//web.MakeOkResp(c, "user has been signed in")
@@ -411,7 +435,9 @@ func (sr *SessionRoutes) SessionPasswordlessValidateSignInRoute(c *gin.Context)
 
 		authUser := validator.AuthUser
 
+		span := tracing.Start(c, "userdbcache.UserRoleList")
 		roles, err := userdbcache.UserRoleList(authUser)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -434,6 +460,8 @@ func (sr *SessionRoutes) SessionPasswordlessValidateSignInRoute(c *gin.Context)
 			return
 		}
 
+		metrics.SignIn(metrics.SIGNIN_SESSION_PASSWORDLESS)
+
 		web.MakeOkResp(c, "user has signed in") //MakeCsrfTokenResp(c, token)
 	})
 }
@@ -493,7 +521,9 @@ func (sr *SessionRoutes) SessionRefreshRoute(c *gin.Context) {
 	}
 
 	// refresh user
+	span := tracing.Start(c, "userdbcache.FindUserById")
 	authUser, err := userdbcache.FindUserById(user.(*auth.AuthUser).Id)
+	tracing.End(span, err)
 
 	if err != nil {
 		web.UnauthorizedResp(c, "user not found")
@@ -577,6 +607,8 @@ func CreateTokenFromSessionRoute(c *gin.Context) {
 		return
 	}
 
+	metrics.TokenIssued(tokenType)
+
 	web.MakeDataResp(c, "", &web.TokenResp{Token: token})
 
 }
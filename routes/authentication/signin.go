@@ -4,8 +4,9 @@ import (
 	"fmt"
 
 	"github.com/antonybholmes/go-edb-server-gin/consts"
+	"github.com/antonybholmes/go-edb-server-gin/metrics"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-mailer"
-	"github.com/antonybholmes/go-mailer/queue"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/auth"
 	"github.com/antonybholmes/go-web/tokengen"
@@ -25,7 +26,9 @@ func UsernamePasswordSignInRoute(c *gin.Context) {
 			return
 		}
 
+		span := tracing.Start(c, "userdbcache.FindUserByUsername")
 		authUser, err := userdbcache.FindUserByUsername(validator.UserBodyReq.Username)
+		tracing.End(span, err)
 
 		if err != nil {
 			web.UserDoesNotExistResp(c)
@@ -37,7 +40,9 @@ func UsernamePasswordSignInRoute(c *gin.Context) {
 			return
 		}
 
+		span = tracing.Start(c, "userdbcache.UserRoleList")
 		roles, err := userdbcache.UserRoleList(authUser)
+		tracing.End(span, err)
 
 		if err != nil {
 			web.ForbiddenResp(c, "could not get user roles")
@@ -72,6 +77,8 @@ func UsernamePasswordSignInRoute(c *gin.Context) {
 			return
 		}
 
+		metrics.SignIn(metrics.SIGNIN_PASSWORD)
+
 		web.MakeDataResp(c, "", &web.LoginResp{
 			RefreshToken: refreshToken,
 			AccessToken:  accessToken})
@@ -124,7 +131,7 @@ func PasswordlessSigninEmailRoute(c *gin.Context, validator *Validator) {
 			//VisitUrl:    validator.Req.VisitUrl
 		}
 
-		queue.PublishEmail(&email)
+		PublishEmail(c, &email)
 
 		//if err != nil {
 		//	return web.ErrorReq(err)
@@ -144,7 +151,9 @@ func PasswordlessSignInRoute(c *gin.Context) {
 
 		authUser := validator.AuthUser
 
+		span := tracing.Start(c, "userdbcache.UserRoleList")
 		roles, err := userdbcache.UserRoleList(authUser)
+		tracing.End(span, err)
 
 		if err != nil {
 			web.ForbiddenResp(c, "could not get user roles")
@@ -165,6 +174,8 @@ func PasswordlessSignInRoute(c *gin.Context) {
 			return
 		}
 
+		metrics.SignIn(metrics.SIGNIN_PASSWORDLESS)
+
 		web.MakeDataResp(c, "", &web.RefreshTokenResp{RefreshToken: t})
 	})
 }
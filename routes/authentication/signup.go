@@ -4,8 +4,8 @@ import (
 	"fmt"
 
 	"github.com/antonybholmes/go-edb-server-gin/consts"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-mailer"
-	"github.com/antonybholmes/go-mailer/queue"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/tokengen"
 	"github.com/antonybholmes/go-web/userdbcache"
@@ -16,7 +16,9 @@ func SignupRoute(c *gin.Context) {
 	NewValidator(c).CheckEmailIsWellFormed().Success(func(validator *Validator) {
 		req := validator.UserBodyReq
 
+		span := tracing.Start(c, "userdbcache.CreateUserFromSignup")
 		authUser, err := userdbcache.CreateUserFromSignup(req)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -61,7 +63,7 @@ func SignupRoute(c *gin.Context) {
 			//VisitUrl:    req.VisitUrl
 		}
 
-		queue.PublishEmail(&email)
+		PublishEmail(c, &email)
 
 		web.MakeOkResp(c, "check your email for a verification link")
 	})
@@ -77,7 +79,9 @@ func EmailAddressVerifiedRoute(c *gin.Context) {
 			web.MakeOkResp(c, "")
 		}
 
+		span := tracing.Start(c, "userdbcache.SetIsVerified")
 		err := userdbcache.SetIsVerified(authUser.PublicId)
+		tracing.End(span, err)
 
 		if err != nil {
 			web.MakeSuccessResp(c, "unable to verify user", false)
@@ -96,7 +100,7 @@ func EmailAddressVerifiedRoute(c *gin.Context) {
 			Name:      authUser.FirstName,
 			To:        authUser.Email,
 			EmailType: mailer.QUEUE_EMAIL_TYPE_VERIFIED}
-		queue.PublishEmail(&email)
+		PublishEmail(c, &email)
 
 		web.MakeOkResp(c, "email address verified")
 	})
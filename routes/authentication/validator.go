@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/mail"
 
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/auth"
 	"github.com/antonybholmes/go-web/middleware"
@@ -92,7 +93,7 @@ func (validator *Validator) CheckUsernameIsWellFormed() *Validator {
 	err := auth.CheckUsername(validator.UserBodyReq.Username)
 
 	if err != nil {
-		log.Debug().Msgf("check user name err %s", err)
+		log.Ctx(validator.c.Request.Context()).Debug().Msgf("check user name err %s", err)
 		validator.Err = err
 	}
 
@@ -126,7 +127,9 @@ func (validator *Validator) LoadAuthUserFromPublicId() *Validator {
 		return validator
 	}
 
+	span := tracing.Start(validator.c, "userdbcache.FindUserByPublicId")
 	authUser, err := userdbcache.FindUserByPublicId(validator.UserBodyReq.PublicId)
+	tracing.End(span, err)
 
 	if err != nil {
 		validator.Err = fmt.Errorf(web.ERROR_USER_DOES_NOT_EXIST)
@@ -145,7 +148,9 @@ func (validator *Validator) LoadAuthUserFromEmail() *Validator {
 		return validator
 	}
 
+	span := tracing.Start(validator.c, "userdbcache.FindUserByEmail")
 	authUser, err := userdbcache.FindUserByEmail(validator.Address)
+	tracing.End(span, err)
 
 	if err != nil {
 		validator.Err = fmt.Errorf(web.ERROR_USER_DOES_NOT_EXIST)
@@ -164,7 +169,9 @@ func (validator *Validator) LoadAuthUserFromUsername() *Validator {
 		return validator
 	}
 
+	span := tracing.Start(validator.c, "userdbcache.FindUserByUsername")
 	authUser, err := userdbcache.FindUserByUsername(validator.UserBodyReq.Username)
+	tracing.End(span, err)
 
 	//log.Debug().Msgf("beep2 %s", authUser.Username)
 
@@ -255,7 +262,9 @@ func (validator *Validator) LoadAuthUserFromToken() *Validator {
 		return validator
 	}
 
+	span := tracing.Start(validator.c, "userdbcache.FindUserByPublicId")
 	authUser, err := userdbcache.FindUserByPublicId(validator.Claims.UserId)
+	tracing.End(span, err)
 
 	if err != nil {
 		validator.Err = fmt.Errorf(web.ERROR_USER_DOES_NOT_EXIST)
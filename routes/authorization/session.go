@@ -2,6 +2,7 @@ package authorization
 
 import (
 	authenticationroutes "github.com/antonybholmes/go-edb-server-gin/routes/authentication"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/antonybholmes/go-web/auth"
 	"github.com/antonybholmes/go-web/middleware"
@@ -9,7 +10,6 @@ import (
 	"github.com/gin-contrib/sessions"
 
 	"github.com/antonybholmes/go-mailer"
-	"github.com/antonybholmes/go-mailer/queue"
 	"github.com/gin-gonic/gin"
 )
 
@@ -39,11 +39,13 @@ func SessionUpdateUserRoute(c *gin.Context) {
 
 	authenticationroutes.NewValidator(c).CheckUsernameIsWellFormed().Success(func(validator *authenticationroutes.Validator) {
 
+		span := tracing.Start(c, "userdbcache.SetUserInfo")
 		err = userdbcache.SetUserInfo(authUser,
 			validator.UserBodyReq.Username,
 			validator.UserBodyReq.FirstName,
 			validator.UserBodyReq.LastName,
 			false)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -62,7 +64,7 @@ func SessionUpdateUserRoute(c *gin.Context) {
 			//VisitUrl:    validator.Req.VisitUrl
 		}
 
-		queue.PublishEmail(&email)
+		authenticationroutes.PublishEmail(c, &email)
 	})
 }
 
@@ -72,7 +74,9 @@ func SessionUpdatePasswordRoute(c *gin.Context) {
 	authUser := user.(*auth.AuthUser)
 
 	// use current session user
+	span := tracing.Start(c, "userdbcache.FindUserByPublicId")
 	authUser, err := userdbcache.FindUserByPublicId(authUser.PublicId)
+	tracing.End(span, err)
 
 	if err != nil {
 		web.BadReqResp(c, "user not found")
@@ -102,7 +106,9 @@ func SessionUpdatePasswordRoute(c *gin.Context) {
 		return
 	}
 
+	span = tracing.Start(c, "userdbcache.SetPassword")
 	err = userdbcache.SetPassword(authUser, req.NewPassword)
+	tracing.End(span, err)
 
 	if err != nil {
 		web.BadReqResp(c, "could not update password")
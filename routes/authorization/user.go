@@ -1,7 +1,7 @@
 package authorization
 
 import (
-	"github.com/antonybholmes/go-mailer/queue"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web/userdbcache"
 	"github.com/rs/zerolog/log"
 
@@ -27,20 +27,24 @@ func UpdateUserRoute(c *gin.Context) {
 
 		publicId := validator.Claims.UserId
 
-		log.Debug().Msgf("UpdateUserRoute: publicId: %s ", publicId)
+		log.Ctx(c.Request.Context()).Debug().Msgf("UpdateUserRoute: publicId: %s ", publicId)
 
+		span := tracing.Start(c, "userdbcache.FindUserByPublicId")
 		authUser, err := userdbcache.FindUserByPublicId(publicId)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
+		span = tracing.Start(c, "userdbcache.SetUserInfo")
 		err = userdbcache.SetUserInfo(authUser,
 			validator.UserBodyReq.Username,
 			validator.UserBodyReq.FirstName,
 			validator.UserBodyReq.LastName,
 			false)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -50,7 +54,9 @@ func UpdateUserRoute(c *gin.Context) {
 		//return SendUserInfoUpdatedEmail(c, authUser)
 
 		// reload user details
+		span = tracing.Start(c, "userdbcache.FindUserByPublicId")
 		authUser, err = userdbcache.FindUserByPublicId(publicId)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
@@ -61,7 +67,7 @@ func UpdateUserRoute(c *gin.Context) {
 		email := mailer.QueueEmail{Name: authUser.FirstName,
 			To:        authUser.Email,
 			EmailType: mailer.QUEUE_EMAIL_TYPE_ACCOUNT_UPDATED}
-		queue.PublishEmail(&email)
+		authenticationroutes.PublishEmail(c, &email)
 
 		// send back updated user to having to do a separate call to get the new data
 		web.MakeDataResp(c, "account updated confirmation email sent", authUser)
@@ -0,0 +1,134 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const STATUS_OK = "ok"
+const STATUS_ERROR = "error"
+const STATUS_UNAVAILABLE = "unavailable"
+
+const PROBE_TIMEOUT = 5 * time.Second
+
+// A probe checks a dependency such as a module database or
+// Redis and returns an error if it is not usable
+type Probe func(ctx context.Context) error
+
+// Wrap a blocking check so that it gives up when the probe
+// times out
+func FuncProbe(check func() error) Probe {
+	return func(ctx context.Context) error {
+		done := make(chan error, 1)
+
+		go func() {
+			done <- check()
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+type ProbeResp struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ReadyResp struct {
+	Status string       `json:"status"`
+	Checks []*ProbeResp `json:"checks"`
+}
+
+type HealthRoutes struct {
+	probes       map[string]Probe
+	shuttingDown atomic.Bool
+}
+
+func NewHealthRoutes() *HealthRoutes {
+	return &HealthRoutes{probes: make(map[string]Probe)}
+}
+
+func (hr *HealthRoutes) AddProbe(name string, probe Probe) {
+	hr.probes[name] = probe
+}
+
+// Once shutting down, readiness fails so the orchestrator stops
+// sending traffic whilst in flight requests drain
+func (hr *HealthRoutes) SetShuttingDown() {
+	hr.shuttingDown.Store(true)
+}
+
+// Liveness: the process is up and able to handle requests
+func (hr *HealthRoutes) HealthzRoute(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": STATUS_OK})
+}
+
+// Readiness: run every probe and report the status of each. Returns
+// 503 if any probe fails so the instance is taken out of rotation.
+func (hr *HealthRoutes) ReadyzRoute(c *gin.Context) {
+	if hr.shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, ReadyResp{Status: STATUS_UNAVAILABLE,
+			Checks: []*ProbeResp{}})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), PROBE_TIMEOUT)
+	defer cancel()
+
+	checks := make([]*ProbeResp, 0, len(hr.probes))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for name, probe := range hr.probes {
+		wg.Add(1)
+
+		go func(name string, probe Probe) {
+			defer wg.Done()
+
+			resp := ProbeResp{Name: name, Status: STATUS_OK}
+
+			err := probe(ctx)
+
+			if err != nil {
+				resp.Status = STATUS_ERROR
+				resp.Error = err.Error()
+			}
+
+			mu.Lock()
+			checks = append(checks, &resp)
+			mu.Unlock()
+		}(name, probe)
+	}
+
+	wg.Wait()
+
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].Name < checks[j].Name
+	})
+
+	status := http.StatusOK
+	ret := ReadyResp{Status: STATUS_OK, Checks: checks}
+
+	for _, check := range checks {
+		if check.Status != STATUS_OK {
+			status = http.StatusServiceUnavailable
+			ret.Status = STATUS_UNAVAILABLE
+			break
+		}
+	}
+
+	c.JSON(status, ret)
+}
@@ -3,6 +3,9 @@ package beds
 import (
 	"github.com/antonybholmes/go-beds"
 	"github.com/antonybholmes/go-dna"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/gin-gonic/gin"
 
@@ -10,13 +13,27 @@ import (
 )
 
 type ReqBedsParams struct {
-	Location string   `json:"location"`
-	Beds     []string `json:"beds"`
+	Location string   `json:"location" form:"location"`
+	Beds     []string `json:"beds" form:"beds"`
+	// multiple regions can be given as locations or BED text
+	// instead of a single location
+	Locations []string `json:"locations,omitempty" form:"locations"`
+	Bed       string   `json:"bed,omitempty" form:"bed"`
 }
 
 type BedsParams struct {
-	Location *dna.Location `json:"location"`
-	Beds     []string      `json:"beds"`
+	Location *dna.Location       `json:"location"`
+	Regions  []*dnaroutes.Region `json:"regions"`
+	Beds     []string            `json:"beds"`
+}
+
+// The regions from each bed overlapping a region from a
+// BED upload or list of locations
+type RegionBedsResp struct {
+	Location *dna.Location       `json:"location"`
+	Name     string              `json:"name,omitempty"`
+	Strand   string              `json:"strand,omitempty"`
+	Beds     [][]*beds.BedRegion `json:"beds"`
 }
 
 func ParseBedParamsFromPost(c *gin.Context) (*BedsParams, error) {
@@ -29,17 +46,29 @@ func ParseBedParamsFromPost(c *gin.Context) (*BedsParams, error) {
 		return nil, err
 	}
 
-	location, err := dna.ParseLocation(params.Location)
+	if params.Location != "" {
+		location, err := dna.ParseLocation(params.Location)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &BedsParams{Location: location, Beds: params.Beds}, nil
+	}
+
+	regions, err := dnaroutes.ParseRegions(c, params.Locations, params.Bed)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &BedsParams{Location: location, Beds: params.Beds}, nil
+	return &BedsParams{Regions: regions, Beds: params.Beds}, nil
 }
 
 func GenomeRoute(c *gin.Context) {
+	span := tracing.Start(c, "bedsdbcache.Genomes")
 	platforms, err := bedsdbcache.Genomes()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -52,7 +81,9 @@ func GenomeRoute(c *gin.Context) {
 func PlatformRoute(c *gin.Context) {
 	genome := c.Param("assembly")
 
+	span := tracing.Start(c, "bedsdbcache.Platforms")
 	platforms, err := bedsdbcache.Platforms(genome)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -71,7 +102,9 @@ func SearchBedsRoute(c *gin.Context) {
 
 	query := c.Query("search")
 
+	span := tracing.Start(c, "bedsdbcache.Search")
 	tracks, err := bedsdbcache.Search(genome, query)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -94,23 +127,61 @@ func BedRegionsRoute(c *gin.Context) {
 		web.BadReqResp(c, "at least 1 bed id must be supplied")
 	}
 
-	ret := make([][]*beds.BedRegion, 0, len(params.Beds))
+	// a single location keeps the original response of
+	// regions per bed
+	if params.Location != nil {
+		ret, err := bedRegions(c, params.Location, params.Beds)
 
-	for _, bed := range params.Beds {
+		if err != nil {
+			c.Error(err)
+			return
+		}
 
-		//log.Debug().Msgf("bed id %s", bed)
+		tabular.MakeResp(c, ret)
+		return
+	}
 
-		reader, err := bedsdbcache.ReaderFromId(bed)
+	ret := make([]*RegionBedsResp, 0, len(params.Regions))
+
+	for _, region := range params.Regions {
+		regions, err := bedRegions(c, region.Location, params.Beds)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		features, _ := reader.OverlappingRegions(params.Location)
+		ret = append(ret, &RegionBedsResp{Location: region.Location,
+			Name:   region.Name,
+			Strand: region.Strand,
+			Beds:   regions})
+	}
+
+	tabular.MakeResp(c, ret)
+}
+
+// Get the regions from each bed overlapping a location
+func bedRegions(c *gin.Context, location *dna.Location, bedIds []string) ([][]*beds.BedRegion, error) {
+	ret := make([][]*beds.BedRegion, 0, len(bedIds))
+
+	for _, bed := range bedIds {
+
+		//log.Debug().Msgf("bed id %s", bed)
+
+		span := tracing.Start(c, "bedsdbcache.ReaderFromId")
+		reader, err := bedsdbcache.ReaderFromId(bed)
+		tracing.End(span, err)
+
+		if err != nil {
+			return nil, err
+		}
+
+		span = tracing.Start(c, "reader.OverlappingRegions")
+		features, _ := reader.OverlappingRegions(location)
+		tracing.End(span, nil)
 
 		ret = append(ret, features)
 	}
 
-	web.MakeDataResp(c, "", ret)
+	return ret, nil
 }
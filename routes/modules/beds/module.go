@@ -0,0 +1,63 @@
+package beds
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-beds/bedsdbcache"
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "beds"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_RDF
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	bedsdbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/genomes", Handler: GenomeRoute, Protected: true, Cache: true},
+		{Method: http.MethodGet, Path: "/platforms/:assembly", Handler: PlatformRoute, Protected: true, Cache: true},
+		{Method: http.MethodGet, Path: "/search/:assembly", Handler: SearchBedsRoute, Protected: true},
+		{Method: http.MethodPost, Path: "/regions", Handler: BedRegionsRoute, Protected: true,
+			Doc: &openapi.Doc{Summary: "BED regions overlapping a location, or each of several regions",
+				Request: ReqBedsParams{},
+				Query:   []*openapi.Param{dnaroutes.BedBaseParam}}},
+	}
+}
+
+func (m *Module) Health() error {
+	err := modules.CheckPath(m.path)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = bedsdbcache.Genomes()
+
+	return err
+}
+
+func (m *Module) Close() error {
+	return bedsdbcache.Close()
+}
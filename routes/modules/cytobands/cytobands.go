@@ -2,13 +2,16 @@ package cytobands
 
 import (
 	"github.com/antonybholmes/go-cytobands/cytobandsdbcache"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/gin-gonic/gin"
 )
 
 func CytobandsRoute(c *gin.Context) {
 
+	span := tracing.Start(c, "cytobandsdbcache.Cytobands")
 	cytobands, _ := cytobandsdbcache.Cytobands(c.Param("assembly"), c.Param("chr"))
+	tracing.End(span, nil)
 
 	// if err != nil {
 	// 	return web.ErrorReq(err)
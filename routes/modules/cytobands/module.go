@@ -0,0 +1,47 @@
+package cytobands
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-cytobands/cytobandsdbcache"
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "cytobands"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_USER
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	cytobandsdbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/:assembly/:chr", Handler: CytobandsRoute, Cache: true},
+	}
+}
+
+func (m *Module) Health() error {
+	return modules.CheckPath(m.path)
+}
+
+func (m *Module) Close() error {
+	return cytobandsdbcache.Close()
+}
@@ -0,0 +1,296 @@
+package dna
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/liftover"
+	"github.com/gin-gonic/gin"
+)
+
+// Name of the multipart field holding an uploaded BED file
+const BED_FORM_FILE = "file"
+
+const MIME_BED = "text/x-bed"
+
+// BED files are 0-based and half open so chr1 99 200 covers the
+// same bases as the 1-based closed location chr1:100-200. Files
+// that are already 1-based can say so with ?bedBase=1.
+const (
+	BED_BASE_0 uint = 0
+	BED_BASE_1 uint = 1
+)
+
+const (
+	STRAND_POS = "+"
+	STRAND_NEG = "-"
+)
+
+// A location from a request along with the name and strand it
+// was given in a BED file, if any. Upstream and downstream are
+// bases of flanking sequence relative to the strand.
+type Region struct {
+	Location   *dna.Location `json:"location"`
+	Name       string        `json:"name,omitempty"`
+	Strand     string        `json:"strand,omitempty"`
+	Upstream   uint          `json:"upstream,omitempty"`
+	Downstream uint          `json:"downstream,omitempty"`
+}
+
+// Check a strand is + or -. Empty and . mean no strand
+// and are returned as an empty string.
+func ParseStrand(strand string) (string, error) {
+	switch strand {
+	case STRAND_POS, STRAND_NEG:
+		return strand, nil
+	case "", ".":
+		return "", nil
+	default:
+		return "", fmt.Errorf("%s is an invalid strand", strand)
+	}
+}
+
+// Returns the location of a region including its flanks. On the
+// minus strand upstream is after the end of the location.
+func (region *Region) FlankedLocation() *dna.Location {
+	upstream := region.Upstream
+	downstream := region.Downstream
+
+	if region.Strand == STRAND_NEG {
+		upstream, downstream = downstream, upstream
+	}
+
+	start := uint(1)
+
+	if region.Location.Start > upstream {
+		start = region.Location.Start - upstream
+	}
+
+	return dna.NewLocation(region.Location.Chr, start, region.Location.End+downstream)
+}
+
+// Convert BED coordinates to a 1-based location
+func BedLocation(chr string, start uint, end uint, base uint) *dna.Location {
+	if base == BED_BASE_0 {
+		start++
+	}
+
+	return dna.NewLocation(chr, start, end)
+}
+
+// Parse BED, BED6 or narrowPeak lines into regions. Only the first
+// six columns are used; track, browser and comment lines are
+// skipped.
+func ParseBed(r io.Reader, base uint) ([]*Region, error) {
+	ret := make([]*Region, 0, 100)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	ln := 0
+
+	for scanner.Scan() {
+		ln++
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" ||
+			strings.HasPrefix(line, "#") ||
+			strings.HasPrefix(line, "track") ||
+			strings.HasPrefix(line, "browser") {
+			continue
+		}
+
+		var tokens []string
+
+		if strings.Contains(line, "\t") {
+			tokens = strings.Split(line, "\t")
+		} else {
+			tokens = strings.Fields(line)
+		}
+
+		if len(tokens) < 3 {
+			return nil, fmt.Errorf("bed line %d: expected at least 3 columns", ln)
+		}
+
+		start, err := strconv.ParseUint(tokens[1], 10, 0)
+
+		if err != nil {
+			return nil, fmt.Errorf("bed line %d: %s is an invalid start", ln, tokens[1])
+		}
+
+		end, err := strconv.ParseUint(tokens[2], 10, 0)
+
+		if err != nil {
+			return nil, fmt.Errorf("bed line %d: %s is an invalid end", ln, tokens[2])
+		}
+
+		// zero length intervals mark insertion points so are
+		// taken as the base after the point
+		if base == BED_BASE_0 && start == end {
+			end++
+		}
+
+		location := BedLocation(tokens[0], uint(start), uint(end), base)
+
+		if location.Start > location.End {
+			return nil, fmt.Errorf("bed line %d: start is after end", ln)
+		}
+
+		region := Region{Location: location}
+
+		if len(tokens) > 3 && tokens[3] != "." {
+			region.Name = tokens[3]
+		}
+
+		if len(tokens) > 5 {
+			region.Strand, err = ParseStrand(tokens[5])
+
+			if err != nil {
+				return nil, fmt.Errorf("bed line %d: %s", ln, err)
+			}
+		}
+
+		ret = append(ret, &region)
+	}
+
+	err := scanner.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Read ?bedBase= which says whether BED input is 0 (the default)
+// or 1-based
+func ParseBedBase(c *gin.Context) (uint, error) {
+	switch c.Query("bedBase") {
+	case "", "0":
+		return BED_BASE_0, nil
+	case "1":
+		return BED_BASE_1, nil
+	default:
+		return 0, fmt.Errorf("%s is an invalid bed base, use 0 or 1", c.Query("bedBase"))
+	}
+}
+
+// Returns true if the request body is raw BED text rather than json
+func IsBedBody(c *gin.Context) bool {
+	switch c.ContentType() {
+	case MIME_BED, "application/x-bed":
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse regions from an uploaded BED file in a multipart form, BED
+// text sent as the body, or else the bed text or locations from a
+// json body. Routes with other params in their body can pass the
+// bed and locations they bound themselves. Regions are lifted to
+// the route's assembly if ?liftover= is set.
+func ParseRegions(c *gin.Context, locations []string, bed string) ([]*Region, error) {
+	regions, err := parseRegions(c, locations, bed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = LiftRegions(c, regions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return regions, nil
+}
+
+func parseRegions(c *gin.Context, locations []string, bed string) ([]*Region, error) {
+	base, err := ParseBedBase(c)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		file, err := c.FormFile(BED_FORM_FILE)
+
+		if err != nil {
+			return nil, fmt.Errorf("multipart requests must upload a BED file in the %s field", BED_FORM_FILE)
+		}
+
+		f, err := file.Open()
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer f.Close()
+
+		return ParseBed(f, base)
+	}
+
+	if IsBedBody(c) {
+		return ParseBed(c.Request.Body, base)
+	}
+
+	if bed != "" {
+		return ParseBed(strings.NewReader(bed), base)
+	}
+
+	locs, err := dna.ParseLocations(locations)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*Region, len(locs))
+
+	for li, location := range locs {
+		ret[li] = &Region{Location: location}
+	}
+
+	return ret, nil
+}
+
+// Lift regions in place if ?liftover= says they are in another
+// assembly. Strands are flipped for regions that map to the - strand.
+func LiftRegions(c *gin.Context, regions []*Region) error {
+	mapped, err := liftover.AutoLift(c, RegionLocations(regions))
+
+	if err != nil {
+		return err
+	}
+
+	for ri, m := range mapped {
+		regions[ri].Location = m.Location
+
+		if m.Strand == STRAND_NEG {
+			switch regions[ri].Strand {
+			case STRAND_POS:
+				regions[ri].Strand = STRAND_NEG
+			case STRAND_NEG:
+				regions[ri].Strand = STRAND_POS
+			}
+		}
+	}
+
+	return nil
+}
+
+// Extract the locations of regions
+func RegionLocations(regions []*Region) []*dna.Location {
+	ret := make([]*dna.Location, len(regions))
+
+	for ri, region := range regions {
+		ret[ri] = region.Location
+	}
+
+	return ret
+}
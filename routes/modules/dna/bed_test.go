@@ -0,0 +1,143 @@
+package dna
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antonybholmes/go-dna"
+)
+
+type testRegion struct {
+	location string
+	name     string
+	strand   string
+}
+
+func TestParseBed(t *testing.T) {
+	tests := []struct {
+		name    string
+		bed     string
+		base    uint
+		regions []testRegion
+	}{
+		{name: "0-based",
+			bed:     "chr1\t99\t200\n",
+			base:    BED_BASE_0,
+			regions: []testRegion{{"chr1:100-200", "", ""}}},
+		{name: "1-based",
+			bed:     "chr1\t100\t200\n",
+			base:    BED_BASE_1,
+			regions: []testRegion{{"chr1:100-200", "", ""}}},
+		{name: "zero length is the base after the point",
+			bed:     "chr1\t99\t99\n",
+			base:    BED_BASE_0,
+			regions: []testRegion{{"chr1:100-100", "", ""}}},
+		{name: "zero length at the chromosome start",
+			bed:     "chr1\t0\t0\n",
+			base:    BED_BASE_0,
+			regions: []testRegion{{"chr1:1-1", "", ""}}},
+		{name: "single base",
+			bed:     "chr1\t0\t1\n",
+			base:    BED_BASE_0,
+			regions: []testRegion{{"chr1:1-1", "", ""}}},
+		{name: "BED6 names and strands",
+			bed:     "chr1\t99\t200\tpeak1\t0\t-\nchr2\t0\t10\t.\t0\t.\n",
+			base:    BED_BASE_0,
+			regions: []testRegion{{"chr1:100-200", "peak1", "-"}, {"chr2:1-10", "", ""}}},
+		{name: "narrowPeak extra columns are ignored",
+			bed:     "chr1\t99\t200\tpeak1\t500\t+\t12.5\t-1\t3.2\t50\n",
+			base:    BED_BASE_0,
+			regions: []testRegion{{"chr1:100-200", "peak1", "+"}}},
+		{name: "headers, comments and blank lines are skipped",
+			bed:     "track name=peaks\nbrowser position chr1:1-1000\n# comment\n\nchr1\t99\t200\n",
+			base:    BED_BASE_0,
+			regions: []testRegion{{"chr1:100-200", "", ""}}},
+		{name: "space separated",
+			bed:     "chr1 99 200 peak1\n",
+			base:    BED_BASE_0,
+			regions: []testRegion{{"chr1:100-200", "peak1", ""}}},
+		{name: "empty",
+			bed:     "",
+			base:    BED_BASE_0,
+			regions: []testRegion{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			regions, err := ParseBed(strings.NewReader(test.bed), test.base)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(regions) != len(test.regions) {
+				t.Fatalf("got %d regions, want %d", len(regions), len(test.regions))
+			}
+
+			for i, region := range regions {
+				got := testRegion{region.Location.String(), region.Name, region.Strand}
+
+				if got != test.regions[i] {
+					t.Errorf("region %d = %v, want %v", i, got, test.regions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseBedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		bed  string
+	}{
+		{"too few columns", "chr1\t99\n"},
+		{"invalid start", "chr1\tx\t200\n"},
+		{"negative start", "chr1\t-1\t200\n"},
+		{"invalid end", "chr1\t99\tx\n"},
+		{"invalid strand", "chr1\t99\t200\tpeak1\t0\tx\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseBed(strings.NewReader(test.bed), BED_BASE_0)
+
+			if err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestFlankedLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		region   Region
+		location string
+	}{
+		{"plus strand",
+			Region{Location: dna.NewLocation("chr1", 1000, 2000), Strand: STRAND_POS, Upstream: 100, Downstream: 50},
+			"chr1:900-2050"},
+		{"minus strand",
+			Region{Location: dna.NewLocation("chr1", 1000, 2000), Strand: STRAND_NEG, Upstream: 100, Downstream: 50},
+			"chr1:950-2100"},
+		{"no strand is treated as plus",
+			Region{Location: dna.NewLocation("chr1", 1000, 2000), Upstream: 100},
+			"chr1:900-2000"},
+		{"clipped at the chromosome start",
+			Region{Location: dna.NewLocation("chr1", 50, 100), Strand: STRAND_POS, Upstream: 100},
+			"chr1:1-100"},
+		{"minus strand clipped at the chromosome start",
+			Region{Location: dna.NewLocation("chr1", 50, 100), Strand: STRAND_NEG, Downstream: 100},
+			"chr1:1-100"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location := test.region.FlankedLocation().String()
+
+			if location != test.location {
+				t.Errorf("location = %s, want %s", location, test.location)
+			}
+		})
+	}
+}
@@ -1,14 +1,18 @@
 package dna
 
 import (
+	"bufio"
 	"fmt"
+	"mime"
 	"strconv"
 	"strings"
 
 	"github.com/antonybholmes/go-dna"
 	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-web"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
 const DEFAULT_ASSEMBLY string = "grch38"
@@ -16,13 +20,48 @@ const DEFAULT_CHR string = "chr1" //"chr3"
 const DEFAULT_START uint = 100000 //187728170
 const DEFAULT_END uint = 100100   //187752257
 
+// Output formats for DNA, either the standard json
+// response or multi-FASTA
+const (
+	OUTPUT_JSON  = "json"
+	OUTPUT_FASTA = "fasta"
+)
+
+const MIME_FASTA = "text/x-fasta"
+
+const DEFAULT_FASTA_LINE_WIDTH = 60
+
 type ReqLocs struct {
 	Locations []string `json:"locations"`
+	// optional names for each location, used to label
+	// FASTA records
+	Names []string `json:"names,omitempty"`
+	// regions as BED text, an alternative to locations
+	Bed string `json:"bed,omitempty"`
+	// locations with their own strand and flanks
+	Regions []*ReqRegion `json:"regions,omitempty"`
 }
 
+type ReqRegion struct {
+	Location   string `json:"location"`
+	Name       string `json:"name,omitempty"`
+	Strand     string `json:"strand,omitempty"`
+	Upstream   uint   `json:"upstream,omitempty"`
+	Downstream uint   `json:"downstream,omitempty"`
+}
+
+// A sequence and how it was obtained. The location includes any
+// flanks and rev and comp are the transformations that were
+// applied once the strand is taken into account.
 type DNA struct {
-	Location *dna.Location `json:"location"`
-	Seq      string        `json:"seq"`
+	Location     *dna.Location `json:"location"`
+	Name         string        `json:"name,omitempty"`
+	Strand       string        `json:"strand,omitempty"`
+	Upstream     uint          `json:"upstream,omitempty"`
+	Downstream   uint          `json:"downstream,omitempty"`
+	IsRev        bool          `json:"isRev"`
+	IsComplement bool          `json:"isComp"`
+	Seq          string        `json:"seq"`
 }
 
 type DNAResp struct {
@@ -38,6 +77,12 @@ type DNAQuery struct {
 	Comp       bool
 	Format     string
 	RepeatMask string
+	Output     string
+	// bases per line of FASTA, 0 for no wrapping
+	LineWidth int
+	// default flanks for regions that do not set their own
+	Upstream   uint
+	Downstream uint
 }
 
 func ParseLocation(c *gin.Context) (*dna.Location, error) {
@@ -85,16 +130,67 @@ func ParseLocation(c *gin.Context) (*dna.Location, error) {
 }
 
 func ParseLocationsFromPost(c *gin.Context) ([]*dna.Location, error) {
+	regions, err := ParseRegionsFromPost(c)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return RegionLocations(regions), nil
+}
+
+// Parse regions from a json list of locations and optional names,
+// a list of regions, or from BED. Names are optional, but if given
+// there must be one per location.
+func ParseRegionsFromPost(c *gin.Context) ([]*Region, error) {
 
 	var locs ReqLocs
 
-	err := c.ShouldBindJSON(&locs)
+	if c.ContentType() != gin.MIMEMultipartPOSTForm && !IsBedBody(c) {
+		err := c.ShouldBindJSON(&locs)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ret, err := parseRegions(c, locs.Locations, locs.Bed)
 
 	if err != nil {
 		return nil, err
 	}
 
-	ret, err := dna.ParseLocations(locs.Locations)
+	if len(locs.Names) > 0 {
+		if len(locs.Names) != len(ret) {
+			return nil, fmt.Errorf("%d names given for %d locations", len(locs.Names), len(ret))
+		}
+
+		for ri, region := range ret {
+			region.Name = locs.Names[ri]
+		}
+	}
+
+	for _, r := range locs.Regions {
+		location, err := dna.ParseLocation(r.Location)
+
+		if err != nil {
+			return nil, err
+		}
+
+		strand, err := ParseStrand(r.Strand)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, &Region{Location: location,
+			Name:       r.Name,
+			Strand:     strand,
+			Upstream:   r.Upstream,
+			Downstream: r.Downstream})
+	}
+
+	err = LiftRegions(c, ret)
 
 	if err != nil {
 		return nil, err
@@ -103,6 +199,24 @@ func ParseLocationsFromPost(c *gin.Context) ([]*dna.Location, error) {
 	return ret, nil
 }
 
+// Determine whether to return json or FASTA from ?format= or,
+// failing that, the Accept header
+func ParseOutput(c *gin.Context) string {
+	if strings.ToLower(c.Query("format")) == OUTPUT_FASTA {
+		return OUTPUT_FASTA
+	}
+
+	for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+
+		if err == nil && mediaType == MIME_FASTA {
+			return OUTPUT_FASTA
+		}
+	}
+
+	return OUTPUT_JSON
+}
+
 func ParseDNAQuery(c *gin.Context) (*DNAQuery, error) {
 	var err error
 
@@ -128,8 +242,16 @@ func ParseDNAQuery(c *gin.Context) (*DNAQuery, error) {
 		}
 	}
 
+	output := ParseOutput(c)
+
+	// format sets the case of the bases, but since ?format=fasta
+	// selects the output, the case can also be given as ?case=
 	format := ""
-	v = c.Query("format")
+	v = c.Query("case")
+
+	if v == "" && output != OUTPUT_FASTA {
+		v = c.Query("format")
+	}
 
 	if v != "" {
 		if strings.Contains(strings.ToLower(v), "lower") {
@@ -150,20 +272,140 @@ func ParseDNAQuery(c *gin.Context) (*DNAQuery, error) {
 		}
 	}
 
+	upstream, err := parseFlank(c, "upstream")
+
+	if err != nil {
+		return nil, err
+	}
+
+	downstream, err := parseFlank(c, "downstream")
+
+	if err != nil {
+		return nil, err
+	}
+
+	lineWidth := DEFAULT_FASTA_LINE_WIDTH
+	v = c.Query("width")
+
+	if v != "" {
+		lineWidth, err = strconv.Atoi(v)
+
+		if err != nil || lineWidth < 0 {
+			return nil, fmt.Errorf("%s is an invalid line width", v)
+		}
+	}
+
 	return &DNAQuery{
 			Rev:        rev,
 			Comp:       comp,
 			Format:     format,
-			RepeatMask: repeatMask},
+			RepeatMask: repeatMask,
+			Output:     output,
+			LineWidth:  lineWidth,
+			Upstream:   upstream,
+			Downstream: downstream},
 		nil
 }
 
+func parseFlank(c *gin.Context, name string) (uint, error) {
+	v := c.Query(name)
+
+	if v == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(v, 10, 0)
+
+	if err != nil {
+		return 0, fmt.Errorf("%s is an invalid %s flank", v, name)
+	}
+
+	return uint(n), nil
+}
+
+// Work out what to fetch for a region. Sequences on the minus
+// strand are reverse complemented, on top of any rev or comp
+// asked for in the query, so that they read 5' to 3'.
+func RegionDNA(region *Region, query *DNAQuery) *DNA {
+	flanked := *region
+
+	if flanked.Upstream == 0 && flanked.Downstream == 0 {
+		flanked.Upstream = query.Upstream
+		flanked.Downstream = query.Downstream
+	}
+
+	minus := region.Strand == STRAND_NEG
+
+	return &DNA{Location: flanked.FlankedLocation(),
+		Name:         region.Name,
+		Strand:       region.Strand,
+		Upstream:     flanked.Upstream,
+		Downstream:   flanked.Downstream,
+		IsRev:        query.Rev != minus,
+		IsComplement: query.Comp != minus}
+}
+
+// Make a FASTA header for a sequence. The name, if any, is the
+// record id and the flags note how the sequence was obtained.
+func FastaHeader(seq *DNA, query *DNAQuery) string {
+	var header strings.Builder
+
+	header.WriteString(">")
+
+	if seq.Name != "" {
+		header.WriteString(seq.Name)
+		header.WriteString(" ")
+	}
+
+	header.WriteString(seq.Location.String())
+
+	if seq.Strand != "" {
+		header.WriteString(" strand=")
+		header.WriteString(seq.Strand)
+	}
+
+	if seq.Upstream > 0 {
+		header.WriteString(fmt.Sprintf(" upstream=%d", seq.Upstream))
+	}
+
+	if seq.Downstream > 0 {
+		header.WriteString(fmt.Sprintf(" downstream=%d", seq.Downstream))
+	}
+
+	if seq.IsRev {
+		header.WriteString(" rev")
+	}
+
+	if seq.IsComplement {
+		header.WriteString(" comp")
+	}
+
+	if query.RepeatMask != "" {
+		header.WriteString(" mask=")
+		header.WriteString(query.RepeatMask)
+	}
+
+	return header.String()
+}
+
+// Write a sequence as FASTA lines of at most width bases
+func writeFastaSeq(w *bufio.Writer, seq string, width int) {
+	if width < 1 {
+		width = len(seq)
+	}
+
+	for i := 0; i < len(seq); i += width {
+		w.WriteString(seq[i:min(i+width, len(seq))])
+		w.WriteByte('\n')
+	}
+}
+
 func GenomesRoute(c *gin.Context) {
 	web.MakeDataResp(c, "", dnadbcache.GetInstance().List())
 }
 
 func DNARoute(c *gin.Context) {
-	locations, err := ParseLocationsFromPost(c)
+	regions, err := ParseRegionsFromPost(c)
 
 	if err != nil {
 		c.Error(err)
@@ -179,28 +421,41 @@ func DNARoute(c *gin.Context) {
 		return
 	}
 
+	span := tracing.Start(c, "dnadbcache.Db")
 	dnadb, err := dnadbcache.Db(assembly)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	seqs := make([]*DNA, 0, len(locations))
+	if query.Output == OUTPUT_FASTA {
+		fastaResp(c, dnadb, regions, query)
+		return
+	}
 
-	for _, location := range locations {
-		seq, err := dnadb.DNA(location,
+	seqs := make([]*DNA, 0, len(regions))
+
+	for _, region := range regions {
+		item := RegionDNA(region, query)
+
+		span := tracing.Start(c, "dna.DNADB.DNA")
+		seq, err := dnadb.DNA(item.Location,
 			query.Format,
 			query.RepeatMask,
-			query.Rev,
-			query.Comp)
+			item.IsRev,
+			item.IsComplement)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		seqs = append(seqs, &DNA{Location: location, Seq: seq})
+		item.Seq = seq
+
+		seqs = append(seqs, item)
 	}
 
 	web.MakeDataResp(c,
@@ -212,3 +467,55 @@ func DNARoute(c *gin.Context) {
 			IsComplement: query.Comp,
 			Seqs:         seqs})
 }
+
+// Stream multi-FASTA, fetching each sequence as it is written so
+// that large requests are not held in memory
+func fastaResp(c *gin.Context,
+	dnadb *dna.DNADB,
+	regions []*Region,
+	query *DNAQuery) {
+
+	w := bufio.NewWriter(c.Writer)
+
+	for ri, region := range regions {
+		item := RegionDNA(region, query)
+
+		span := tracing.Start(c, "dna.DNADB.DNA")
+		seq, err := dnadb.DNA(item.Location,
+			query.Format,
+			query.RepeatMask,
+			item.IsRev,
+			item.IsComplement)
+		tracing.End(span, err)
+
+		if err != nil {
+			if ri > 0 {
+				// too late to send an error response so send
+				// the records so far and truncate the output
+				w.Flush()
+				log.Ctx(c.Request.Context()).Error().Msgf("fasta %s: %s", item.Location, err)
+				c.Abort()
+			} else {
+				c.Error(err)
+			}
+
+			return
+		}
+
+		// only label the response as FASTA once there is a
+		// record so an error above is sent as an error
+		if ri == 0 {
+			c.Header("Content-Type", MIME_FASTA+"; charset=utf-8")
+		}
+
+		w.WriteString(FastaHeader(item, query))
+		w.WriteByte('\n')
+		writeFastaSeq(w, seq, query.LineWidth)
+	}
+
+	if len(regions) == 0 {
+		c.Header("Content-Type", MIME_FASTA+"; charset=utf-8")
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,128 @@
+package dna
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/enzymes"
+	"github.com/antonybholmes/go-edb-server-gin/oligos"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+)
+
+// Documents ?bedBase= for routes that accept BED input
+var BedBaseParam = openapi.QueryParam("bedBase", "0 (default) if BED input is 0-based, or 1 if 1-based")
+
+// Documents ?liftover= for routes with an :assembly
+var LiftoverParam = openapi.QueryParam("liftover", "assembly the locations are in, if not this one, so they are lifted over first")
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "dna"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_USER
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	dnadbcache.InitCache(m.path)
+
+	source := &oligoSource{path: m.path}
+
+	oligos.InitCache(cfg.Oligos.Dir, source)
+	oligos.Prebuild(cfg.Oligos.Prebuild)
+
+	enzymes.InitCache(cfg.Enzymes.File)
+
+	fragmentsDir = cfg.Enzymes.FragmentsDir
+	seqSource = source
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodPost, Path: "/:assembly", Handler: DNARoute,
+			Doc: &openapi.Doc{Summary: "Get the DNA sequence of locations or BED regions",
+				Request:  ReqLocs{},
+				Response: DNAResp{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("format", "lower or upper case, or fasta to return multi-FASTA"),
+					openapi.QueryParam("case", "lower or upper case when format is fasta"),
+					openapi.QueryParam("width", "bases per FASTA line, 0 for no wrapping"),
+					openapi.QueryParam("mask", "repeat mask, n or lower"),
+					openapi.QueryParam("rev", "reverse the sequence"),
+					openapi.QueryParam("comp", "complement the sequence"),
+					openapi.QueryParam("upstream", "bases of upstream flank for regions without their own"),
+					openapi.QueryParam("downstream", "bases of downstream flank for regions without their own"),
+					BedBaseParam,
+					LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/:assembly/stats", Handler: StatsRoute,
+			Doc: &openapi.Doc{Summary: "Sequence composition of locations or BED regions",
+				Request:  ReqLocs{},
+				Response: []*RegionStats{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("window", "size of sliding windows for a profile, 0 for none"),
+					openapi.QueryParam("step", "bases between windows, defaults to the window size"),
+					openapi.QueryParam("upstream", "bases of upstream flank for regions without their own"),
+					openapi.QueryParam("downstream", "bases of downstream flank for regions without their own"),
+					BedBaseParam,
+					LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/:assembly/oligos", Handler: OligosRoute,
+			Doc: &openapi.Doc{Summary: "Find where oligos occur in an assembly",
+				Request:  ReqOligos{},
+				Response: oligos.SearchResult{},
+				Query:    []*openapi.Param{LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/:assembly/primers", Handler: PrimersRoute,
+			Doc: &openapi.Doc{Summary: "Design PCR primer pairs around target locations or BED regions",
+				Request:  ReqPrimers{},
+				Response: []*PrimersResp{},
+				Query:    []*openapi.Param{BedBaseParam, LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/:assembly/translate", Handler: TranslateRoute,
+			Doc: &openapi.Doc{Summary: "Translate locations or BED regions and find ORFs",
+				Request:  ReqLocs{},
+				Response: []*TranslateResp{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("frames", "comma separated frames, 1 to 3 and -1 to -3, all six by default"),
+					openapi.QueryParam("minOrf", "shortest ORF to report in amino acids, 100 by default"),
+					BedBaseParam,
+					LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/:assembly/transcripts", Handler: TranscriptsRoute,
+			Doc: &openapi.Doc{Summary: "Spliced mRNA, CDS and protein of transcripts and the effect of variants on them",
+				Request:  ReqTranscripts{},
+				Response: []*TranscriptResp{}}},
+		{Method: http.MethodPost, Path: "/:assembly/restriction", Handler: RestrictionRoute,
+			Doc: &openapi.Doc{Summary: "Restriction sites, cuts and fragments of locations or BED regions",
+				Request:  ReqLocs{},
+				Response: []*RestrictionResp{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("enzymes", "comma separated enzyme names"),
+					BedBaseParam,
+					LiftoverParam}}},
+		{Method: http.MethodGet, Path: "/:assembly/fragments/:enzyme", Handler: FragmentsRoute,
+			Doc: &openapi.Doc{Summary: "Genome wide restriction fragment map of an enzyme as BED"}},
+		{Method: http.MethodGet, Path: "/enzymes", Handler: EnzymesRoute, Cache: true,
+			Doc: &openapi.Doc{Summary: "List restriction enzymes", Response: []*enzymes.Enzyme{}}},
+		{Method: http.MethodGet, Path: "/genomes", Handler: GenomesRoute, Cache: true,
+			Doc: &openapi.Doc{Summary: "List available genomes"}},
+	}
+}
+
+func (m *Module) Health() error {
+	return modules.CheckPath(m.path)
+}
+
+func (m *Module) Close() error {
+	return dnadbcache.Close()
+}
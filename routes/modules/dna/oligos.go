@@ -0,0 +1,132 @@
+package dna
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/chromsizes"
+	"github.com/antonybholmes/go-edb-server-gin/liftover"
+	"github.com/antonybholmes/go-edb-server-gin/oligos"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// Each assembly dir needs a UCSC style chrom.sizes so that its
+// sequence can be indexed for oligo search
+const CHROM_SIZES_FILE = "chrom.sizes"
+
+// Seconds clients are asked to wait while an index is built
+const OLIGO_INDEX_RETRY_SECS = "60"
+
+type ReqOligos struct {
+	Oligos     []string `json:"oligos"`
+	Mismatches int      `json:"mismatches"`
+	// both (default), + or -
+	Strand string `json:"strand,omitempty"`
+	// only search within this location
+	Location string `json:"location,omitempty"`
+	MaxHits  int    `json:"maxHits,omitempty"`
+}
+
+// Sequence and chromosome sizes of the dna module's assemblies for
+// building indexes and fragment maps
+var seqSource oligos.Source
+
+// Supplies sequence from the dna module to build oligo indexes
+type oligoSource struct {
+	path string
+}
+
+func (source *oligoSource) ChromSizes(assembly string) ([]*chromsizes.Size, error) {
+	return chromsizes.Read(filepath.Join(source.path, assembly, CHROM_SIZES_FILE))
+}
+
+func (source *oligoSource) Fetcher(assembly string) (oligos.FetchFunc, error) {
+	dnadb, err := dnadbcache.Db(assembly)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(location *dna.Location) (string, error) {
+		return dnadb.DNA(location, "upper", "", false, false)
+	}, nil
+}
+
+// Chromosome sizes of an assembly from its chrom.sizes so that
+// other modules can work genome wide
+func ChromSizes(assembly string) ([]*chromsizes.Size, error) {
+	if seqSource == nil {
+		return nil, fmt.Errorf("chromosome sizes are not available")
+	}
+
+	if !oligos.ValidAssembly.MatchString(assembly) {
+		return nil, fmt.Errorf("%s is not a valid assembly", assembly)
+	}
+
+	return seqSource.ChromSizes(assembly)
+}
+
+func OligosRoute(c *gin.Context) {
+	var req ReqOligos
+
+	err := c.ShouldBindJSON(&req)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	options := oligos.SearchOptions{Mismatches: req.Mismatches,
+		Strand:  req.Strand,
+		MaxHits: req.MaxHits}
+
+	if req.Location != "" {
+		location, err := dna.ParseLocation(req.Location)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		lifted, err := liftover.AutoLiftLocations(c, []*dna.Location{location})
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		options.Location = lifted[0]
+	}
+
+	span := tracing.Start(c, "oligos.GetIndex")
+	index, err := oligos.GetIndex(c.Param("assembly"))
+	tracing.End(span, err)
+
+	if err == oligos.ErrIndexBuilding {
+		c.Header("Retry-After", OLIGO_INDEX_RETRY_SECS)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable,
+			gin.H{"status": http.StatusServiceUnavailable, "message": err.Error()})
+		return
+	}
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	span = tracing.Start(c, "oligos.Index.Search")
+	ret, err := index.Search(req.Oligos, &options)
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	tabular.MakeResp(c, ret)
+}
@@ -0,0 +1,104 @@
+package dna
+
+import (
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	"github.com/antonybholmes/go-edb-server-gin/primers"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+)
+
+type ReqPrimers struct {
+	// targets the product must cover
+	Locations   []string             `json:"locations"`
+	Bed         string               `json:"bed,omitempty"`
+	Constraints *primers.Constraints `json:"constraints,omitempty"`
+}
+
+type PrimersResp struct {
+	Location *dna.Location `json:"location"`
+	Name     string        `json:"name,omitempty"`
+	// best first, empty if no pair meets the constraints
+	Pairs []*primers.PrimerPair `json:"pairs"`
+}
+
+func PrimersRoute(c *gin.Context) {
+	var req ReqPrimers
+
+	err := c.ShouldBindJSON(&req)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	regions, err := ParseRegions(c, req.Locations, req.Bed)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	constraints := req.Constraints
+
+	if constraints == nil {
+		constraints = &primers.Constraints{}
+	}
+
+	err = constraints.Complete()
+
+	if err != nil {
+		web.BadReqResp(c, err.Error())
+		return
+	}
+
+	span := tracing.Start(c, "dnadbcache.Db")
+	dnadb, err := dnadbcache.Db(c.Param("assembly"))
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret := make([]*PrimersResp, 0, len(regions))
+
+	for ri, region := range regions {
+		target := region.Location
+
+		// primers can be anywhere a product of the max size
+		// covering the target could start or end
+		flank := uint(constraints.MaxProductSize)
+		start := max(target.Start, flank+1) - flank
+
+		location := dna.NewLocation(target.Chr, start, target.End+flank)
+
+		// masked bases are lowercase so repeats can be avoided
+		span := tracing.Start(c, "dna.DNADB.DNA")
+		seq, err := dnadb.DNA(location, "", "lower", false, false)
+		tracing.End(span, err)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		span = tracing.Start(c, "primers.Design")
+		pairs := primers.Design(seq,
+			target.Chr,
+			start,
+			int(target.Start-start),
+			int(target.End-start)+1,
+			constraints)
+		tracing.End(span, nil)
+
+		ret = append(ret, &PrimersResp{Location: target, Name: region.Name, Pairs: pairs})
+
+		jobs.SetProgress(c, ri+1, len(regions))
+	}
+
+	tabular.MakeResp(c, ret)
+}
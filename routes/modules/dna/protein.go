@@ -0,0 +1,361 @@
+package dna
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	"github.com/antonybholmes/go-edb-server-gin/protein"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-edb-server-gin/variants"
+	"github.com/antonybholmes/go-genome"
+	"github.com/antonybholmes/go-genome/genomedbcache"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+)
+
+var ALL_FRAMES = []int{1, 2, 3, -1, -2, -3}
+
+// Shortest ORF reported by default, in amino acids
+const DEFAULT_MIN_ORF_LEN = 100
+
+// Genes can match more than one feature when searched for so
+// look at a few to find the transcript
+const MAX_TRANSCRIPT_SEARCH = 10
+
+const (
+	TRANSCRIPT_FOUND     = "found"
+	TRANSCRIPT_NOT_FOUND = "not_found"
+)
+
+type FrameResp struct {
+	Frame   int    `json:"frame"`
+	Protein string `json:"protein"`
+}
+
+type ORFResp struct {
+	Location *dna.Location `json:"location"`
+	Strand   string        `json:"strand"`
+	*protein.ORF
+}
+
+type TranslateResp struct {
+	Location *dna.Location `json:"location"`
+	Name     string        `json:"name,omitempty"`
+	Strand   string        `json:"strand,omitempty"`
+	Frames   []*FrameResp  `json:"frames"`
+	ORFs     []*ORFResp    `json:"orfs"`
+}
+
+// Transcripts can be given by id or, for their canonical
+// transcript, by gene. Variants are written as chr:pos:ref>alt or
+// can be sent as VCF text.
+type ReqTranscripts struct {
+	Ids      []string `json:"ids"`
+	Variants []string `json:"variants,omitempty"`
+	Vcf      string   `json:"vcf,omitempty"`
+}
+
+// A transcript asked for by id or gene. Those not found only have
+// the query, status and why.
+type TranscriptResp struct {
+	Query      string        `json:"query"`
+	Status     string        `json:"status"`
+	Reason     string        `json:"reason,omitempty"`
+	GeneId     string        `json:"geneId,omitempty"`
+	GeneSymbol string        `json:"geneSymbol,omitempty"`
+	Location   *dna.Location `json:"location,omitempty"`
+	*protein.Transcript
+	Effects []*protein.Effect `json:"effects,omitempty"`
+}
+
+// Read ?frames= as a comma separated list such as 1,2,3
+func parseFrames(c *gin.Context) ([]int, error) {
+	v := c.Query("frames")
+
+	if v == "" {
+		return ALL_FRAMES, nil
+	}
+
+	ret := make([]int, 0, len(ALL_FRAMES))
+
+	for _, f := range strings.Split(v, ",") {
+		frame, err := strconv.Atoi(strings.TrimSpace(f))
+
+		if err != nil || frame == 0 || frame < -3 || frame > 3 {
+			return nil, fmt.Errorf("%s is an invalid frame, use 1 to 3 or -1 to -3", f)
+		}
+
+		ret = append(ret, frame)
+	}
+
+	return ret, nil
+}
+
+func parseMinORFLen(c *gin.Context) (int, error) {
+	v := c.Query("minOrf")
+
+	if v == "" {
+		return DEFAULT_MIN_ORF_LEN, nil
+	}
+
+	n, err := strconv.Atoi(v)
+
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s is an invalid minOrf", v)
+	}
+
+	return n, nil
+}
+
+// Translate locations or BED regions in up to six frames and find
+// the ORFs in them
+func TranslateRoute(c *gin.Context) {
+	regions, err := ParseRegionsFromPost(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	frames, err := parseFrames(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	minLen, err := parseMinORFLen(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	span := tracing.Start(c, "dnadbcache.Db")
+	dnadb, err := dnadbcache.Db(c.Param("assembly"))
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret := make([]*TranslateResp, 0, len(regions))
+
+	for ri, region := range regions {
+		location := region.Location
+
+		span := tracing.Start(c, "dna.DNADB.DNA")
+		seq, err := dnadb.DNA(location, "upper", "", false, false)
+		tracing.End(span, err)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		resp := TranslateResp{Location: location,
+			Name:   region.Name,
+			Strand: region.Strand,
+			Frames: make([]*FrameResp, 0, len(frames)),
+			ORFs:   make([]*ORFResp, 0, 10)}
+
+		for _, frame := range frames {
+			resp.Frames = append(resp.Frames, &FrameResp{Frame: frame, Protein: protein.TranslateFrame(seq, frame)})
+		}
+
+		for _, orf := range protein.FindORFs(seq, frames, minLen) {
+			strand := STRAND_POS
+
+			if orf.Frame < 0 {
+				strand = STRAND_NEG
+			}
+
+			resp.ORFs = append(resp.ORFs, &ORFResp{Location: dna.NewLocation(location.Chr,
+				location.Start+uint(orf.Start),
+				location.Start+uint(orf.End)-1),
+				Strand: strand,
+				ORF:    orf})
+		}
+
+		ret = append(ret, &resp)
+
+		jobs.SetProgress(c, ri+1, len(regions))
+	}
+
+	web.MakeDataResp(c, "", ret)
+}
+
+func sameId(a string, b string) bool {
+	// ignore versions such as ENST00000288602.11
+	return strings.EqualFold(strings.Split(a, ".")[0], strings.Split(b, ".")[0])
+}
+
+// Find a transcript by id, or the canonical transcript of a gene,
+// in features from a gene search
+func findTranscript(features []*genome.GenomicFeature, id string) (*genome.GenomicFeature, *genome.GenomicFeature) {
+	var canonical *genome.GenomicFeature
+	var gene *genome.GenomicFeature
+
+	for _, feature := range features {
+		for _, transcript := range feature.Children {
+			if transcript.Level != genome.LEVEL_TRANSCRIPT {
+				continue
+			}
+
+			if sameId(transcript.TranscriptId, id) {
+				return feature, transcript
+			}
+
+			if canonical == nil && (sameId(feature.GeneId, id) || strings.EqualFold(feature.GeneSymbol, id)) &&
+				(transcript.IsCanonical || len(feature.Children) == 1) {
+				gene = feature
+				canonical = transcript
+			}
+		}
+	}
+
+	return gene, canonical
+}
+
+// Splice transcripts, predict their CDS and protein, and describe
+// the effect of variants on them
+func TranscriptsRoute(c *gin.Context) {
+	assembly := c.Param("assembly")
+
+	var req ReqTranscripts
+
+	err := c.ShouldBindJSON(&req)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	vars := make([]*variants.Variant, 0, len(req.Variants))
+
+	for _, v := range req.Variants {
+		variant, err := variants.ParseVariant(v)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		vars = append(vars, variant)
+	}
+
+	if req.Vcf != "" {
+		vcf, err := variants.ParseVCF(strings.NewReader(req.Vcf), "")
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		vars = append(vars, vcf...)
+	}
+
+	span := tracing.Start(c, "genomedbcache.GeneDB")
+	db, err := genomedbcache.GeneDB(assembly)
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	span = tracing.Start(c, "dnadbcache.Db")
+	dnadb, err := dnadbcache.Db(assembly)
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret := make([]*TranscriptResp, 0, len(req.Ids))
+
+	for ii, id := range req.Ids {
+		span := tracing.Start(c, "genome.GeneDB.SearchForGeneByName")
+		features, err := db.SearchForGeneByName(id, genome.LEVEL_EXON, MAX_TRANSCRIPT_SEARCH, false, false, "")
+		tracing.End(span, err)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		gene, transcript := findTranscript(features, id)
+
+		if transcript == nil {
+			ret = append(ret, &TranscriptResp{Query: id, Status: TRANSCRIPT_NOT_FOUND, Reason: "no such transcript or gene"})
+			jobs.SetProgress(c, ii+1, len(req.Ids))
+			continue
+		}
+
+		exons := make([]*dna.Location, 0, len(transcript.Children))
+
+		for _, exon := range transcript.Children {
+			if exon.Level == genome.LEVEL_EXON {
+				exons = append(exons, exon.Location)
+			}
+		}
+
+		if len(exons) == 0 {
+			ret = append(ret, &TranscriptResp{Query: id, Status: TRANSCRIPT_NOT_FOUND, Reason: "transcript has no exons"})
+			jobs.SetProgress(c, ii+1, len(req.Ids))
+			continue
+		}
+
+		rev := transcript.Strand == STRAND_NEG
+
+		// transcript order
+		sort.Slice(exons, func(i, j int) bool {
+			if rev {
+				return exons[i].Start > exons[j].Start
+			}
+
+			return exons[i].Start < exons[j].Start
+		})
+
+		seqs := make([]string, len(exons))
+
+		for ei, exon := range exons {
+			span := tracing.Start(c, "dna.DNADB.DNA")
+			seqs[ei], err = dnadb.DNA(exon, "upper", "", rev, rev)
+			tracing.End(span, err)
+
+			if err != nil {
+				c.Error(err)
+				return
+			}
+		}
+
+		resp := TranscriptResp{Query: id,
+			Status:     TRANSCRIPT_FOUND,
+			GeneId:     gene.GeneId,
+			GeneSymbol: gene.GeneSymbol,
+			Location:   transcript.Location,
+			Transcript: protein.NewTranscript(transcript.TranscriptId, transcript.Strand, exons, seqs)}
+
+		if len(vars) > 0 {
+			resp.Effects = make([]*protein.Effect, 0, len(vars))
+
+			for _, variant := range vars {
+				resp.Effects = append(resp.Effects, resp.Transcript.Effect(variant))
+			}
+		}
+
+		ret = append(ret, &resp)
+
+		jobs.SetProgress(c, ii+1, len(req.Ids))
+	}
+
+	web.MakeDataResp(c, "", ret)
+}
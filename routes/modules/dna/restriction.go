@@ -0,0 +1,388 @@
+package dna
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/chromsizes"
+	"github.com/antonybholmes/go-edb-server-gin/enzymes"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	"github.com/antonybholmes/go-edb-server-gin/oligos"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Enzymes that can be mapped in one request
+const MAX_ENZYMES = 20
+
+// Sequence is scanned in blocks of this size when making a genome
+// wide fragment map
+const FRAGMENTS_BLOCK_SIZE uint = 1000000
+
+// Seconds clients are asked to wait while a fragment map is made
+const FRAGMENTS_RETRY_SECS = "60"
+
+// Where genome wide fragment maps are kept
+var fragmentsDir string
+
+// A fragment map being made, or that failed to be
+type fragmentsBuild struct {
+	building bool
+	err      error
+}
+
+// fragment maps in progress by file
+var fragmentMaps = make(map[string]*fragmentsBuild)
+
+var fragmentsLock sync.Mutex
+
+type SiteResp struct {
+	Location *dna.Location `json:"location"`
+	Strand   string        `json:"strand"`
+	// 1-based positions of the base before the cut on each
+	// strand, which can be outside the region
+	Cut      int `json:"cut"`
+	OtherCut int `json:"otherCut"`
+}
+
+type FragmentResp struct {
+	Location *dna.Location `json:"location"`
+	Len      uint          `json:"len"`
+}
+
+type EnzymeSitesResp struct {
+	Enzyme    string          `json:"enzyme"`
+	Site      string          `json:"site"`
+	Sites     []*SiteResp     `json:"sites"`
+	Fragments []*FragmentResp `json:"fragments"`
+}
+
+type RestrictionResp struct {
+	Location *dna.Location      `json:"location"`
+	Name     string             `json:"name,omitempty"`
+	Strand   string             `json:"strand,omitempty"`
+	Enzymes  []*EnzymeSitesResp `json:"enzymes"`
+}
+
+func EnzymesRoute(c *gin.Context) {
+	ret, err := enzymes.List()
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	web.MakeDataResp(c, "", ret)
+}
+
+// Read ?enzymes= as a comma separated list of names
+func parseEnzymes(c *gin.Context) ([]*enzymes.Enzyme, error) {
+	names := strings.Split(c.Query("enzymes"), ",")
+
+	ret := make([]*enzymes.Enzyme, 0, len(names))
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			continue
+		}
+
+		enzyme, err := enzymes.Get(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, enzyme)
+	}
+
+	if len(ret) == 0 || len(ret) > MAX_ENZYMES {
+		return nil, fmt.Errorf("give 1 to %d enzymes", MAX_ENZYMES)
+	}
+
+	return ret, nil
+}
+
+// Restriction sites, cuts and fragments of locations or BED regions
+func RestrictionRoute(c *gin.Context) {
+	regions, err := ParseRegionsFromPost(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	list, err := parseEnzymes(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	span := tracing.Start(c, "dnadbcache.Db")
+	dnadb, err := dnadbcache.Db(c.Param("assembly"))
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret := make([]*RestrictionResp, 0, len(regions))
+
+	for ri, region := range regions {
+		location := region.Location
+
+		span := tracing.Start(c, "dna.DNADB.DNA")
+		seq, err := dnadb.DNA(location, "upper", "", false, false)
+		tracing.End(span, err)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		resp := RestrictionResp{Location: location,
+			Name:    region.Name,
+			Strand:  region.Strand,
+			Enzymes: make([]*EnzymeSitesResp, 0, len(list))}
+
+		// genomic position of the base before offset 0
+		offset := int(location.Start) - 1
+
+		for _, enzyme := range list {
+			sites := enzyme.Sites(seq)
+
+			item := EnzymeSitesResp{Enzyme: enzyme.Name,
+				Site:      enzyme.Site,
+				Sites:     make([]*SiteResp, 0, len(sites)),
+				Fragments: make([]*FragmentResp, 0, len(sites)+1)}
+
+			for _, site := range sites {
+				item.Sites = append(item.Sites, &SiteResp{Location: dna.NewLocation(location.Chr,
+					location.Start+uint(site.Start),
+					location.Start+uint(site.Start+len(enzyme.Site))-1),
+					Strand:   site.Strand,
+					Cut:      offset + site.Cut,
+					OtherCut: offset + site.OtherCut})
+			}
+
+			cuts := enzymes.Fragments(sites, len(seq))
+
+			for i := 1; i < len(cuts); i++ {
+				item.Fragments = append(item.Fragments, &FragmentResp{Location: dna.NewLocation(location.Chr,
+					location.Start+uint(cuts[i-1]),
+					location.Start+uint(cuts[i])-1),
+					Len: uint(cuts[i] - cuts[i-1])})
+			}
+
+			resp.Enzymes = append(resp.Enzymes, &item)
+		}
+
+		ret = append(ret, &resp)
+
+		jobs.SetProgress(c, ri+1, len(regions))
+	}
+
+	web.MakeDataResp(c, "", ret)
+}
+
+// Cuts on the + strand of a chromosome, as 0-based offsets
+func chromCuts(size *chromsizes.Size, fetch oligos.FetchFunc, enzyme *enzymes.Enzyme) ([]int, error) {
+	ret := make([]int, 0, 1000)
+
+	l := uint(len(enzyme.Site))
+
+	for start := uint(1); start <= size.Len; start += FRAGMENTS_BLOCK_SIZE {
+		// overlap blocks so sites across their ends are found
+		end := min(start+FRAGMENTS_BLOCK_SIZE+l-2, size.Len)
+
+		seq, err := fetch(dna.NewLocation(size.Name, start, end))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, site := range enzyme.Sites(seq) {
+			// sites starting in the overlap belong to the next block
+			if uint(site.Start) >= FRAGMENTS_BLOCK_SIZE {
+				continue
+			}
+
+			cut := int(start) - 1 + site.Cut
+
+			if cut > 0 && cut < int(size.Len) {
+				ret = append(ret, cut)
+			}
+		}
+	}
+
+	sort.Ints(ret)
+
+	return ret, nil
+}
+
+// Write the fragments of each chromosome as BED
+func writeFragments(w io.Writer, assembly string, enzyme *enzymes.Enzyme) error {
+	sizes, err := seqSource.ChromSizes(assembly)
+
+	if err != nil {
+		return err
+	}
+
+	fetch, err := seqSource.Fetcher(assembly)
+
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+
+	n := 0
+
+	for _, size := range sizes {
+		cuts, err := chromCuts(size, fetch, enzyme)
+
+		if err != nil {
+			return err
+		}
+
+		prev := 0
+
+		for _, cut := range append(cuts, int(size.Len)) {
+			// sites close together can cut at the same place
+			if cut <= prev {
+				continue
+			}
+
+			n++
+
+			fmt.Fprintf(bw, "%s\t%d\t%d\t%s_%d\n", size.Name, prev, cut, enzyme.Name, n)
+
+			prev = cut
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Make a fragment map file in the background, recording how it went
+func buildFragments(file string, assembly string, enzyme *enzymes.Enzyme) {
+	err := writeFragmentsFile(file, assembly, enzyme)
+
+	fragmentsLock.Lock()
+	defer fragmentsLock.Unlock()
+
+	if err != nil {
+		log.Error().Msgf("fragments %s: %s", file, err)
+		fragmentMaps[file] = &fragmentsBuild{err: err}
+		return
+	}
+
+	log.Info().Msgf("made fragment map %s", file)
+
+	delete(fragmentMaps, file)
+}
+
+// Write the map to a temp file first so a partial map is never served
+func writeFragmentsFile(file string, assembly string, enzyme *enzymes.Enzyme) error {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), enzyme.Name+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	err = writeFragments(tmp, assembly, enzyme)
+
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+// Genome wide fragment map of an enzyme as BED. Maps are made in the
+// background the first time they are asked for, during which 503 is
+// returned, and then kept.
+func FragmentsRoute(c *gin.Context) {
+	assembly := c.Param("assembly")
+
+	if !oligos.ValidAssembly.MatchString(assembly) {
+		web.BadReqResp(c, fmt.Sprintf("%s is not a valid assembly", assembly))
+		return
+	}
+
+	enzyme, err := enzymes.Get(c.Param("enzyme"))
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if seqSource == nil {
+		c.Error(fmt.Errorf("fragment maps are not available"))
+		return
+	}
+
+	file := filepath.Join(fragmentsDir, assembly, enzyme.Name+".bed")
+
+	_, err = os.Stat(file)
+
+	if err == nil {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s.bed\"", assembly, enzyme.Name))
+		c.Header("Content-Type", MIME_BED)
+		c.File(file)
+		return
+	}
+
+	fragmentsLock.Lock()
+
+	build, ok := fragmentMaps[file]
+
+	if ok && build.err != nil {
+		// report the failure once and then allow another try
+		delete(fragmentMaps, file)
+		fragmentsLock.Unlock()
+		c.Error(build.err)
+		return
+	}
+
+	if !ok {
+		fragmentMaps[file] = &fragmentsBuild{building: true}
+
+		go buildFragments(file, assembly, enzyme)
+	}
+
+	fragmentsLock.Unlock()
+
+	c.Header("Retry-After", FRAGMENTS_RETRY_SECS)
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable,
+		gin.H{"status": http.StatusServiceUnavailable, "message": "the fragment map is being made, try again in a few minutes"})
+}
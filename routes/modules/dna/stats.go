@@ -0,0 +1,262 @@
+package dna
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// Stop a small window over a large region producing an
+// enormous profile
+const MAX_STATS_WINDOWS = 10000
+
+var DINUCLEOTIDES = []string{"AA", "AC", "AG", "AT",
+	"CA", "CC", "CG", "CT",
+	"GA", "GC", "GG", "GT",
+	"TA", "TC", "TG", "TT"}
+
+// Composition of a sequence. Fractions are of the whole sequence
+// except GC, which ignores Ns. The CpG observed/expected ratio is
+// CpG count * length / (C count * G count), also ignoring Ns.
+type SeqStats struct {
+	Location      *dna.Location      `json:"location"`
+	Length        int                `json:"length"`
+	GC            float64            `json:"gc"`
+	CpGObsExp     float64            `json:"cpgObsExp"`
+	Repeat        float64            `json:"repeat"`
+	N             float64            `json:"n"`
+	Dinucleotides map[string]float64 `json:"dinucleotides,omitempty"`
+}
+
+type RegionStats struct {
+	Name   string `json:"name,omitempty"`
+	Strand string `json:"strand,omitempty"`
+	SeqStats
+	// stats for sliding windows across the region, if asked for
+	Profile []*SeqStats `json:"profile,omitempty"`
+}
+
+// Calculate the composition of a sequence. Repeat masked bases
+// must be lowercase. Dinucleotide frequencies are optional since
+// they are not needed for window profiles.
+func MakeSeqStats(seq string, dinucleotides bool) *SeqStats {
+	var a, c, g, t, n, masked, cpg, pairs int
+
+	var counts map[string]int
+
+	if dinucleotides {
+		counts = make(map[string]int, len(DINUCLEOTIDES))
+	}
+
+	var prev byte
+
+	for i := 0; i < len(seq); i++ {
+		b := seq[i]
+
+		if b >= 'a' && b <= 'z' {
+			masked++
+			b -= 'a' - 'A'
+		}
+
+		switch b {
+		case 'A':
+			a++
+		case 'C':
+			c++
+		case 'G':
+			g++
+
+			if prev == 'C' {
+				cpg++
+			}
+		case 'T':
+			t++
+		default:
+			n++
+			b = 'N'
+		}
+
+		if dinucleotides && prev != 0 && prev != 'N' && b != 'N' {
+			counts[string([]byte{prev, b})]++
+			pairs++
+		}
+
+		prev = b
+	}
+
+	ret := SeqStats{Length: len(seq)}
+
+	if len(seq) > 0 {
+		ret.Repeat = float64(masked) / float64(len(seq))
+		ret.N = float64(n) / float64(len(seq))
+	}
+
+	acgt := a + c + g + t
+
+	if acgt > 0 {
+		ret.GC = float64(g+c) / float64(acgt)
+	}
+
+	if c > 0 && g > 0 {
+		ret.CpGObsExp = float64(cpg) * float64(acgt) / (float64(c) * float64(g))
+	}
+
+	if dinucleotides {
+		ret.Dinucleotides = make(map[string]float64, len(DINUCLEOTIDES))
+
+		for _, di := range DINUCLEOTIDES {
+			if pairs > 0 {
+				ret.Dinucleotides[di] = float64(counts[di]) / float64(pairs)
+			} else {
+				ret.Dinucleotides[di] = 0
+			}
+		}
+	}
+
+	return &ret
+}
+
+// Stats for windows of a sequence taken from location. Windows
+// are in the order of the sequence so run from the end of the
+// location when the sequence is reversed.
+func MakeProfile(seq string, location *dna.Location, rev bool, window int, step int) []*SeqStats {
+	ret := make([]*SeqStats, 0, len(seq)/step+1)
+
+	for i := 0; i < len(seq); i += step {
+		end := min(i+window, len(seq))
+
+		stats := MakeSeqStats(seq[i:end], false)
+
+		if rev {
+			stats.Location = dna.NewLocation(location.Chr,
+				location.End-uint(end)+1,
+				location.End-uint(i))
+		} else {
+			stats.Location = dna.NewLocation(location.Chr,
+				location.Start+uint(i),
+				location.Start+uint(end)-1)
+		}
+
+		ret = append(ret, stats)
+
+		if end == len(seq) {
+			break
+		}
+	}
+
+	return ret
+}
+
+// Read ?window= and ?step= for sliding window profiles. A window
+// of 0 means no profile and step defaults to the window size.
+func parseWindow(c *gin.Context) (int, int, error) {
+	window := 0
+	step := 0
+
+	var err error
+
+	v := c.Query("window")
+
+	if v != "" {
+		window, err = strconv.Atoi(v)
+
+		if err != nil || window < 0 {
+			return 0, 0, fmt.Errorf("%s is an invalid window", v)
+		}
+	}
+
+	v = c.Query("step")
+
+	if v != "" {
+		step, err = strconv.Atoi(v)
+
+		if err != nil || step < 1 {
+			return 0, 0, fmt.Errorf("%s is an invalid step", v)
+		}
+	}
+
+	if step == 0 {
+		step = window
+	}
+
+	return window, step, nil
+}
+
+func StatsRoute(c *gin.Context) {
+	regions, err := ParseRegionsFromPost(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	query, err := ParseDNAQuery(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	window, step, err := parseWindow(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	span := tracing.Start(c, "dnadbcache.Db")
+	dnadb, err := dnadbcache.Db(c.Param("assembly"))
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret := make([]*RegionStats, 0, len(regions))
+
+	for ri, region := range regions {
+		item := RegionDNA(region, query)
+
+		if window > 0 && int(item.Location.Len())/step > MAX_STATS_WINDOWS {
+			c.Error(fmt.Errorf("%s would have more than %d windows", item.Location, MAX_STATS_WINDOWS))
+			return
+		}
+
+		// masked bases are lowercase so they can be counted
+		span := tracing.Start(c, "dna.DNADB.DNA")
+		seq, err := dnadb.DNA(item.Location,
+			"",
+			"lower",
+			item.IsRev,
+			item.IsComplement)
+		tracing.End(span, err)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		stats := RegionStats{Name: region.Name,
+			Strand:   region.Strand,
+			SeqStats: *MakeSeqStats(seq, true)}
+
+		stats.Location = item.Location
+
+		if window > 0 {
+			stats.Profile = MakeProfile(seq, item.Location, item.IsRev, window, step)
+		}
+
+		ret = append(ret, &stats)
+
+		jobs.SetProgress(c, ri+1, len(regions))
+	}
+
+	tabular.MakeResp(c, ret)
+}
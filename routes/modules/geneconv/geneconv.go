@@ -3,9 +3,10 @@ package geneconv
 import (
 	"strings"
 
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	geneconv "github.com/antonybholmes/go-geneconv"
 	geneconvdbcache "github.com/antonybholmes/go-geneconv/geneconvdbcache"
-	"github.com/antonybholmes/go-web"
 	"github.com/gin-gonic/gin"
 )
 
@@ -86,15 +87,17 @@ func ConvertRoute(c *gin.Context) {
 	for _, search := range params.Searches {
 
 		// Don't care about the errors, just plug empty list into failures
+		span := tracing.Start(c, "geneconvdbcache.Convert")
 		conversion, _ := geneconvdbcache.Convert(search,
 			fromSpecies,
 			toSpecies,
 			params.Exact)
+		tracing.End(span, nil)
 
 		ret.Conversions = append(ret.Conversions, conversion)
 	}
 
-	web.MakeDataResp(c, "", ret)
+	tabular.MakeResp(c, ret)
 
 	//web.MakeDataResp(c, "", mutationdbcache.GetInstance().List())
 }
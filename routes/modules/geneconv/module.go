@@ -0,0 +1,49 @@
+package geneconv
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	geneconvdbcache "github.com/antonybholmes/go-geneconv/geneconvdbcache"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "geneconv"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_USER
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	geneconvdbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodPost, Path: "/convert/:from/:to", Handler: ConvertRoute,
+			Doc: &openapi.Doc{Summary: "Convert genes between species", Request: ReqParams{}}},
+	}
+}
+
+func (m *Module) Health() error {
+	return modules.CheckPath(m.path)
+}
+
+func (m *Module) Close() error {
+	return geneconvdbcache.Close()
+}
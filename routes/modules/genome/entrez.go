@@ -0,0 +1,192 @@
+package genes
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antonybholmes/go-edb-server-gin/oligos"
+)
+
+// The gene dbs have no Entrez ids so they are mapped to Ensembl gene
+// ids by a table per assembly in this dir of the module, such as
+// entrez/grch38.tsv or entrez/grch38.tsv.gz
+const ENTREZ_DIR = "entrez"
+
+// Column names of the Entrez and Ensembl ids, including those of
+// NCBI's gene2ensembl
+var ENTREZ_COLUMNS = []string{"entrez", "entrez_id", "geneid"}
+var ENSEMBL_COLUMNS = []string{"gene_id", "ensembl", "ensembl_gene_identifier"}
+
+// A table that cannot be read is tried again after this long
+const ENTREZ_RETRY_INTERVAL = 5 * time.Minute
+
+// Ensembl gene ids of each Entrez id of an assembly, loaded once
+type entrezTable struct {
+	once   sync.Once
+	ids    map[string][]string
+	err    error
+	loaded time.Time
+}
+
+var entrezTables = struct {
+	dir    string
+	lock   sync.Mutex
+	tables map[string]*entrezTable
+}{tables: make(map[string]*entrezTable)}
+
+func initEntrez(dir string) {
+	entrezTables.lock.Lock()
+	defer entrezTables.lock.Unlock()
+
+	entrezTables.dir = dir
+	entrezTables.tables = make(map[string]*entrezTable)
+}
+
+// Ensembl gene ids of an Entrez id, which is usually one but can be
+// several or none
+func entrezToEnsembl(assembly string, id string) ([]string, error) {
+	if !oligos.ValidAssembly.MatchString(assembly) {
+		return nil, fmt.Errorf("%s is not a valid assembly", assembly)
+	}
+
+	key := strings.ToLower(assembly)
+
+	entrezTables.lock.Lock()
+
+	table, ok := entrezTables.tables[key]
+
+	if !ok {
+		table = &entrezTable{}
+		entrezTables.tables[key] = table
+	}
+
+	dir := entrezTables.dir
+
+	entrezTables.lock.Unlock()
+
+	table.once.Do(func() {
+		table.ids, table.err = readEntrezTable(dir, key)
+		table.loaded = time.Now()
+	})
+
+	if table.err != nil && time.Since(table.loaded) > ENTREZ_RETRY_INTERVAL {
+		entrezTables.lock.Lock()
+
+		if entrezTables.tables[key] == table {
+			delete(entrezTables.tables, key)
+		}
+
+		entrezTables.lock.Unlock()
+	}
+
+	if table.err != nil {
+		return nil, table.err
+	}
+
+	return table.ids[id], nil
+}
+
+func column(header []string, names []string) int {
+	for i, h := range header {
+		for _, name := range names {
+			if strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(h), "#"), name) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// Read a tab separated table with a header naming its Entrez and
+// Ensembl gene id columns. Other columns are ignored.
+func readEntrezTable(dir string, assembly string) (map[string][]string, error) {
+	file := filepath.Join(dir, assembly+".tsv")
+
+	f, err := os.Open(file)
+
+	if os.IsNotExist(err) {
+		file += ".gz"
+		f, err = os.Open(file)
+	}
+
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no entrez id table for %s", assembly)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var reader io.Reader = f
+
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer gz.Close()
+
+		reader = gz
+	}
+
+	scanner := bufio.NewScanner(reader)
+
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("%s is empty", file)
+	}
+
+	header := strings.Split(scanner.Text(), "\t")
+
+	ei := column(header, ENTREZ_COLUMNS)
+	gi := column(header, ENSEMBL_COLUMNS)
+
+	if ei == -1 || gi == -1 {
+		return nil, fmt.Errorf("%s must have entrez and gene_id columns", file)
+	}
+
+	ret := make(map[string][]string)
+
+	for scanner.Scan() {
+		tokens := strings.Split(scanner.Text(), "\t")
+
+		if len(tokens) <= max(ei, gi) {
+			continue
+		}
+
+		entrez := strings.TrimSpace(tokens[ei])
+		gene := unversioned(strings.TrimSpace(tokens[gi]))
+
+		if entrez == "" || gene == "" || gene == "-" {
+			continue
+		}
+
+		ids := ret[entrez]
+
+		// tables such as gene2ensembl repeat a gene for each
+		// of its transcripts
+		if !slices.Contains(ids, gene) {
+			ret[entrez] = append(ids, gene)
+		}
+	}
+
+	err = scanner.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
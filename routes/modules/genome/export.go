@@ -0,0 +1,162 @@
+package genes
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/antonybholmes/go-edb-server-gin/genemodels"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-genome"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Genes matched by a search before export
+const DEFAULT_EXPORT_SEARCH_N uint16 = 20
+
+// Drop the transcripts of genes that are not canonical, in case the
+// gene db returns them anyway
+func canonicalOnly(genes []*genome.GenomicFeature) []*genome.GenomicFeature {
+	ret := make([]*genome.GenomicFeature, 0, len(genes))
+
+	for _, gene := range genes {
+		g := *gene
+		g.Children = make([]*genome.GenomicFeature, 0, 1)
+
+		for _, transcript := range gene.Children {
+			if transcript.Level != genome.LEVEL_TRANSCRIPT || transcript.IsCanonical {
+				g.Children = append(g.Children, transcript)
+			}
+		}
+
+		ret = append(ret, &g)
+	}
+
+	return ret
+}
+
+// Export the gene models of locations or BED regions, or of genes
+// matching ?search=, as GTF, GFF3 or BED12 so they can be loaded into
+// other tools. Genes are exported down to ?level=, exons by default,
+// with the same ?canonical= and ?type= filters as the other routes.
+func ExportGenesRoute(c *gin.Context) {
+	assembly := c.Param("assembly")
+
+	format := strings.ToLower(c.Query("format"))
+
+	var write func(w io.Writer, genes []*genome.GenomicFeature, level genome.Level) error
+	var contentType string
+
+	switch format {
+	case genemodels.FORMAT_GTF, "":
+		format = genemodels.FORMAT_GTF
+		write = genemodels.WriteGTF
+		contentType = genemodels.MIME_GTF
+	case genemodels.FORMAT_GFF3, "gff":
+		format = genemodels.FORMAT_GFF3
+		write = genemodels.WriteGFF3
+		contentType = genemodels.MIME_GFF3
+	case genemodels.FORMAT_BED12, "bed":
+		format = genemodels.FORMAT_BED12
+		write = genemodels.WriteBED12
+		contentType = dnaroutes.MIME_BED
+	default:
+		web.BadReqResp(c, fmt.Sprintf("%s is not a supported format, use gtf, gff3 or bed12", format))
+		return
+	}
+
+	query, err := parseGeneQuery(c, assembly)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// whole gene models unless asked for less
+	if c.Query("level") == "" {
+		query.Level = genome.LEVEL_EXON
+	}
+
+	genes := make([]*genome.GenomicFeature, 0, 100)
+
+	search := c.Query("search")
+
+	if search != "" {
+		n := web.ParseN(c, DEFAULT_EXPORT_SEARCH_N)
+
+		span := tracing.Start(c, "genome.GeneDB.SearchForGeneByName")
+		features, err := query.Db.SearchForGeneByName(search, genome.LEVEL_EXON, n, c.Query("mode") == "fuzzy", query.Canonical, query.GeneType)
+		tracing.End(span, err)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		genes = append(genes, features...)
+	} else {
+		regions, err := dnaroutes.ParseRegionsFromPost(c)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		within := strings.HasPrefix(strings.ToLower(c.Query("within")), "t")
+
+		// genes can overlap more than one region
+		seen := make(map[string]struct{})
+
+		for _, region := range regions {
+			span := tracing.Start(c, "genome.GeneDB.OverlappingGenes")
+			features, err := query.Db.OverlappingGenes(region.Location, query.Canonical, query.GeneType)
+			tracing.End(span, err)
+
+			if err != nil {
+				c.Error(err)
+				return
+			}
+
+			for _, feature := range features {
+				if within && (feature.Location.Start < region.Location.Start || feature.Location.End > region.Location.End) {
+					continue
+				}
+
+				id := feature.GeneId + ":" + feature.Location.String()
+
+				_, ok := seen[id]
+
+				if ok {
+					continue
+				}
+
+				seen[id] = struct{}{}
+
+				genes = append(genes, feature)
+			}
+		}
+	}
+
+	if query.Canonical {
+		genes = canonicalOnly(genes)
+	}
+
+	c.Header("Content-Type", contentType+"; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"genes.%s.%s\"", assembly, format))
+
+	span := tracing.Start(c, "genemodels.Write")
+	err = write(c.Writer, genes, query.Level)
+	tracing.End(span, err)
+
+	if err != nil {
+		if c.Writer.Written() {
+			log.Ctx(c.Request.Context()).Error().Msgf("export %s: %s", assembly, err)
+			c.Abort()
+		} else {
+			c.Error(err)
+		}
+	}
+}
@@ -1,20 +1,26 @@
 package genes
 
 import (
-	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/annotation"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
 	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-genome"
 	"github.com/antonybholmes/go-genome/genomedbcache"
-	basemath "github.com/antonybholmes/go-math"
 	"github.com/antonybholmes/go-web"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
 const DEFAULT_LEVEL genome.Level = genome.LEVEL_GENE
@@ -33,14 +39,36 @@ type GeneQuery struct {
 
 type GenesResp struct {
 	Location *dna.Location            `json:"location"`
+	Name     string                   `json:"name,omitempty"`
+	Strand   string                   `json:"strand,omitempty"`
 	Features []*genome.GenomicFeature `json:"features"`
 }
 
+// Genes for a region, labelled with the name and strand the
+// region was given in a BED file
+type RegionFeatures struct {
+	Name   string `json:"name,omitempty"`
+	Strand string `json:"strand,omitempty"`
+	*genome.GenomicFeatures
+}
+
+type RegionAnnotation struct {
+	Name   string `json:"name,omitempty"`
+	Strand string `json:"strand,omitempty"`
+	*genome.GeneAnnotation
+}
+
 const MAX_ANNOTATIONS = 1000
 
+// Rows of streamed annotations sent at a time
+const STREAM_FLUSH_ROWS = 100
+
 type AnnotationResponse struct {
-	Status int                      `json:"status"`
-	Data   []*genome.GeneAnnotation `json:"data"`
+	Status int                 `json:"status"`
+	Data   []*RegionAnnotation `json:"data"`
+	// set if only the first MAX_ANNOTATIONS regions were annotated
+	Truncated bool `json:"truncated,omitempty"`
+	Total     int  `json:"total,omitempty"`
 }
 
 func parseGeneQuery(c *gin.Context, assembly string) (*GeneQuery, error) {
@@ -69,7 +97,9 @@ func parseGeneQuery(c *gin.Context, assembly string) (*GeneQuery, error) {
 		geneType = ""
 	}
 
+	span := tracing.Start(c, "genomedbcache.GeneDB")
 	db, err := genomedbcache.GeneDB(assembly)
+	tracing.End(span, err)
 
 	if err != nil {
 		return nil, fmt.Errorf("unable to open database for assembly %s %s", assembly, err)
@@ -101,7 +131,9 @@ func parseGeneQuery(c *gin.Context, assembly string) (*GeneQuery, error) {
 // }
 
 func GenomesRoute(c *gin.Context) {
+	span := tracing.Start(c, "genomedbcache.List")
 	infos, err := genomedbcache.GetInstance().List()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -112,7 +144,7 @@ func GenomesRoute(c *gin.Context) {
 }
 
 func OverlappingGenesRoute(c *gin.Context) {
-	locations, err := dnaroutes.ParseLocationsFromPost(c) // dnaroutes.ParseLocationsFromPost(c)
+	regions, err := dnaroutes.ParseRegionsFromPost(c)
 
 	if err != nil {
 		c.Error(err)
@@ -126,25 +158,30 @@ func OverlappingGenesRoute(c *gin.Context) {
 		return
 	}
 
-	if len(locations) == 0 {
+	if len(regions) == 0 {
 		web.BadReqResp(c, "must supply at least 1 location")
 	}
 
-	ret := make([]*GenesResp, 0, len(locations))
+	ret := make([]*GenesResp, 0, len(regions))
 
-	for _, location := range locations {
-		features, err := query.Db.OverlappingGenes(location, query.Canonical, query.GeneType)
+	for _, region := range regions {
+		span := tracing.Start(c, "genome.GeneDB.OverlappingGenes")
+		features, err := query.Db.OverlappingGenes(region.Location, query.Canonical, query.GeneType)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		ret = append(ret, &GenesResp{Location: location, Features: features})
+		ret = append(ret, &GenesResp{Location: region.Location,
+			Name:     region.Name,
+			Strand:   region.Strand,
+			Features: features})
 
 	}
 
-	web.MakeDataResp(c, "", &ret)
+	tabular.MakeResp(c, &ret)
 }
 
 func SearchForGeneByNameRoute(c *gin.Context) {
@@ -168,7 +205,9 @@ func SearchForGeneByNameRoute(c *gin.Context) {
 
 	canonical := strings.HasPrefix(strings.ToLower(c.Query("canonical")), "t")
 
+	span := tracing.Start(c, "genome.GeneDB.SearchForGeneByName")
 	features, _ := query.Db.SearchForGeneByName(search, query.Level, n, fuzzyMode, canonical, c.Query("type"))
+	tracing.End(span, nil)
 
 	// if err != nil {
 	// 	return web.ErrorReq(err)
@@ -178,7 +217,7 @@ func SearchForGeneByNameRoute(c *gin.Context) {
 }
 
 func WithinGenesRoute(c *gin.Context) {
-	locations, err := dnaroutes.ParseLocationsFromPost(c) // dnaroutes.ParseLocationsFromPost(c)
+	regions, err := dnaroutes.ParseRegionsFromPost(c)
 
 	if err != nil {
 		c.Error(err)
@@ -192,25 +231,27 @@ func WithinGenesRoute(c *gin.Context) {
 		return
 	}
 
-	data := make([]*genome.GenomicFeatures, len(locations))
+	data := make([]*RegionFeatures, len(regions))
 
-	for li, location := range locations {
-		genes, err := query.Db.WithinGenes(location, query.Level)
+	for ri, region := range regions {
+		span := tracing.Start(c, "genome.GeneDB.WithinGenes")
+		genes, err := query.Db.WithinGenes(region.Location, query.Level)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		data[li] = genes
+		data[ri] = &RegionFeatures{Name: region.Name, Strand: region.Strand, GenomicFeatures: genes}
 	}
 
-	web.MakeDataResp(c, "", &data)
+	tabular.MakeResp(c, &data)
 }
 
 // Find the n closest genes to a location
 func ClosestGeneRoute(c *gin.Context) {
-	locations, err := dnaroutes.ParseLocationsFromPost(c)
+	regions, err := dnaroutes.ParseRegionsFromPost(c)
 
 	if err != nil {
 		c.Error(err)
@@ -226,58 +267,94 @@ func ClosestGeneRoute(c *gin.Context) {
 
 	n := web.ParseN(c, DEFAULT_CLOSEST_N)
 
-	data := make([]*genome.GenomicFeatures, len(locations))
+	data := make([]*RegionFeatures, len(regions))
 
-	for li, location := range locations {
-		genes, err := query.Db.ClosestGenes(location, n, query.Level)
+	for ri, region := range regions {
+		span := tracing.Start(c, "genome.GeneDB.ClosestGenes")
+		genes, err := query.Db.ClosestGenes(region.Location, n, query.Level)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		data[li] = genes
+		data[ri] = &RegionFeatures{Name: region.Name, Strand: region.Strand, GenomicFeatures: genes}
 	}
 
-	web.MakeDataResp(c, "", &data)
+	tabular.MakeResp(c, &data)
 }
 
 func ParseTSSRegion(c *gin.Context) *dna.TSSRegion {
+	return parseTSSRegion(c, dna.NewTSSRegion(2000, 1000))
+}
+
+// Read ?tss=5000,1000 as the bases upstream and downstream of a TSS
+func parseTSSRegion(c *gin.Context, defaultRegion *dna.TSSRegion) *dna.TSSRegion {
 
 	v := c.Query("tss")
 
 	if v == "" {
-		return dna.NewTSSRegion(2000, 1000)
+		return defaultRegion
 	}
 
 	tokens := strings.Split(v, ",")
 
+	if len(tokens) < 2 {
+		return defaultRegion
+	}
+
 	s, err := strconv.ParseUint(tokens[0], 10, 0)
 
 	if err != nil {
-		return dna.NewTSSRegion(2000, 1000)
+		return defaultRegion
 	}
 
 	e, err := strconv.ParseUint(tokens[1], 10, 0)
 
 	if err != nil {
-		return dna.NewTSSRegion(2000, 1000)
+		return defaultRegion
 	}
 
 	return dna.NewTSSRegion(uint(s), uint(e))
 }
 
+// Read ?tes= as how many bases after the end of a gene are downstream
+// of it
+func parseDownstreamDist(c *gin.Context) (uint, error) {
+	v := c.Query("tes")
+
+	if v == "" {
+		return annotation.DEFAULT_DOWNSTREAM_DIST, nil
+	}
+
+	n, err := strconv.ParseUint(v, 10, 0)
+
+	if err != nil {
+		return 0, fmt.Errorf("%s is an invalid downstream distance", v)
+	}
+
+	return uint(n), nil
+}
+
+// Annotate regions with the genes they are in and their closest
+// genes. TSV, CSV and NDJSON rows are streamed as they are made so
+// any number of regions can be annotated. JSON is held in memory so
+// is limited to MAX_ANNOTATIONS regions, unless running as a job, and
+// says so if regions were left out. ?sweep=true annotates regions in
+// order of position from each chromosome's genes in memory, which is
+// much faster for large sets, and rows are returned in that order.
+// Both label regions as promoter, exonic, intronic, downstream (within
+// ?tes= bases after a gene) or intergenic by the parts of genes they
+// overlap.
 func AnnotateRoute(c *gin.Context) {
-	locations, err := dnaroutes.ParseLocationsFromPost(c)
+	regions, err := dnaroutes.ParseRegionsFromPost(c)
 
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	// limit amount of data returned per request to 1000 entries at a time
-	locations = locations[0:basemath.Min(len(locations), MAX_ANNOTATIONS)]
-
 	query, err := parseGeneQuery(c, c.Param("assembly"))
 
 	if err != nil {
@@ -289,104 +366,300 @@ func AnnotateRoute(c *gin.Context) {
 
 	tssRegion := ParseTSSRegion(c)
 
-	output := web.ParseOutput(c)
+	format := tabular.ParseFormat(c)
 
-	annotationDb := genome.NewAnnotateDb(query.Db, tssRegion, n)
+	// output=text is the original way of asking for a table
+	if web.ParseOutput(c) == "text" {
+		format = tabular.FORMAT_TSV
+	}
 
-	data := make([]*genome.GeneAnnotation, len(locations))
+	downstream, err := parseDownstreamDist(c)
 
-	for li, location := range locations {
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	classifier := annotation.NewClassifier(tssRegion, downstream)
 
-		annotations, err := annotationDb.Annotate(location)
+	fetch := func(location *dna.Location) ([]*genome.GenomicFeature, error) {
+		span := tracing.Start(c, "genome.GeneDB.OverlappingGenes")
+		features, err := query.Db.OverlappingGenes(location, query.Canonical, query.GeneType)
+		tracing.End(span, err)
+
+		return features, err
+	}
+
+	var annotate func(location *dna.Location) (*genome.GeneAnnotation, error)
+
+	if strings.HasPrefix(strings.ToLower(c.Query("sweep")), "t") {
+		regions = sortRegions(regions)
+
+		annotate = annotation.NewSweeper(fetch, classifier, n).Annotate
+	} else {
+		annotate = annotation.NewDbAnnotator(fetch, classifier, n).Annotate
+	}
+
+	switch format {
+	case tabular.FORMAT_TSV, tabular.FORMAT_CSV, tabular.FORMAT_NDJSON:
+		streamAnnotations(c, regions, annotate, format, tssRegion, int(n))
+		return
+	}
+
+	total := len(regions)
+
+	// limit amount of data returned per request to 1000 entries at a time
+	// unless running as a job where there is no client waiting
+	if !jobs.IsJob(c) && total > MAX_ANNOTATIONS {
+		regions = regions[0:MAX_ANNOTATIONS]
+
+		c.Header("Warning", fmt.Sprintf("299 - \"only the first %d of %d regions were annotated, use format=tsv or format=ndjson to annotate all of them\"", MAX_ANNOTATIONS, total))
+	}
+
+	data := make([]*RegionAnnotation, len(regions))
+
+	for ri, region := range regions {
+		annotations, err := annotate(region.Location)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		data[li] = annotations
+		data[ri] = &RegionAnnotation{Name: region.Name, Strand: region.Strand, GeneAnnotation: annotations}
+
+		jobs.SetProgress(c, ri+1, len(regions))
+	}
+
+	resp := AnnotationResponse{Status: http.StatusOK, Data: data}
+
+	if len(regions) < total {
+		resp.Truncated = true
+		resp.Total = total
 	}
 
-	if output == "text" {
-		tsv, err := MakeGeneTable(data, tssRegion)
+	c.JSON(http.StatusOK, resp)
+}
+
+// Regions in order of chromosome and start, leaving the input as is
+func sortRegions(regions []*dnaroutes.Region) []*dnaroutes.Region {
+	ret := make([]*dnaroutes.Region, len(regions))
+	copy(ret, regions)
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Location.Chr != ret[j].Location.Chr {
+			return ret[i].Location.Chr < ret[j].Location.Chr
+		}
+
+		return ret[i].Location.Start < ret[j].Location.Start
+	})
+
+	return ret
+}
+
+// Write each annotation as a row as soon as it is made
+func streamAnnotations(c *gin.Context,
+	regions []*dnaroutes.Region,
+	annotate func(location *dna.Location) (*genome.GeneAnnotation, error),
+	format string,
+	tssRegion *dna.TSSRegion,
+	closestN int) {
+
+	var write func(annotation *RegionAnnotation) error
+	var flush func() error
+
+	var contentType string
+
+	switch format {
+	case tabular.FORMAT_NDJSON:
+		contentType = tabular.MIME_NDJSON
+
+		enc := json.NewEncoder(c.Writer)
+
+		write = func(annotation *RegionAnnotation) error {
+			return enc.Encode(annotation)
+		}
+
+		flush = func() error {
+			return nil
+		}
+	default:
+		comma := '\t'
+		contentType = tabular.MIME_TSV
+
+		if format == tabular.FORMAT_CSV {
+			comma = ','
+			contentType = tabular.MIME_CSV
+		}
+
+		table, err := newGeneTableWriter(c.Writer, tssRegion, closestN, labelledRegions(regions), comma)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		c.String(http.StatusOK, tsv)
-	} else {
+		write = table.Write
+
+		flush = func() error {
+			table.wtr.Flush()
+			return table.wtr.Error()
+		}
+	}
 
-		c.JSON(http.StatusOK, AnnotationResponse{Status: http.StatusOK, Data: data})
+	// the response is only labelled once there is a row to send,
+	// so that an error annotating the first region is not sent
+	// as if it were part of the table
+	begin := func() {
+		c.Header("Content-Type", contentType)
+		c.Status(http.StatusOK)
 	}
+
+	for ri, region := range regions {
+		annotations, err := annotate(region.Location)
+
+		if err == nil {
+			if ri == 0 {
+				begin()
+			}
+
+			err = write(&RegionAnnotation{Name: region.Name, Strand: region.Strand, GeneAnnotation: annotations})
+		}
+
+		// send rows in batches rather than holding them all
+		if err == nil && (ri+1)%STREAM_FLUSH_ROWS == 0 {
+			err = flush()
+			c.Writer.Flush()
+		}
+
+		if err != nil {
+			if c.Writer.Written() {
+				// too late to send an error response so the
+				// output is truncated
+				log.Ctx(c.Request.Context()).Error().Msgf("annotate %s: %s", region.Location, err)
+				c.Abort()
+			} else {
+				c.Error(err)
+			}
+
+			return
+		}
+
+		jobs.SetProgress(c, ri+1, len(regions))
+	}
+
+	if len(regions) == 0 {
+		begin()
+	}
+
+	err := flush()
+
+	if err != nil {
+		log.Ctx(c.Request.Context()).Error().Msgf("annotate: %s", err)
+		c.Abort()
+		return
+	}
+
+	c.Writer.Flush()
+}
+
+// only add name and strand columns if the regions came
+// from a BED file that has them
+func labelledRegions(regions []*dnaroutes.Region) bool {
+	for _, region := range regions {
+		if region.Name != "" || region.Strand != "" {
+			return true
+		}
+	}
+
+	return false
 }
 
-func MakeGeneTable(
-	data []*genome.GeneAnnotation,
+// Writes annotations as rows of a table, one column group per
+// closest gene
+type geneTableWriter struct {
+	wtr      *csv.Writer
+	closestN int
+	labelled bool
+}
+
+func newGeneTableWriter(w io.Writer,
 	ts *dna.TSSRegion,
-) (string, error) {
-	var buffer bytes.Buffer
-	wtr := csv.NewWriter(&buffer)
-	wtr.Comma = '\t'
-
-	closestN := len(data[0].ClosestGenes)
-
-	headers := make([]string, 6+5*closestN)
-
-	headers[0] = "Location"
-	headers[1] = "ID"
-	headers[2] = "Gene Symbol"
-	headers[3] = fmt.Sprintf(
-		"Relative To Gene (prom=-%d/+%dkb)",
-		ts.Offset5P()/1000,
-		ts.Offset3P()/1000)
-	headers[4] = "TSS Distance"
-	headers[5] = "Gene Location"
-
-	idx := 6
-	for i := 1; i <= closestN; i++ {
-		headers[idx] = fmt.Sprintf("#%d Closest ID", i)
-		headers[idx] = fmt.Sprintf("#%d Closest Gene Symbols", i)
-		headers[idx] = fmt.Sprintf(
-			"#%d Relative To Closet Gene (prom=-%d/+%dkb)",
-			i,
+	closestN int,
+	labelled bool,
+	comma rune) (*geneTableWriter, error) {
+
+	wtr := csv.NewWriter(w)
+	wtr.Comma = comma
+
+	headers := make([]string, 0, 8+5*closestN)
+
+	headers = append(headers, "Location")
+
+	if labelled {
+		headers = append(headers, "Name", "Strand")
+	}
+
+	headers = append(headers, "ID",
+		"Gene Symbol",
+		fmt.Sprintf(
+			"Relative To Gene (prom=-%d/+%dkb)",
 			ts.Offset5P()/1000,
-			ts.Offset3P()/1000)
-		headers[idx] = fmt.Sprintf("#%d TSS Closest Distance", i)
-		headers[idx] = fmt.Sprintf("#%d Gene Location", i)
+			ts.Offset3P()/1000),
+		"TSS Distance",
+		"Gene Location")
+
+	for i := 1; i <= closestN; i++ {
+		headers = append(headers, fmt.Sprintf("#%d Closest ID", i),
+			fmt.Sprintf("#%d Closest Gene Symbols", i),
+			fmt.Sprintf(
+				"#%d Relative To Closest Gene (prom=-%d/+%dkb)",
+				i,
+				ts.Offset5P()/1000,
+				ts.Offset3P()/1000),
+			fmt.Sprintf("#%d TSS Closest Distance", i),
+			fmt.Sprintf("#%d Gene Location", i))
 	}
 
 	err := wtr.Write(headers)
 
 	if err != nil {
-		return "", err
-	}
-
-	for _, annotation := range data {
-		row := []string{annotation.Location.String(),
-			annotation.GeneIds,
-			annotation.GeneSymbols,
-			annotation.PromLabels,
-			annotation.TSSDists,
-			annotation.Locations}
-
-		for _, closestGene := range annotation.ClosestGenes {
-			row = append(row, closestGene.GeneId)
-			row = append(row, genome.GeneWithStrandLabel(closestGene.GeneSymbol, closestGene.Strand))
-			row = append(row, closestGene.PromLabel)
-			row = append(row, strconv.Itoa(closestGene.TssDist))
-			row = append(row, closestGene.Location.String())
-		}
+		return nil, err
+	}
 
-		err := wtr.Write(row)
+	return &geneTableWriter{wtr: wtr, closestN: closestN, labelled: labelled}, nil
+}
 
-		if err != nil {
-			return "", err
-		}
+func (table *geneTableWriter) Write(annotation *RegionAnnotation) error {
+	row := make([]string, 0, 8+5*table.closestN)
+
+	row = append(row, annotation.Location.String())
+
+	if table.labelled {
+		row = append(row, annotation.Name, annotation.Strand)
 	}
 
-	wtr.Flush()
+	row = append(row, annotation.GeneIds,
+		annotation.GeneSymbols,
+		annotation.PromLabels,
+		annotation.TSSDists,
+		annotation.Locations)
+
+	for i := 0; i < table.closestN; i++ {
+		// pad regions with fewer closest genes so columns line up
+		if i >= len(annotation.ClosestGenes) {
+			row = append(row, "", "", "", "", "")
+			continue
+		}
+
+		closestGene := annotation.ClosestGenes[i]
+
+		row = append(row, closestGene.GeneId)
+		row = append(row, genome.GeneWithStrandLabel(closestGene.GeneSymbol, closestGene.Strand))
+		row = append(row, closestGene.PromLabel)
+		row = append(row, strconv.Itoa(closestGene.TssDist))
+		row = append(row, closestGene.Location.String())
+	}
 
-	return buffer.String(), nil
+	return table.wtr.Write(row)
 }
@@ -0,0 +1,254 @@
+package genes
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/great"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-genome"
+	"github.com/antonybholmes/go-pathway/pathwaydbcache"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+)
+
+// Terms returned by default
+const DEFAULT_GREAT_TERMS uint16 = 100
+
+type GreatDataset struct {
+	Organization string `json:"organization"`
+	Name         string `json:"name"`
+}
+
+// Regions to test as locations or BED, and the pathway datasets to
+// test them against
+type ReqGreat struct {
+	Locations []string        `json:"locations"`
+	Bed       string          `json:"bed,omitempty"`
+	Datasets  []*GreatDataset `json:"datasets"`
+}
+
+type GreatRegion struct {
+	Location *dna.Location        `json:"location"`
+	Name     string               `json:"name,omitempty"`
+	Strand   string               `json:"strand,omitempty"`
+	Genes    []*great.Association `json:"genes"`
+}
+
+type GreatResp struct {
+	Terms   []*great.TermResult `json:"terms"`
+	Regions []*GreatRegion      `json:"regions"`
+}
+
+// The TSS of every gene of an assembly is read from its gene db
+// the first time it is tested, since domains depend on all genes.
+// The lock only guards the map so requests for genes that are
+// already read are not held up by one reading the gene db.
+var greatGenes = struct {
+	sync.Mutex
+	genes map[string][]*great.Gene
+}{genes: make(map[string][]*great.Gene)}
+
+func tssGenes(db *genome.GeneDB, assembly string, geneType string) ([]*great.Gene, error) {
+	key := assembly + ":" + geneType
+
+	greatGenes.Lock()
+	genes, ok := greatGenes.genes[key]
+	greatGenes.Unlock()
+
+	if ok {
+		return genes, nil
+	}
+
+	sizes, err := dnaroutes.ChromSizes(assembly)
+
+	if err != nil {
+		return nil, err
+	}
+
+	genes = make([]*great.Gene, 0, 20000)
+
+	for _, size := range sizes {
+		features, err := db.WithinGenes(dna.NewLocation(size.Name, 1, size.Len), genome.LEVEL_GENE)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, feature := range features.Features {
+			if geneType != "" && feature.GeneType != geneType {
+				continue
+			}
+
+			tss := feature.Location.Start
+
+			if feature.Strand == "-" {
+				tss = feature.Location.End
+			}
+
+			genes = append(genes, &great.Gene{Id: feature.GeneId,
+				Symbol: feature.GeneSymbol,
+				Chr:    feature.Location.Chr,
+				TSS:    tss,
+				Strand: feature.Strand})
+		}
+	}
+
+	greatGenes.Lock()
+	defer greatGenes.Unlock()
+
+	// keep the first copy if requests read the genes at once
+	if existing, ok := greatGenes.genes[key]; ok {
+		return existing, nil
+	}
+
+	greatGenes.genes[key] = genes
+
+	return genes, nil
+}
+
+func parseExtension(c *gin.Context) (uint, error) {
+	v := c.Query("extension")
+
+	if v == "" {
+		return great.DEFAULT_EXTENSION, nil
+	}
+
+	n, err := strconv.ParseUint(v, 10, 0)
+
+	if err != nil {
+		return 0, fmt.Errorf("%s is an invalid extension", v)
+	}
+
+	return uint(n), nil
+}
+
+// Test regions for enrichment near the genes of pathways, GREAT
+// style, by giving each gene a basal plus extension regulatory
+// domain and associating regions with the domains they fall in
+func GreatRoute(c *gin.Context) {
+	assembly := c.Param("assembly")
+
+	var req ReqGreat
+
+	err := c.ShouldBindJSON(&req)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if len(req.Datasets) == 0 {
+		web.BadReqResp(c, "must supply at least 1 dataset")
+		return
+	}
+
+	regions, err := dnaroutes.ParseRegions(c, req.Locations, req.Bed)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if len(regions) == 0 {
+		web.BadReqResp(c, "must supply at least 1 location")
+		return
+	}
+
+	extension, err := parseExtension(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	basal := parseTSSRegion(c, dna.NewTSSRegion(great.DEFAULT_BASAL_UPSTREAM, great.DEFAULT_BASAL_DOWNSTREAM))
+
+	n := web.ParseN(c, DEFAULT_GREAT_TERMS)
+
+	query, err := parseGeneQuery(c, assembly)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	span := tracing.Start(c, "genes.tssGenes")
+	genes, err := tssGenes(query.Db, assembly, query.GeneType)
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	sizes, err := dnaroutes.ChromSizes(assembly)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	datasets := make([]*great.Dataset, 0, len(req.Datasets))
+
+	for _, d := range req.Datasets {
+		span := tracing.Start(c, "pathwaydbcache.MakePublicDataset")
+		dataset, err := pathwaydbcache.MakePublicDataset(d.Organization, d.Name)
+		tracing.End(span, err)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		terms := make([]*great.Term, 0, len(dataset.Pathways))
+
+		for _, pathway := range dataset.Pathways {
+			terms = append(terms, &great.Term{Name: pathway.Name, Genes: pathway.Genes})
+		}
+
+		datasets = append(datasets, &great.Dataset{Name: d.Name, Terms: terms})
+	}
+
+	domains := great.NewDomains(genes, sizes, basal, extension)
+
+	resp := GreatResp{Regions: make([]*GreatRegion, 0, len(regions))}
+
+	associations := make([][]*great.Association, 0, len(regions))
+
+	for ri, region := range regions {
+		genes := domains.Associate(region.Location)
+
+		associations = append(associations, genes)
+
+		resp.Regions = append(resp.Regions, &GreatRegion{Location: region.Location,
+			Name:   region.Name,
+			Strand: region.Strand,
+			Genes:  genes})
+
+		jobs.SetProgress(c, ri+1, len(regions))
+	}
+
+	span = tracing.Start(c, "great.Enrichment")
+	terms := great.Enrichment(domains, associations, datasets)
+	tracing.End(span, nil)
+
+	// only terms near at least one region are of interest
+	resp.Terms = make([]*great.TermResult, 0, n)
+
+	for _, term := range terms {
+		if len(resp.Terms) == int(n) {
+			break
+		}
+
+		if term.Regions > 0 {
+			resp.Terms = append(resp.Terms, term)
+		}
+	}
+
+	web.MakeDataResp(c, "", &resp)
+}
@@ -0,0 +1,258 @@
+package genes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-genome"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ID_TYPE_SYMBOL             = "symbol"
+	ID_TYPE_ENSEMBL_GENE       = "ensembl_gene"
+	ID_TYPE_ENSEMBL_TRANSCRIPT = "ensembl_transcript"
+	ID_TYPE_ENTREZ             = "entrez"
+)
+
+const (
+	LOOKUP_MATCHED   = "matched"
+	LOOKUP_AMBIGUOUS = "ambiguous"
+	LOOKUP_UNMATCHED = "unmatched"
+)
+
+// Identifiers looked up per request, unless running as a job
+const MAX_LOOKUP_IDS = 5000
+
+// Genes returned by a search that are checked for a match
+const MAX_LOOKUP_SEARCH uint16 = 20
+
+var ensemblGeneId = regexp.MustCompile(`^ENS[A-Z]*G\d+(\.\d+)?$`)
+var ensemblTranscriptId = regexp.MustCompile(`^ENS[A-Z]*T\d+(\.\d+)?$`)
+var entrezId = regexp.MustCompile(`^\d+$`)
+
+// Identifiers of mixed types, such as a column from a spreadsheet
+type ReqLookup struct {
+	Ids []string `json:"ids"`
+}
+
+type TranscriptRecord struct {
+	Id       string        `json:"id"`
+	Location *dna.Location `json:"location"`
+}
+
+type GeneRecord struct {
+	GeneId     string            `json:"geneId"`
+	GeneSymbol string            `json:"geneSymbol"`
+	GeneType   string            `json:"geneType"`
+	Location   *dna.Location     `json:"location"`
+	Strand     string            `json:"strand"`
+	Canonical  *TranscriptRecord `json:"canonical,omitempty"`
+}
+
+// An identifier and the genes it resolves to. Ambiguous identifiers
+// list every gene they could be, and unmatched ones none, but may
+// have candidates that the search found but that are not exactly
+// what was asked for.
+type LookupResp struct {
+	Id         string        `json:"id"`
+	Type       string        `json:"type"`
+	Status     string        `json:"status"`
+	Reason     string        `json:"reason,omitempty"`
+	Genes      []*GeneRecord `json:"genes"`
+	Candidates []*GeneRecord `json:"candidates,omitempty"`
+}
+
+func idType(id string) string {
+	switch {
+	case ensemblGeneId.MatchString(strings.ToUpper(id)):
+		return ID_TYPE_ENSEMBL_GENE
+	case ensemblTranscriptId.MatchString(strings.ToUpper(id)):
+		return ID_TYPE_ENSEMBL_TRANSCRIPT
+	case entrezId.MatchString(id):
+		return ID_TYPE_ENTREZ
+	default:
+		return ID_TYPE_SYMBOL
+	}
+}
+
+// Ensembl ids are compared without their versions, such as
+// ENSG00000113916.18
+func unversioned(id string) string {
+	return strings.ToUpper(strings.Split(id, ".")[0])
+}
+
+// If a gene is exactly what an identifier of a type refers to
+func isExactMatch(feature *genome.GenomicFeature, id string, t string) bool {
+	switch t {
+	case ID_TYPE_ENSEMBL_GENE:
+		return unversioned(feature.GeneId) == unversioned(id)
+	case ID_TYPE_ENSEMBL_TRANSCRIPT:
+		for _, transcript := range feature.Children {
+			if unversioned(transcript.TranscriptId) == unversioned(id) {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return strings.EqualFold(feature.GeneSymbol, id)
+	}
+}
+
+func makeGeneRecord(feature *genome.GenomicFeature) *GeneRecord {
+	ret := GeneRecord{GeneId: feature.GeneId,
+		GeneSymbol: feature.GeneSymbol,
+		GeneType:   feature.GeneType,
+		Location:   feature.Location,
+		Strand:     feature.Strand}
+
+	transcripts := make([]*genome.GenomicFeature, 0, len(feature.Children))
+
+	for _, child := range feature.Children {
+		if child.Level == genome.LEVEL_TRANSCRIPT {
+			transcripts = append(transcripts, child)
+		}
+	}
+
+	for _, transcript := range transcripts {
+		// a gene with one transcript has no choice of canonical
+		if transcript.IsCanonical || len(transcripts) == 1 {
+			ret.Canonical = &TranscriptRecord{Id: transcript.TranscriptId, Location: transcript.Location}
+			break
+		}
+	}
+
+	return &ret
+}
+
+// Genes exactly matching an identifier, or if there are none, the
+// genes a search for it finds as candidates
+func lookupGene(c *gin.Context, query *GeneQuery, v string) (*LookupResp, error) {
+	ret := LookupResp{Id: v,
+		Status:     LOOKUP_UNMATCHED,
+		Genes:      make([]*GeneRecord, 0, 1),
+		Candidates: make([]*GeneRecord, 0)}
+
+	id := strings.TrimSpace(v)
+
+	if id == "" {
+		return &ret, nil
+	}
+
+	ret.Type = idType(id)
+
+	searches := []string{id}
+	t := ret.Type
+
+	switch ret.Type {
+	case ID_TYPE_ENSEMBL_GENE, ID_TYPE_ENSEMBL_TRANSCRIPT:
+		searches[0] = unversioned(id)
+	case ID_TYPE_ENTREZ:
+		// a search for the number would only find unrelated genes
+		// so the genes it maps to are looked up instead
+		genes, err := entrezToEnsembl(c.Param("assembly"), id)
+
+		if err != nil {
+			ret.Reason = err.Error()
+			return &ret, nil
+		}
+
+		if len(genes) == 0 {
+			ret.Reason = "unknown entrez id"
+			return &ret, nil
+		}
+
+		searches = genes
+		t = ID_TYPE_ENSEMBL_GENE
+	}
+
+	features := make([]*genome.GenomicFeature, 0, MAX_LOOKUP_SEARCH)
+	exact := make([]*genome.GenomicFeature, 0, len(searches))
+
+	for _, search := range searches {
+		span := tracing.Start(c, "genome.GeneDB.SearchForGeneByName")
+		found, err := query.Db.SearchForGeneByName(search, genome.LEVEL_TRANSCRIPT, MAX_LOOKUP_SEARCH, false, query.Canonical, query.GeneType)
+		tracing.End(span, err)
+
+		if err != nil {
+			return nil, err
+		}
+
+		features = append(features, found...)
+
+		for _, feature := range found {
+			if isExactMatch(feature, search, t) {
+				exact = append(exact, feature)
+			}
+		}
+	}
+
+	switch {
+	case len(exact) == 1:
+		ret.Status = LOOKUP_MATCHED
+	case len(exact) > 1:
+		ret.Status = LOOKUP_AMBIGUOUS
+	default:
+		// only partial matches, such as other genes whose
+		// names contain the search, so nothing matched
+		for _, feature := range features {
+			ret.Candidates = append(ret.Candidates, makeGeneRecord(feature))
+		}
+	}
+
+	for _, feature := range exact {
+		ret.Genes = append(ret.Genes, makeGeneRecord(feature))
+	}
+
+	return &ret, nil
+}
+
+// Resolve symbols, Ensembl gene and transcript ids and Entrez ids, in
+// any mix, to genes. Each identifier is reported in the order given,
+// including blank ones, so results line up with the list sent.
+func LookupGenesRoute(c *gin.Context) {
+	var req ReqLookup
+
+	err := c.ShouldBindJSON(&req)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !jobs.IsJob(c) && len(req.Ids) > MAX_LOOKUP_IDS {
+		web.BadReqResp(c, fmt.Sprintf("at most %d ids can be looked up, submit larger lists as a job", MAX_LOOKUP_IDS))
+		return
+	}
+
+	query, err := parseGeneQuery(c, c.Param("assembly"))
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret := make([]*LookupResp, 0, len(req.Ids))
+
+	for ii, id := range req.Ids {
+		resp, err := lookupGene(c, query, id)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		ret = append(ret, resp)
+
+		jobs.SetProgress(c, ii+1, len(req.Ids))
+	}
+
+	tabular.MakeResp(c, ret)
+}
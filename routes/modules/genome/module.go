@@ -0,0 +1,129 @@
+package genes
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-genome/genomedbcache"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "genome"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_USER
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	genomedbcache.InitCache(m.path)
+
+	initEntrez(filepath.Join(m.path, ENTREZ_DIR))
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/genomes", Handler: GenomesRoute, Cache: true,
+			Doc: &openapi.Doc{Summary: "List available gene databases"}},
+		{Method: http.MethodPost, Path: "/within/:assembly", Handler: WithinGenesRoute,
+			Doc: &openapi.Doc{Summary: "Genes within locations",
+				Request: dnaroutes.ReqLocs{},
+				Query:   []*openapi.Param{dnaroutes.BedBaseParam, dnaroutes.LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/closest/:assembly", Handler: ClosestGeneRoute,
+			Doc: &openapi.Doc{Summary: "Closest genes to locations",
+				Request: dnaroutes.ReqLocs{},
+				Query:   []*openapi.Param{dnaroutes.BedBaseParam, dnaroutes.LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/annotate/:assembly", Handler: AnnotateRoute,
+			Doc: &openapi.Doc{Summary: "Annotate locations with nearby genes",
+				Request: dnaroutes.ReqLocs{},
+				Query:   []*openapi.Param{dnaroutes.BedBaseParam, dnaroutes.LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/annotate/:assembly/summary", Handler: AnnotationSummaryRoute,
+			Doc: &openapi.Doc{Summary: "Summarize where locations are relative to genes",
+				Request:  dnaroutes.ReqLocs{},
+				Response: AnnotationSummary{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("tss", "promoter as bases upstream,downstream of the TSS, default 2000,1000"),
+					openapi.QueryParam("tes", "bases after a gene that are downstream of it, default 3000"),
+					openapi.QueryParam("bins", "ascending TSS distances separating the histogram bins"),
+					dnaroutes.BedBaseParam,
+					dnaroutes.LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/overlap/:assembly", Handler: OverlappingGenesRoute,
+			Doc: &openapi.Doc{Summary: "Genes overlapping locations",
+				Request:  dnaroutes.ReqLocs{},
+				Response: []*GenesResp{},
+				Query:    []*openapi.Param{dnaroutes.BedBaseParam, dnaroutes.LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/great/:assembly", Handler: GreatRoute,
+			Doc: &openapi.Doc{Summary: "Test regions for pathway enrichment using gene regulatory domains",
+				Request:  ReqGreat{},
+				Response: GreatResp{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("tss", "basal domain as bases upstream,downstream of the TSS, default 5000,1000"),
+					openapi.QueryParam("extension", "most bases a domain is extended by, default 1000000"),
+					openapi.QueryParam("type", "protein to only use protein coding genes"),
+					openapi.QueryParam("n", "number of terms to return"),
+					dnaroutes.BedBaseParam,
+					dnaroutes.LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/export/:assembly", Handler: ExportGenesRoute,
+			Doc: &openapi.Doc{Summary: "Export the gene models of locations as GTF, GFF3 or BED12",
+				Request: dnaroutes.ReqLocs{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("format", "gtf (default), gff3 or bed12"),
+					openapi.QueryParam("level", "gene, transcript or exon (default)"),
+					openapi.QueryParam("canonical", "only export canonical transcripts"),
+					openapi.QueryParam("type", "protein to only export protein coding genes"),
+					openapi.QueryParam("within", "only export genes entirely within a location"),
+					dnaroutes.BedBaseParam,
+					dnaroutes.LiftoverParam}}},
+		{Method: http.MethodGet, Path: "/export/:assembly", Handler: ExportGenesRoute,
+			Doc: &openapi.Doc{Summary: "Export the gene models of genes found by name as GTF, GFF3 or BED12",
+				Query: []*openapi.Param{
+					openapi.QueryParam("search", "gene symbol or id"),
+					openapi.QueryParam("format", "gtf (default), gff3 or bed12"),
+					openapi.QueryParam("level", "gene, transcript or exon (default)"),
+					openapi.QueryParam("canonical", "only export canonical transcripts"),
+					openapi.QueryParam("type", "protein to only export protein coding genes")}}},
+		{Method: http.MethodPost, Path: "/lookup/:assembly", Handler: LookupGenesRoute,
+			Doc: &openapi.Doc{Summary: "Look up genes by symbol, Ensembl id or Entrez id",
+				Request:  ReqLookup{},
+				Response: []*LookupResp{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("canonical", "only match canonical transcripts"),
+					openapi.QueryParam("type", "protein to only match protein coding genes"),
+					openapi.QueryParam("format", "json (default), tsv, csv or ndjson")}}},
+		{Method: http.MethodGet, Path: "/info/:assembly", Handler: SearchForGeneByNameRoute,
+			Doc: &openapi.Doc{Summary: "Search for genes by name",
+				Query: []*openapi.Param{openapi.QueryParam("search", "gene symbol or id")}}},
+	}
+}
+
+func (m *Module) Health() error {
+	err := modules.CheckPath(m.path)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = genomedbcache.GetInstance().List()
+
+	return err
+}
+
+func (m *Module) Close() error {
+	return genomedbcache.Close()
+}
@@ -0,0 +1,215 @@
+package genes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/annotation"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-genome"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+)
+
+// Summaries are small so more regions can be summarized than
+// annotated in one request
+const MAX_SUMMARY_REGIONS = 100000
+
+var DEFAULT_TSS_BINS = []int{-100000, -10000, -5000, -1000, 0, 1000, 5000, 10000, 100000}
+
+type CategoryCount struct {
+	Category string  `json:"category"`
+	Count    int     `json:"count"`
+	Fraction float64 `json:"fraction"`
+}
+
+type TSSDistBin struct {
+	Label    string  `json:"label"`
+	Count    int     `json:"count"`
+	Fraction float64 `json:"fraction"`
+}
+
+type ChrCount struct {
+	Chr   string `json:"chr"`
+	Count int    `json:"count"`
+}
+
+type AnnotationSummary struct {
+	Regions    int              `json:"regions"`
+	Promoter   string           `json:"promoter"`
+	Categories []*CategoryCount `json:"categories"`
+	// distance from the middle of each region to its closest TSS
+	TSSDists []*TSSDistBin `json:"tssDists"`
+	Chrs     []*ChrCount   `json:"chrs"`
+}
+
+func fraction(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(n) / float64(total)
+}
+
+// Read ?bins= as ascending, comma separated TSS distances that
+// separate the histogram bins
+func parseTSSBins(c *gin.Context) ([]int, error) {
+	v := c.Query("bins")
+
+	if v == "" {
+		return DEFAULT_TSS_BINS, nil
+	}
+
+	tokens := strings.Split(v, ",")
+
+	ret := make([]int, 0, len(tokens))
+
+	for _, t := range tokens {
+		n, err := strconv.Atoi(strings.TrimSpace(t))
+
+		if err != nil {
+			return nil, fmt.Errorf("%s is an invalid bin", t)
+		}
+
+		if len(ret) > 0 && n <= ret[len(ret)-1] {
+			return nil, fmt.Errorf("bins must be in ascending order")
+		}
+
+		ret = append(ret, n)
+	}
+
+	return ret, nil
+}
+
+// Labels of the bins either side of and between the edges
+func tssBinLabels(edges []int) []string {
+	ret := make([]string, 0, len(edges)+1)
+
+	ret = append(ret, fmt.Sprintf("<%d", edges[0]))
+
+	for i := 1; i < len(edges); i++ {
+		ret = append(ret, fmt.Sprintf("%d to %d", edges[i-1], edges[i]))
+	}
+
+	return append(ret, fmt.Sprintf(">=%d", edges[len(edges)-1]))
+}
+
+func tssBin(edges []int, dist int) int {
+	for i, edge := range edges {
+		if dist < edge {
+			return i
+		}
+	}
+
+	return len(edges)
+}
+
+// Summarize where regions are relative to genes for peak QC: the
+// fraction labelled promoter, exonic, intronic, downstream and
+// intergenic, as by annotate, a histogram of distances to the closest TSS and
+// counts per chromosome
+func AnnotationSummaryRoute(c *gin.Context) {
+	regions, err := dnaroutes.ParseRegionsFromPost(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if !jobs.IsJob(c) && len(regions) > MAX_SUMMARY_REGIONS {
+		web.BadReqResp(c, fmt.Sprintf("at most %d regions can be summarized, submit larger sets as a job", MAX_SUMMARY_REGIONS))
+		return
+	}
+
+	bins, err := parseTSSBins(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	downstream, err := parseDownstreamDist(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	query, err := parseGeneQuery(c, c.Param("assembly"))
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	tssRegion := ParseTSSRegion(c)
+
+	annotator := annotation.NewDbAnnotator(func(location *dna.Location) ([]*genome.GenomicFeature, error) {
+		span := tracing.Start(c, "genome.GeneDB.OverlappingGenes")
+		features, err := query.Db.OverlappingGenes(location, query.Canonical, query.GeneType)
+		tracing.End(span, err)
+
+		return features, err
+	}, annotation.NewClassifier(tssRegion, downstream), 1)
+
+	categories := make([]int, len(annotation.LABELS))
+	distBins := make([]int, len(bins)+1)
+	distTotal := 0
+
+	chrCounts := make(map[string]*ChrCount)
+	chrs := make([]*ChrCount, 0, 30)
+
+	for ri, region := range regions {
+		location := region.Location
+
+		regionAnnotation, err := annotator.Annotate(location)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		categories[annotation.Rank(annotation.RegionLabel(regionAnnotation))]++
+
+		if len(regionAnnotation.ClosestGenes) > 0 {
+			distBins[tssBin(bins, regionAnnotation.ClosestGenes[0].TssDist)]++
+			distTotal++
+		}
+
+		chr, ok := chrCounts[location.Chr]
+
+		if !ok {
+			chr = &ChrCount{Chr: location.Chr}
+			chrCounts[location.Chr] = chr
+			chrs = append(chrs, chr)
+		}
+
+		chr.Count++
+
+		jobs.SetProgress(c, ri+1, len(regions))
+	}
+
+	ret := AnnotationSummary{Regions: len(regions),
+		Promoter:   fmt.Sprintf("-%d/+%d", tssRegion.Offset5P(), tssRegion.Offset3P()),
+		Categories: make([]*CategoryCount, 0, len(annotation.LABELS)),
+		TSSDists:   make([]*TSSDistBin, 0, len(distBins)),
+		Chrs:       chrs}
+
+	for ci, name := range annotation.LABELS {
+		ret.Categories = append(ret.Categories, &CategoryCount{Category: name,
+			Count:    categories[ci],
+			Fraction: fraction(categories[ci], len(regions))})
+	}
+
+	for bi, label := range tssBinLabels(bins) {
+		ret.TSSDists = append(ret.TSSDists, &TSSDistBin{Label: label,
+			Count:    distBins[bi],
+			Fraction: fraction(distBins[bi], distTotal)})
+	}
+
+	web.MakeDataResp(c, "", &ret)
+}
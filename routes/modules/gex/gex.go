@@ -1,6 +1,8 @@
 package gex
 
 import (
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-gex"
 	"github.com/antonybholmes/go-gex/gexdbcache"
 	"github.com/antonybholmes/go-web"
@@ -30,7 +32,9 @@ func parseParamsFromPost(c *gin.Context) (*GexParams, error) {
 
 func SpeciesRoute(c *gin.Context) {
 
+	span := tracing.Start(c, "gexdbcache.Species")
 	types, err := gexdbcache.Species()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -51,7 +55,9 @@ func TechnologiesRoute(c *gin.Context) {
 	// 	return
 	// }
 
+	span := tracing.Start(c, "gexdbcache.Technologies")
 	technologies := gexdbcache.Technologies() //gexdbcache.Technologies()
+	tracing.End(span, nil)
 
 	web.MakeDataResp(c, "", technologies)
 }
@@ -81,7 +87,9 @@ func GexDatasetsRoute(c *gin.Context) {
 
 	technology := c.Param("technology")
 
+	span := tracing.Start(c, "gexdbcache.Datasets")
 	datasets, err := gexdbcache.Datasets(species, technology)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -101,24 +109,28 @@ func GexGeneExpRoute(c *gin.Context) {
 
 	if params.Technology == gex.MICROARRAY_TECHNOLOGY {
 		// microarray
+		span := tracing.Start(c, "gexdbcache.FindMicroarrayValues")
 		ret, err := gexdbcache.FindMicroarrayValues(params.Datasets, params.Genes)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		web.MakeDataResp(c, "", ret)
+		tabular.MakeResp(c, ret)
 	} else {
 		// default to rna-seq
+		span := tracing.Start(c, "gexdbcache.FindRNASeqValues")
 		ret, err := gexdbcache.FindRNASeqValues(params.Datasets, params.GexType, params.Genes)
+		tracing.End(span, err)
 
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		web.MakeDataResp(c, "", ret)
+		tabular.MakeResp(c, ret)
 	}
 }
 
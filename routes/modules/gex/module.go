@@ -0,0 +1,60 @@
+package gex
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	"github.com/antonybholmes/go-gex/gexdbcache"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "gex"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_RDF
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	gexdbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/species", Handler: SpeciesRoute, Cache: true},
+		{Method: http.MethodGet, Path: "/technologies", Handler: TechnologiesRoute, Cache: true},
+		{Method: http.MethodGet, Path: "/datasets/:species/:technology", Handler: GexDatasetsRoute, Protected: true},
+		{Method: http.MethodPost, Path: "/exp", Handler: GexGeneExpRoute, Protected: true,
+			Doc: &openapi.Doc{Summary: "Gene expression for genes in datasets", Request: GexParams{}}},
+	}
+}
+
+func (m *Module) Health() error {
+	err := modules.CheckPath(m.path)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = gexdbcache.Species()
+
+	return err
+}
+
+func (m *Module) Close() error {
+	return gexdbcache.Close()
+}
@@ -1,6 +1,7 @@
 package gex
 
 import (
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-hubs/hubsdbcache"
 	"github.com/antonybholmes/go-web"
 	"github.com/gin-gonic/gin"
@@ -10,7 +11,9 @@ func HubsRoute(c *gin.Context) {
 
 	assembly := c.Param("assembly")
 
+	span := tracing.Start(c, "hubsdbcache.Hubs")
 	hubs, err := hubsdbcache.Hubs(assembly)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
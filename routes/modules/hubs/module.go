@@ -0,0 +1,47 @@
+package gex
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	"github.com/antonybholmes/go-hubs/hubsdbcache"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "hubs"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_RDF
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	hubsdbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/:assembly", Handler: HubsRoute, Protected: true, Cache: true},
+	}
+}
+
+func (m *Module) Health() error {
+	return modules.CheckPath(m.path)
+}
+
+func (m *Module) Close() error {
+	return hubsdbcache.Close()
+}
@@ -0,0 +1,50 @@
+package liftover
+
+import (
+	"github.com/antonybholmes/go-edb-server-gin/liftover"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+type LiftoverResp struct {
+	Name string `json:"name,omitempty"`
+	*liftover.Result
+}
+
+func LiftoverRoute(c *gin.Context) {
+	regions, err := dnaroutes.ParseRegionsFromPost(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	minMatch, err := liftover.ParseMinMatch(c)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	span := tracing.Start(c, "liftover.Lift")
+	results, err := liftover.Lift(c.Param("from"),
+		c.Param("to"),
+		dnaroutes.RegionLocations(regions),
+		minMatch)
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret := make([]*LiftoverResp, len(results))
+
+	for ri, result := range results {
+		ret[ri] = &LiftoverResp{Name: regions[ri].Name, Result: result}
+	}
+
+	tabular.MakeResp(c, ret)
+}
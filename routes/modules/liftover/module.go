@@ -0,0 +1,51 @@
+package liftover
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/liftover"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "liftover"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_USER
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	liftover.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodPost, Path: "/:from/:to", Handler: LiftoverRoute,
+			Doc: &openapi.Doc{Summary: "Lift locations or BED regions between assemblies",
+				Request:  dnaroutes.ReqLocs{},
+				Response: []*LiftoverResp{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("minMatch", "fraction of bases that must map, 0.95 by default"),
+					dnaroutes.BedBaseParam}}},
+	}
+}
+
+func (m *Module) Health() error {
+	return modules.CheckPath(m.path)
+}
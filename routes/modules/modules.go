@@ -0,0 +1,239 @@
+package modules
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/antonybholmes/go-edb-server-gin/cache"
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Roles a module can require for its protected routes
+const (
+	ROLE_USER = "user"
+	ROLE_RDF  = "rdf"
+)
+
+// A route served by a module. Paths are relative to
+// /modules/<name>
+type Route struct {
+	Method  string
+	Path    string
+	Handler gin.HandlerFunc
+	// protected routes must pass the auth chain for the
+	// role the module requires
+	Protected bool
+	// responses depend only on the request and the module
+	// data so can be cached
+	Cache bool
+	// optional description of the payloads for the
+	// OpenAPI spec
+	Doc *openapi.Doc
+}
+
+// A data module such as dna or genome. Modules register themselves
+// in an init function so that the server picks them up simply by
+// importing the package.
+type Module interface {
+	// Name is used for the route prefix and to look up the
+	// module in the config
+	Name() string
+
+	// Role required to access protected routes
+	Role() string
+
+	// Open the module data using its config
+	Init(cfg *config.Config) error
+
+	Routes() []*Route
+
+	// Returns an error if the module cannot serve requests,
+	// e.g. its data is missing
+	Health() error
+}
+
+// Builds the middleware chain that checks a user has the
+// given role
+type RoleMiddleware func(role string) gin.HandlersChain
+
+var (
+	mu       sync.Mutex
+	registry = make(map[string]Module)
+
+	// modules that are enabled and the reason they failed
+	// to init, if any
+	enabled = make(map[string]error)
+)
+
+func Register(module Module) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	name := module.Name()
+
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("module %s already registered", name))
+	}
+
+	registry[name] = module
+}
+
+// Returns all registered modules sorted by name
+func Registered() []Module {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ret := make([]Module, 0, len(registry))
+
+	for _, module := range registry {
+		ret = append(ret, module)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name() < ret[j].Name()
+	})
+
+	return ret
+}
+
+// Initialize every enabled module and add its routes to the group.
+// Modules that fail to initialize are logged and skipped so one
+// missing dataset does not take down the whole server. Returns
+// the modules that are being served.
+func Setup(group *gin.RouterGroup, cfg *config.Config, roleMiddleware RoleMiddleware) []Module {
+	ret := make([]Module, 0, len(registry))
+
+	for _, module := range Registered() {
+		name := module.Name()
+
+		if !cfg.IsEnabled(name) {
+			log.Info().Msgf("module %s disabled", name)
+			continue
+		}
+
+		err := module.Init(cfg)
+
+		mu.Lock()
+		enabled[name] = err
+		mu.Unlock()
+
+		if err != nil {
+			log.Error().Msgf("module %s failed to init: %s", name, err)
+			continue
+		}
+
+		moduleGroup := group.Group("/" + name)
+
+		for _, route := range module.Routes() {
+			handlers := gin.HandlersChain{}
+
+			if route.Protected {
+				handlers = append(handlers, roleMiddleware(module.Role())...)
+
+				openapi.SecureRoute(route.Method,
+					moduleGroup.BasePath()+route.Path,
+					openapi.RoleSecurity(module.Role()))
+			}
+
+			// cache after auth so only authorized users
+			// can read cached responses
+			if route.Cache {
+				handlers = append(handlers, cache.Middleware(name, cfg.ModulePath(name), route.Protected))
+			}
+
+			if route.Doc != nil {
+				openapi.DescribeRoute(route.Method, moduleGroup.BasePath()+route.Path, route.Doc)
+			}
+
+			handlers = append(handlers, route.Handler)
+
+			moduleGroup.Handle(route.Method, route.Path, handlers...)
+		}
+
+		log.Info().Msgf("module %s enabled", name)
+
+		ret = append(ret, module)
+	}
+
+	return ret
+}
+
+// Returns the names of the enabled modules, including those
+// that failed to init
+func Enabled() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ret := make([]string, 0, len(enabled))
+
+	for name := range enabled {
+		ret = append(ret, name)
+	}
+
+	sort.Strings(ret)
+
+	return ret
+}
+
+// Returns an error if an enabled module cannot serve requests
+func Check(name string) error {
+	mu.Lock()
+	err, ok := enabled[name]
+	module := registry[name]
+	mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("module %s is not enabled", name)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return module.Health()
+}
+
+// Release resources held by the enabled modules, such as their db
+// handles. Modules holding open handles should implement io.Closer.
+// Modules that failed to init have nothing to close.
+func Close() {
+	for _, name := range Enabled() {
+		mu.Lock()
+		module := registry[name]
+		err := enabled[name]
+		mu.Unlock()
+
+		if err != nil {
+			continue
+		}
+
+		closer, ok := module.(io.Closer)
+
+		if !ok {
+			continue
+		}
+
+		err = closer.Close()
+
+		if err != nil {
+			log.Error().Msgf("module %s failed to close: %s", name, err)
+		}
+	}
+}
+
+// Checks a module data file or directory exists
+func CheckPath(path string) error {
+	_, err := os.Stat(path)
+
+	if err != nil {
+		return fmt.Errorf("data not found: %s", path)
+	}
+
+	return nil
+}
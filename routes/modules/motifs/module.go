@@ -0,0 +1,58 @@
+package motifs
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	"github.com/antonybholmes/go-motifs/motifsdb"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "motifs"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_USER
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	motifsdb.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/datasets", Handler: DatasetsRoute, Cache: true},
+		{Method: http.MethodPost, Path: "/search", Handler: SearchRoute,
+			Doc: &openapi.Doc{Summary: "Search for motifs", Request: ReqParams{}, Response: MotifRes{}}},
+	}
+}
+
+func (m *Module) Health() error {
+	err := modules.CheckPath(m.path)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = motifsdb.Datasets()
+
+	return err
+}
+
+func (m *Module) Close() error {
+	return motifsdb.Close()
+}
@@ -1,6 +1,7 @@
 package motifs
 
 import (
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-motifs"
 	"github.com/antonybholmes/go-motifs/motifsdb"
 	"github.com/antonybholmes/go-web"
@@ -40,7 +41,9 @@ func ParseParamsFromPost(c *gin.Context) (*ReqParams, error) {
 func DatasetsRoute(c *gin.Context) {
 
 	// Don't care about the errors, just plug empty list into failures
+	span := tracing.Start(c, "motifsdb.Datasets")
 	datasets, err := motifsdb.Datasets()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -71,10 +74,12 @@ func SearchRoute(c *gin.Context) {
 	//log.Debug().Msgf("motif %v", params)
 
 	// Don't care about the errors, just plug empty list into failures
+	span := tracing.Start(c, "motifsdb.Search")
 	motifs, err := motifsdb.Search(search, params.Reverse, params.Complement)
+	tracing.End(span, err)
 
 	if err != nil {
-		log.Debug().Msgf("motif %s", err)
+		log.Ctx(c.Request.Context()).Debug().Msgf("motif %s", err)
 		c.Error(err)
 		return
 	}
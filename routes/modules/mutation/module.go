@@ -0,0 +1,63 @@
+package mutations
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-mutations/mutationdbcache"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "mutations"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_RDF
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	mutationdbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/datasets/:assembly", Handler: MutationDatasetsRoute, Cache: true},
+		{Method: http.MethodPost, Path: "/:assembly/:name", Handler: MutationsRoute,
+			Doc: &openapi.Doc{Summary: "Mutations in locations", Request: ReqMutationParams{},
+				Query: []*openapi.Param{dnaroutes.LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/maf/:assembly", Handler: PileupRoute},
+		{Method: http.MethodPost, Path: "/seq/:assembly", Handler: VariantSeqRoute,
+			Doc: &openapi.Doc{Summary: "Sequence of locations with a sample's mutations or VCF variants applied",
+				Request:  ReqVariantSeq{},
+				Response: []*VariantSeqResp{},
+				Query: []*openapi.Param{
+					openapi.QueryParam("haplotype", "ref, alt or both (default)"),
+					dnaroutes.LiftoverParam}}},
+		{Method: http.MethodPost, Path: "/pileup/:assembly", Handler: PileupRoute, Protected: true,
+			Doc: &openapi.Doc{Summary: "Mutation pileup for locations", Request: ReqMutationParams{},
+				Query: []*openapi.Param{dnaroutes.LiftoverParam}}},
+	}
+}
+
+func (m *Module) Health() error {
+	return modules.CheckPath(m.path)
+}
+
+func (m *Module) Close() error {
+	return mutationdbcache.Close()
+}
@@ -2,7 +2,10 @@ package mutations
 
 import (
 	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/liftover"
 	authenticationroutes "github.com/antonybholmes/go-edb-server-gin/routes/authentication"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-mutations"
 	"github.com/antonybholmes/go-mutations/mutationdbcache"
 	"github.com/antonybholmes/go-web"
@@ -36,6 +39,12 @@ func ParseParamsFromPost(c *gin.Context) (*MutationParams, error) {
 		return nil, err
 	}
 
+	locations, err = liftover.AutoLiftLocations(c, locations)
+
+	if err != nil {
+		return nil, err
+	}
+
 	return &MutationParams{locations, locs.Datasets}, nil
 }
 
@@ -43,7 +52,9 @@ func MutationDatasetsRoute(c *gin.Context) {
 
 	assembly := c.Param("assembly")
 
+	span := tracing.Start(c, "mutationdbcache.List")
 	datasets, err := mutationdbcache.List(assembly)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -65,9 +76,11 @@ func MutationsRoute(c *gin.Context) {
 
 	location := params.Locations[0]
 
+	span := tracing.Start(c, "mutationdbcache.Search")
 	search, err := mutationdbcache.GetInstance().Search(assembly,
 		location,
 		params.Datasets)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -90,7 +103,7 @@ func MutationsRoute(c *gin.Context) {
 	// 	ret[i] = mutations
 	// }
 
-	web.MakeDataResp(c, "", search)
+	tabular.MakeResp(c, search)
 
 	//web.MakeDataResp(c, "", mutationdbcache.GetInstance().List())
 }
@@ -204,7 +217,7 @@ func PileupRoute(c *gin.Context) {
 			return
 		}
 
-		log.Debug().Msgf("pileup: %v", params)
+		log.Ctx(c.Request.Context()).Debug().Msgf("pileup: %v", params)
 
 		location := params.Locations[0]
 
@@ -220,12 +233,14 @@ func PileupRoute(c *gin.Context) {
 		// 	ret.Mutations[i] = make([]*mutations.Mutation, 0, 10)
 		// }
 
+		span := tracing.Start(c, "mutationdbcache.Search")
 		search, err := mutationdbcache.GetInstance().Search(assembly,
 			location,
 			params.Datasets)
+		tracing.End(span, err)
 
 		if err != nil {
-			log.Debug().Msgf("here 1 %s", err)
+			log.Ctx(c.Request.Context()).Debug().Msgf("here 1 %s", err)
 			c.Error(err)
 			return
 		}
@@ -0,0 +1,172 @@
+package mutations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-dna/dnadbcache"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	"github.com/antonybholmes/go-edb-server-gin/liftover"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
+	"github.com/antonybholmes/go-edb-server-gin/variants"
+	"github.com/antonybholmes/go-mutations"
+	"github.com/antonybholmes/go-mutations/mutationdbcache"
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HAPLOTYPE_REF  = "ref"
+	HAPLOTYPE_ALT  = "alt"
+	HAPLOTYPE_BOTH = "both"
+)
+
+// Variants come from the mutations of a sample in datasets, from
+// VCF records, or both
+type ReqVariantSeq struct {
+	Locations []string `json:"locations"`
+	Datasets  []string `json:"datasets,omitempty"`
+	Sample    string   `json:"sample,omitempty"`
+	// VCF text, filtered to the sample's genotypes if it has them
+	Vcf string `json:"vcf,omitempty"`
+}
+
+type VariantSeqResp struct {
+	Location *dna.Location       `json:"location"`
+	Ref      string              `json:"ref,omitempty"`
+	Alt      *variants.Haplotype `json:"alt,omitempty"`
+}
+
+// Convert mutations, which use MAF style alleles, into variants.
+// MAF insertions are between Start and End so go before End.
+func mutationVariants(chr string, search *mutations.SearchResults, sample string) []*variants.Variant {
+	ret := make([]*variants.Variant, 0, len(search.Mutations))
+
+	for _, mutation := range search.Mutations {
+		if mutation.Sample != sample {
+			continue
+		}
+
+		start := mutation.Start
+
+		if mutation.Ref == "-" {
+			start++
+		}
+
+		variant := variants.NewVariant(chr, start, mutation.Ref, mutation.Tum)
+		variant.Sample = mutation.Sample
+
+		ret = append(ret, variant)
+	}
+
+	return ret
+}
+
+// Sequence of locations with SNVs and indels applied, along with a
+// map from reference to alt coordinates
+func VariantSeqRoute(c *gin.Context) {
+	assembly := c.Param("assembly")
+
+	var req ReqVariantSeq
+
+	err := c.ShouldBindJSON(&req)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	haplotype := c.DefaultQuery("haplotype", HAPLOTYPE_BOTH)
+
+	if haplotype != HAPLOTYPE_REF && haplotype != HAPLOTYPE_ALT && haplotype != HAPLOTYPE_BOTH {
+		web.BadReqResp(c, fmt.Sprintf("%s is an invalid haplotype", haplotype))
+		return
+	}
+
+	if len(req.Datasets) > 0 && req.Sample == "" {
+		web.BadReqResp(c, "a sample is needed to apply mutations from datasets")
+		return
+	}
+
+	locations, err := dna.ParseLocations(req.Locations)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// VCF records are not lifted so must already be in the assembly
+	locations, err = liftover.AutoLiftLocations(c, locations)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	vcf := make([]*variants.Variant, 0)
+
+	if req.Vcf != "" {
+		vcf, err = variants.ParseVCF(strings.NewReader(req.Vcf), req.Sample)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	span := tracing.Start(c, "dnadbcache.Db")
+	dnadb, err := dnadbcache.Db(assembly)
+	tracing.End(span, err)
+
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	ret := make([]*VariantSeqResp, 0, len(locations))
+
+	for li, location := range locations {
+		span := tracing.Start(c, "dna.DNADB.DNA")
+		seq, err := dnadb.DNA(location, "upper", "", false, false)
+		tracing.End(span, err)
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		resp := VariantSeqResp{Location: location}
+
+		if haplotype != HAPLOTYPE_ALT {
+			resp.Ref = seq
+		}
+
+		if haplotype != HAPLOTYPE_REF {
+			applied := vcf
+
+			if len(req.Datasets) > 0 {
+				span := tracing.Start(c, "mutationdbcache.Search")
+				search, err := mutationdbcache.GetInstance().Search(assembly,
+					location,
+					req.Datasets)
+				tracing.End(span, err)
+
+				if err != nil {
+					c.Error(err)
+					return
+				}
+
+				applied = append(mutationVariants(location.Chr, search, req.Sample), vcf...)
+			}
+
+			resp.Alt = variants.Apply(seq, location, applied)
+		}
+
+		ret = append(ret, &resp)
+
+		jobs.SetProgress(c, li+1, len(locations))
+	}
+
+	web.MakeDataResp(c, "", ret)
+}
@@ -0,0 +1,61 @@
+package pathway
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	"github.com/antonybholmes/go-pathway/pathwaydbcache"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "pathway"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_USER
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	pathwaydbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/genes", Handler: GenesRoute, Cache: true},
+		{Method: http.MethodPost, Path: "/dataset", Handler: DatasetRoute, Cache: true,
+			Doc: &openapi.Doc{Summary: "Get a pathway dataset", Request: ReqDatasetParams{}}},
+		{Method: http.MethodGet, Path: "/datasets", Handler: DatasetsRoute, Cache: true},
+		{Method: http.MethodPost, Path: "/overlap", Handler: PathwayOverlapRoute,
+			Doc: &openapi.Doc{Summary: "Test a geneset for pathway overlap", Request: ReqOverlapParams{}}},
+	}
+}
+
+func (m *Module) Health() error {
+	err := modules.CheckPath(m.path)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = pathwaydbcache.AllDatasetsInfo()
+
+	return err
+}
+
+func (m *Module) Close() error {
+	return pathwaydbcache.Close()
+}
@@ -1,6 +1,8 @@
 package pathway
 
 import (
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	pathway "github.com/antonybholmes/go-pathway"
 	"github.com/antonybholmes/go-pathway/pathwaydbcache"
 	"github.com/antonybholmes/go-web"
@@ -84,8 +86,10 @@ func DatasetRoute(c *gin.Context) {
 
 	//log.Debug().Msgf("params %v", params)
 
+	span := tracing.Start(c, "pathwaydbcache.MakePublicDataset")
 	datasets, err := pathwaydbcache.MakePublicDataset(params.Organization,
 		params.Name)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -97,7 +101,9 @@ func DatasetRoute(c *gin.Context) {
 
 func DatasetsRoute(c *gin.Context) {
 
+	span := tracing.Start(c, "pathwaydbcache.AllDatasetsInfo")
 	datasets, err := pathwaydbcache.AllDatasetsInfo()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -118,7 +124,9 @@ func PathwayOverlapRoute(c *gin.Context) {
 
 	testPathway := params.Geneset.ToPathway()
 
+	span := tracing.Start(c, "pathwaydbcache.Overlap")
 	tests, err := pathwaydbcache.Overlap(testPathway, params.Datasets)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -137,7 +145,7 @@ func PathwayOverlapRoute(c *gin.Context) {
 	// 	ret.Conversions = append(ret.Conversions, conversion)
 	// }
 
-	web.MakeDataResp(c, "", tests)
+	tabular.MakeResp(c, tests)
 
 	// web.MakeDataResp(c, "", mutationdbcache.GetInstance().List())
 }
@@ -0,0 +1,63 @@
+package scrna
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	"github.com/antonybholmes/go-scrna/scrnadbcache"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "scrna"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_RDF
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	scrnadbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/species", Handler: ScrnaSpeciesRoute, Cache: true},
+		{Method: http.MethodGet, Path: "/assemblies/:species", Handler: ScrnaAssembliesRoute, Cache: true},
+		{Method: http.MethodGet, Path: "/datasets/:species/:assembly", Handler: ScrnaDatasetsRoute, Protected: true},
+		{Method: http.MethodGet, Path: "/metadata/:id", Handler: ScrnaMetadataRoute, Protected: true},
+		{Method: http.MethodGet, Path: "/genes/:id", Handler: ScrnaGenesRoute, Protected: true},
+		{Method: http.MethodGet, Path: "/genes/search/:id", Handler: ScrnaSearchGenesRoute, Protected: true},
+		{Method: http.MethodPost, Path: "/gex/:id", Handler: ScrnaGexRoute, Protected: true,
+			Doc: &openapi.Doc{Summary: "Single cell expression for genes", Request: ScrnaParams{}}},
+	}
+}
+
+func (m *Module) Health() error {
+	err := modules.CheckPath(m.path)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = scrnadbcache.Species()
+
+	return err
+}
+
+func (m *Module) Close() error {
+	return scrnadbcache.Close()
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	"github.com/antonybholmes/go-scrna/scrnadbcache"
 	"github.com/antonybholmes/go-sys"
 	"github.com/antonybholmes/go-web"
@@ -32,7 +34,9 @@ func parseParamsFromPost(c *gin.Context) (*ScrnaParams, error) {
 
 func ScrnaSpeciesRoute(c *gin.Context) {
 
+	span := tracing.Start(c, "scrnadbcache.Species")
 	types, err := scrnadbcache.Species()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -53,7 +57,9 @@ func ScrnaAssembliesRoute(c *gin.Context) {
 	// 	return
 	// }
 
+	span := tracing.Start(c, "scrnadbcache.Assemblies")
 	assemblies, err := scrnadbcache.Assemblies(species) //gexdbcache.Technologies()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -87,7 +93,9 @@ func ScrnaDatasetsRoute(c *gin.Context) {
 	species := c.Param("species")
 	assembly := c.Param("assembly")
 
+	span := tracing.Start(c, "scrnadbcache.Datasets")
 	datasets, err := scrnadbcache.Datasets(species, assembly)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -114,14 +122,16 @@ func ScrnaGexRoute(c *gin.Context) {
 	}
 
 	// default to rna-seq
+	span := tracing.Start(c, "scrnadbcache.Gex")
 	ret, err := scrnadbcache.Gex(publicId, params.Genes)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	web.MakeDataResp(c, "", ret)
+	tabular.MakeResp(c, ret)
 }
 
 // func ScrnaMetadataRoute(c *gin.Context) {
@@ -150,7 +160,9 @@ func ScrnaMetadataRoute(c *gin.Context) {
 		return
 	}
 
+	span := tracing.Start(c, "scrnadbcache.Metadata")
 	ret, err := scrnadbcache.Metadata(publicId)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -168,7 +180,9 @@ func ScrnaGenesRoute(c *gin.Context) {
 		return
 	}
 
+	span := tracing.Start(c, "scrnadbcache.Genes")
 	ret, err := scrnadbcache.Genes(publicId)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -205,9 +219,11 @@ func ScrnaSearchGenesRoute(c *gin.Context) {
 
 	safeQuery := sys.SanitizeQuery(query)
 
-	log.Debug().Msgf("safe %s", safeQuery)
+	log.Ctx(c.Request.Context()).Debug().Msgf("safe %s", safeQuery)
 
+	span := tracing.Start(c, "scrnadbcache.SearchGenes")
 	ret, err := scrnadbcache.SearchGenes(publicId, safeQuery, limit)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
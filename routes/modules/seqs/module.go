@@ -0,0 +1,64 @@
+package seqs
+
+import (
+	"net/http"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/antonybholmes/go-edb-server-gin/openapi"
+	"github.com/antonybholmes/go-edb-server-gin/routes/modules"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-seqs/seqsdbcache"
+)
+
+type Module struct {
+	path string
+}
+
+func init() {
+	modules.Register(&Module{})
+}
+
+func (m *Module) Name() string {
+	return "seqs"
+}
+
+func (m *Module) Role() string {
+	return modules.ROLE_RDF
+}
+
+func (m *Module) Init(cfg *config.Config) error {
+	m.path = cfg.ModulePath(m.Name())
+
+	seqsdbcache.InitCache(m.path)
+
+	return nil
+}
+
+func (m *Module) Routes() []*modules.Route {
+	return []*modules.Route{
+		{Method: http.MethodGet, Path: "/genomes", Handler: GenomeRoute, Protected: true, Cache: true},
+		{Method: http.MethodGet, Path: "/platforms/:assembly", Handler: PlatformRoute, Protected: true, Cache: true},
+		{Method: http.MethodGet, Path: "/search/:assembly", Handler: SearchSeqRoute, Protected: true},
+		{Method: http.MethodPost, Path: "/bins", Handler: BinsRoute, Protected: true, Cache: true,
+			Doc: &openapi.Doc{Summary: "Binned track counts for locations",
+				Request:  ReqSeqParams{},
+				Response: []*SeqResp{},
+				Query:    []*openapi.Param{dnaroutes.BedBaseParam}}},
+	}
+}
+
+func (m *Module) Health() error {
+	err := modules.CheckPath(m.path)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = seqsdbcache.Genomes()
+
+	return err
+}
+
+func (m *Module) Close() error {
+	return seqsdbcache.Close()
+}
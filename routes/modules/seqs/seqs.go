@@ -1,7 +1,13 @@
 package seqs
 
 import (
+	"fmt"
+
 	"github.com/antonybholmes/go-dna"
+	"github.com/antonybholmes/go-edb-server-gin/jobs"
+	dnaroutes "github.com/antonybholmes/go-edb-server-gin/routes/modules/dna"
+	"github.com/antonybholmes/go-edb-server-gin/tabular"
+	"github.com/antonybholmes/go-edb-server-gin/tracing"
 	seq "github.com/antonybholmes/go-seqs"
 	"github.com/antonybholmes/go-seqs/seqsdbcache"
 	"github.com/antonybholmes/go-web"
@@ -10,21 +16,25 @@ import (
 )
 
 type ReqSeqParams struct {
-	Locations []string `json:"locations"`
-	Scale     float64  `json:"scale"`
-	BinSizes  []uint   `json:"binSizes"`
-	Tracks    []string `json:"tracks"`
+	Locations []string `json:"locations" form:"locations"`
+	Scale     float64  `json:"scale" form:"scale"`
+	BinSizes  []uint   `json:"binSizes" form:"binSizes"`
+	Tracks    []string `json:"tracks" form:"tracks"`
+	// regions as BED text, an alternative to locations
+	Bed string `json:"bed,omitempty" form:"bed"`
 }
 
 type SeqParams struct {
-	Locations []*dna.Location
-	Scale     float64
-	BinSizes  []uint
-	Tracks    []string
+	Regions  []*dnaroutes.Region
+	Scale    float64
+	BinSizes []uint
+	Tracks   []string
 }
 
 type SeqResp struct {
 	Location *dna.Location         `json:"location"`
+	Name     string                `json:"name,omitempty"`
+	Strand   string                `json:"strand,omitempty"`
 	Tracks   []*seq.TrackBinCounts `json:"tracks"`
 }
 
@@ -38,28 +48,40 @@ func ParseSeqParamsFromPost(c *gin.Context) (*SeqParams, error) {
 		return nil, err
 	}
 
-	locations := make([]*dna.Location, 0, len(params.Locations))
+	regions, err := dnaroutes.ParseRegions(c, params.Locations, params.Bed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// a single bin size applies to every region, which is
+	// easier for BED uploads
+	if len(params.BinSizes) == 1 && len(regions) > 1 {
+		binSize := params.BinSizes[0]
 
-	for _, loc := range params.Locations {
-		location, err := dna.ParseLocation(loc)
+		params.BinSizes = make([]uint, len(regions))
 
-		if err != nil {
-			return nil, err
+		for ri := range regions {
+			params.BinSizes[ri] = binSize
 		}
+	}
 
-		locations = append(locations, location)
+	if len(params.BinSizes) < len(regions) {
+		return nil, fmt.Errorf("%d bin sizes given for %d locations", len(params.BinSizes), len(regions))
 	}
 
 	return &SeqParams{
-			Locations: locations,
-			BinSizes:  params.BinSizes,
-			Tracks:    params.Tracks,
-			Scale:     params.Scale},
+			Regions:  regions,
+			BinSizes: params.BinSizes,
+			Tracks:   params.Tracks,
+			Scale:    params.Scale},
 		nil
 }
 
 func GenomeRoute(c *gin.Context) {
+	span := tracing.Start(c, "seqsdbcache.Genomes")
 	platforms, err := seqsdbcache.Genomes()
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -72,7 +94,9 @@ func GenomeRoute(c *gin.Context) {
 func PlatformRoute(c *gin.Context) {
 	genome := c.Param("assembly")
 
+	span := tracing.Start(c, "seqsdbcache.Platforms")
 	platforms, err := seqsdbcache.Platforms(genome)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -86,7 +110,9 @@ func TracksRoute(c *gin.Context) {
 	platform := c.Param("platform")
 	genome := c.Param("assembly")
 
+	span := tracing.Start(c, "seqsdbcache.Tracks")
 	tracks, err := seqsdbcache.Tracks(platform, genome)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -106,7 +132,9 @@ func SearchSeqRoute(c *gin.Context) {
 
 	query := c.Query("search")
 
+	span := tracing.Start(c, "seqsdbcache.Search")
 	tracks, err := seqsdbcache.Search(genome, query)
+	tracing.End(span, err)
 
 	if err != nil {
 		c.Error(err)
@@ -118,25 +146,32 @@ func SearchSeqRoute(c *gin.Context) {
 
 func BinsRoute(c *gin.Context) {
 
+	span := tracing.Start(c, "seqs.ParseSeqParamsFromPost")
 	params, err := ParseSeqParamsFromPost(c)
+	tracing.End(span, err)
 
 	if err != nil {
-		log.Debug().Msgf("err %s", err)
+		log.Ctx(c.Request.Context()).Debug().Msgf("err %s", err)
 		c.Error(err)
 		return
 	}
 
 	//log.Debug().Msgf("bin %v %v", params.Locations, params.BinSizes)
 
-	ret := make([]*SeqResp, 0, len(params.Locations)) //make([]*seq.BinCounts, 0, len(params.Tracks))
+	ret := make([]*SeqResp, 0, len(params.Regions)) //make([]*seq.BinCounts, 0, len(params.Tracks))
 
-	for li, location := range params.Locations {
-		resp := SeqResp{Location: location, Tracks: make([]*seq.TrackBinCounts, 0, len(params.Tracks))}
+	for li, region := range params.Regions {
+		resp := SeqResp{Location: region.Location,
+			Name:   region.Name,
+			Strand: region.Strand,
+			Tracks: make([]*seq.TrackBinCounts, 0, len(params.Tracks))}
 
 		for _, track := range params.Tracks {
+			span := tracing.Start(c, "seqsdbcache.ReaderFromId")
 			reader, err := seqsdbcache.ReaderFromId(track,
 				params.BinSizes[li],
 				params.Scale)
+			tracing.End(span, err)
 
 			if err != nil {
 				//log.Debug().Msgf("stupid err %s", err)
@@ -147,7 +182,9 @@ func BinsRoute(c *gin.Context) {
 			// guarantees something is returned even with error
 			// so we can ignore the errors for now to make the api
 			// more robus
-			trackBinCounts, _ := reader.TrackBinCounts(location)
+			span = tracing.Start(c, "reader.TrackBinCounts")
+			trackBinCounts, _ := reader.TrackBinCounts(region.Location)
+			tracing.End(span, nil)
 
 			// if err != nil {
 			// 	return web.ErrorReq(err)
@@ -157,9 +194,11 @@ func BinsRoute(c *gin.Context) {
 		}
 
 		ret = append(ret, &resp)
+
+		jobs.SetProgress(c, li+1, len(params.Regions))
 	}
 
 	//log.Debug().Msgf("ret %v", len(ret))
 
-	web.MakeDataResp(c, "", ret)
+	tabular.MakeResp(c, ret)
 }
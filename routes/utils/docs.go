@@ -0,0 +1,18 @@
+package utils
+
+import "github.com/antonybholmes/go-edb-server-gin/openapi"
+
+func init() {
+	openapi.Describe(XlsxSheetsRoute, &openapi.Doc{Summary: "List the sheets in an xlsx file",
+		Request:  XlsxReq{},
+		Response: XlsxSheetsResp{}})
+	openapi.Describe(XlsxToRoute, &openapi.Doc{Summary: "Convert an xlsx sheet to a table",
+		Request:  XlsxReq{},
+		Response: XlsxResp{}})
+	openapi.Describe(HashedPasswordRoute, &openapi.Doc{Summary: "Hash a password",
+		Response: HashResp{},
+		Query:    []*openapi.Param{openapi.QueryParam("password", "password to hash")}})
+	openapi.Describe(RandomKeyRoute, &openapi.Doc{Summary: "Generate a random key",
+		Response: KeyResp{},
+		Query:    []*openapi.Param{openapi.QueryParam("l", "key length")}})
+}
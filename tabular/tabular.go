@@ -0,0 +1,504 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/antonybholmes/go-web"
+	"github.com/gin-gonic/gin"
+)
+
+// Output formats for tabular responses
+const (
+	FORMAT_JSON   = "json"
+	FORMAT_TSV    = "tsv"
+	FORMAT_CSV    = "csv"
+	FORMAT_NDJSON = "ndjson"
+)
+
+const (
+	MIME_TSV    = "text/tab-separated-values"
+	MIME_CSV    = "text/csv"
+	MIME_NDJSON = "application/x-ndjson"
+)
+
+// separates the names of nested fields in column headers,
+// e.g. features.geneId
+const HEADER_SEP = "."
+
+// separates scalar list items within a cell
+const LIST_SEP = ","
+
+// Determine the output format from ?format= or, failing that, the
+// Accept header. Anything unrecognised is JSON.
+func ParseFormat(c *gin.Context) string {
+	switch strings.ToLower(c.Query("format")) {
+	case FORMAT_TSV, "tab", "text":
+		return FORMAT_TSV
+	case FORMAT_CSV:
+		return FORMAT_CSV
+	case FORMAT_NDJSON, "jsonl":
+		return FORMAT_NDJSON
+	case FORMAT_JSON:
+		return FORMAT_JSON
+	}
+
+	for _, accept := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case MIME_TSV, "text/tsv", "text/plain":
+			return FORMAT_TSV
+		case MIME_CSV:
+			return FORMAT_CSV
+		case MIME_NDJSON, "application/jsonl":
+			return FORMAT_NDJSON
+		case "application/json":
+			return FORMAT_JSON
+		}
+	}
+
+	return FORMAT_JSON
+}
+
+// Respond with data in the format the client asked for. JSON uses
+// the standard data envelope. NDJSON writes one line per item of
+// data. TSV and CSV flatten each item into rows; nested objects
+// become dotted columns and the first list of objects in an item
+// is expanded into one row per entry.
+func MakeResp(c *gin.Context, data any) {
+	switch ParseFormat(c) {
+	case FORMAT_TSV:
+		writeTable(c, data, '\t', MIME_TSV)
+	case FORMAT_CSV:
+		writeTable(c, data, ',', MIME_CSV)
+	case FORMAT_NDJSON:
+		WriteNDJSON(c, data)
+	default:
+		web.MakeDataResp(c, "", data)
+	}
+}
+
+// Stream each item of data as a line of JSON
+func WriteNDJSON(c *gin.Context, data any) {
+	c.Header("Content-Type", MIME_NDJSON)
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+
+	for _, item := range items(data) {
+		err := enc.Encode(item.Interface())
+
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+func writeTable(c *gin.Context, data any, comma rune, mimeType string) {
+	table := NewTable()
+
+	values := items(data)
+
+	for _, item := range values {
+		table.AddItem(item)
+	}
+
+	// no rows to take the headers from so use the type of what
+	// would have been listed
+	if len(values) == 0 && data != nil {
+		t := reflect.TypeOf(data)
+
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			t = t.Elem()
+		}
+
+		table.AddType(t)
+	}
+
+	c.Header("Content-Type", mimeType)
+	c.Status(http.StatusOK)
+
+	err := table.Write(c.Writer, comma)
+
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+// the top level entries of data, which is either a list or a
+// single item
+func items(data any) []reflect.Value {
+	v := deref(reflect.ValueOf(data))
+
+	if !v.IsValid() {
+		return []reflect.Value{}
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		ret := make([]reflect.Value, v.Len())
+
+		for i := range v.Len() {
+			ret[i] = v.Index(i)
+		}
+
+		return ret
+	}
+
+	return []reflect.Value{v}
+}
+
+type row map[string]string
+
+// Flattened rows with headers in the order they were first seen
+type Table struct {
+	Headers []string
+	Rows    []row
+	seen    map[string]struct{}
+}
+
+func NewTable() *Table {
+	return &Table{Headers: make([]string, 0, 20),
+		Rows: make([]row, 0, 100),
+		seen: make(map[string]struct{})}
+}
+
+func (table *Table) AddItem(item reflect.Value) {
+	table.Rows = append(table.Rows, table.flatten("", item)...)
+}
+
+// Add the columns an item of type t would have, without adding a
+// row for it
+func (table *Table) AddType(t reflect.Type) {
+	table.flatten("", sample(t, make(map[reflect.Type]struct{})))
+}
+
+func (table *Table) addHeader(header string) {
+	if _, ok := table.seen[header]; !ok {
+		table.seen[header] = struct{}{}
+		table.Headers = append(table.Headers, header)
+	}
+}
+
+func (table *Table) Write(w io.Writer, comma rune) error {
+	wtr := csv.NewWriter(w)
+	wtr.Comma = comma
+
+	err := wtr.Write(table.Headers)
+
+	if err != nil {
+		return err
+	}
+
+	for _, r := range table.Rows {
+		record := make([]string, len(table.Headers))
+
+		for i, header := range table.Headers {
+			record[i] = r[header]
+		}
+
+		err := wtr.Write(record)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	wtr.Flush()
+
+	return wtr.Error()
+}
+
+// list of objects to expand into multiple rows
+type expansion struct {
+	prefix string
+	items  reflect.Value
+}
+
+func (table *Table) flatten(prefix string, v reflect.Value) []row {
+	base := make(row)
+	var exp *expansion
+
+	table.walk(prefix, v, base, &exp)
+
+	if exp == nil || exp.items.Len() == 0 {
+		return []row{base}
+	}
+
+	ret := make([]row, 0, exp.items.Len())
+
+	for i := range exp.items.Len() {
+		for _, child := range table.flatten(exp.prefix, exp.items.Index(i)) {
+			r := make(row, len(base)+len(child))
+
+			for k, v := range base {
+				r[k] = v
+			}
+
+			for k, v := range child {
+				r[k] = v
+			}
+
+			ret = append(ret, r)
+		}
+	}
+
+	return ret
+}
+
+func (table *Table) set(r row, header string, value string) {
+	table.addHeader(header)
+	r[header] = value
+}
+
+func (table *Table) walk(prefix string, v reflect.Value, r row, exp **expansion) {
+	v = deref(v)
+
+	if !v.IsValid() {
+		table.set(r, header(prefix), "")
+		return
+	}
+
+	// types such as dna.Location know how to format themselves
+	if s, ok := stringer(v); ok {
+		table.set(r, header(prefix), s)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		table.walkStruct(prefix, v, r, exp)
+	case reflect.Map:
+		keys := v.MapKeys()
+
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+
+		for _, key := range keys {
+			table.walk(join(prefix, fmt.Sprint(key.Interface())), v.MapIndex(key), r, exp)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			table.set(r, header(prefix), string(v.Bytes()))
+			return
+		}
+
+		if isScalarList(v) {
+			values := make([]string, v.Len())
+
+			for i := range v.Len() {
+				values[i] = scalar(deref(v.Index(i)))
+			}
+
+			table.set(r, header(prefix), strings.Join(values, LIST_SEP))
+			return
+		}
+
+		if *exp == nil {
+			*exp = &expansion{prefix: prefix, items: v}
+			return
+		}
+
+		// only one list per item can be expanded, others are
+		// kept as json
+		buf, err := json.Marshal(v.Interface())
+
+		if err != nil {
+			table.set(r, header(prefix), "")
+			return
+		}
+
+		table.set(r, header(prefix), string(buf))
+	default:
+		table.set(r, header(prefix), scalar(v))
+	}
+}
+
+func (table *Table) walkStruct(prefix string, v reflect.Value, r row, exp **expansion) {
+	t := v.Type()
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			fv := deref(v.Field(i))
+
+			if fv.IsValid() && fv.Kind() == reflect.Struct {
+				table.walkStruct(prefix, fv, r, exp)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		table.walk(join(prefix, name), v.Field(i), r, exp)
+	}
+}
+
+// A value of type t with its pointers set and one entry in each list
+// of objects, so that flattening it finds every column. Types that
+// contain themselves, such as a feature with child features, are
+// only followed once.
+func sample(t reflect.Type, seen map[reflect.Type]struct{}) reflect.Value {
+	switch t.Kind() {
+	case reflect.Pointer:
+		if _, ok := seen[t.Elem()]; ok {
+			return reflect.Zero(t)
+		}
+
+		ret := reflect.New(t.Elem())
+		ret.Elem().Set(sample(t.Elem(), seen))
+
+		return ret
+	case reflect.Struct:
+		ret := reflect.New(t).Elem()
+
+		if _, ok := seen[t]; ok {
+			return ret
+		}
+
+		seen[t] = struct{}{}
+		defer delete(seen, t)
+
+		for i := range t.NumField() {
+			if ret.Field(i).CanSet() {
+				ret.Field(i).Set(sample(t.Field(i).Type, seen))
+			}
+		}
+
+		return ret
+	case reflect.Slice:
+		ret := reflect.MakeSlice(t, 0, 1)
+
+		if isScalarList(ret) {
+			return ret
+		}
+
+		return reflect.Append(ret, sample(t.Elem(), seen))
+	default:
+		return reflect.Zero(t)
+	}
+}
+
+func deref(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+
+		v = v.Elem()
+	}
+
+	return v
+}
+
+func stringer(v reflect.Value) (string, bool) {
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), true
+	}
+
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String(), true
+		}
+	}
+
+	return "", false
+}
+
+func isScalarList(v reflect.Value) bool {
+	t := v.Type().Elem()
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		// structs that format themselves are fine in a list
+		return t.Implements(reflect.TypeFor[fmt.Stringer]()) ||
+			reflect.PointerTo(t).Implements(reflect.TypeFor[fmt.Stringer]())
+	default:
+		return true
+	}
+}
+
+func scalar(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+
+	if s, ok := stringer(v); ok {
+		return s
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func join(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + HEADER_SEP + name
+}
+
+// a list of scalars at the top level has no name
+func header(prefix string) string {
+	if prefix == "" {
+		return "value"
+	}
+
+	return prefix
+}
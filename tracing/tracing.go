@@ -0,0 +1,240 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/antonybholmes/go-edb-server-gin/config"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const TRACER_NAME = "github.com/antonybholmes/go-edb-server-gin"
+
+// Each response carries the trace id so users can quote it
+// when reporting a problem
+const REQUEST_ID_HEADER = "X-Request-Id"
+
+const (
+	EXPORTER_OTLP   = "otlp"
+	EXPORTER_STDOUT = "stdout"
+	EXPORTER_FILE   = "file"
+	EXPORTER_NONE   = "none"
+)
+
+var tracer = otel.Tracer(TRACER_NAME)
+
+// Set up the global tracer provider for a service. The returned
+// function flushes any pending spans and should be called on shutdown.
+func Init(cfg *config.Config, name string, version string) (func(context.Context) error, error) {
+	res := resource.NewSchemaless(
+		attribute.String("service.name", name),
+		attribute.String("service.version", version))
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio)))}
+
+	var closer io.Closer
+
+	switch strings.ToLower(cfg.Tracing.Exporter) {
+	case EXPORTER_OTLP:
+		exporterOpts := []otlptracehttp.Option{}
+
+		if cfg.Tracing.Endpoint != "" {
+			exporterOpts = append(exporterOpts, otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint))
+		}
+
+		if cfg.Tracing.Insecure {
+			exporterOpts = append(exporterOpts, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err := otlptracehttp.New(context.Background(), exporterOpts...)
+
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case EXPORTER_STDOUT:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case EXPORTER_FILE:
+		file, err := os.OpenFile(cfg.Tracing.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+
+		if err != nil {
+			return nil, err
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		closer = file
+
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case EXPORTER_NONE, "":
+		// spans are still created so that requests get ids
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", cfg.Tracing.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+
+	otel.SetTracerProvider(provider)
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+
+		if closer != nil {
+			closer.Close()
+		}
+
+		return err
+	}, nil
+}
+
+// Start a span for each request, continuing any trace the caller
+// propagated. The request context carries the span and a logger
+// tagged with the trace id.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(),
+			propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx, span := tracer.Start(ctx,
+			c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("client.address", c.ClientIP())))
+
+		defer span.End()
+
+		traceId := span.SpanContext().TraceID().String()
+
+		c.Header(REQUEST_ID_HEADER, traceId)
+
+		logger := log.With().Str("trace_id", traceId).Logger()
+
+		c.Request = c.Request.WithContext(logger.WithContext(ctx))
+
+		c.Next()
+
+		status := c.Writer.Status()
+
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// Start a child span of the request span, e.g. around a call
+// into a dbcache. End it with End.
+func Start(c *gin.Context, name string) trace.Span {
+	_, span := tracer.Start(c.Request.Context(), name)
+
+	return span
+}
+
+// End a span, marking it as failed if err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Copy the trace of a request onto another request, e.g. a
+// job, so its spans belong to the same trace
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Log each request once it has been served. Lines carry the
+// request's trace id so they can be matched to its spans and to
+// the X-Request-Id the client saw. Use after Middleware.
+func AccessLogMiddleware(logger zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+
+		e := logger.Info()
+
+		if status >= http.StatusInternalServerError {
+			e = logger.Error()
+		}
+
+		e.Ctx(c.Request.Context()).
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Str("ip", c.ClientIP()).
+			Int("size", max(c.Writer.Size(), 0)).
+			Msg(c.Errors.ByType(gin.ErrorTypePrivate).String())
+	}
+}
+
+// Adds trace ids to log lines that carry a context, e.g.
+// log.Info().Ctx(ctx)
+type LogHook struct{}
+
+func (h LogHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	ctx := e.GetCtx()
+
+	if ctx == nil {
+		return
+	}
+
+	sc := trace.SpanContextFromContext(ctx)
+
+	if sc.IsValid() {
+		e.Str("trace_id", sc.TraceID().String()).Str("span_id", sc.SpanID().String())
+	}
+}
@@ -0,0 +1,265 @@
+package variants
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/antonybholmes/go-dna"
+)
+
+const (
+	TYPE_SNV = "snv"
+	TYPE_MNV = "mnv"
+	TYPE_INS = "ins"
+	TYPE_DEL = "del"
+	// a replacement of a different length
+	TYPE_INDEL = "indel"
+)
+
+const (
+	BLOCK_REF     = "ref"
+	BLOCK_VARIANT = "variant"
+)
+
+// A change to the reference. Start is the 1-based position of the
+// first reference base replaced. Variants are stored without the
+// padding base VCF uses for indels, so an insertion has no Ref and
+// goes before Start, and a deletion has no Alt.
+type Variant struct {
+	Chr    string `json:"chr"`
+	Start  uint   `json:"start"`
+	Ref    string `json:"ref"`
+	Alt    string `json:"alt"`
+	Type   string `json:"type"`
+	Id     string `json:"id,omitempty"`
+	Sample string `json:"sample,omitempty"`
+}
+
+type Skipped struct {
+	Variant *Variant `json:"variant"`
+	Reason  string   `json:"reason"`
+}
+
+// Maps a stretch of the reference to the alt sequence. Reference
+// coordinates are genomic and alt coordinates are 1-based offsets
+// into the alt sequence. Empty stretches, such as the alt side of a
+// deletion, have an end one less than their start.
+type Block struct {
+	Type     string `json:"type"`
+	RefStart uint   `json:"refStart"`
+	RefEnd   uint   `json:"refEnd"`
+	AltStart int    `json:"altStart"`
+	AltEnd   int    `json:"altEnd"`
+	// which variant, for variant blocks
+	Variant string `json:"variant,omitempty"`
+}
+
+type Haplotype struct {
+	Seq     string     `json:"seq"`
+	Applied []*Variant `json:"applied"`
+	Skipped []*Skipped `json:"skipped"`
+	Map     []*Block   `json:"map"`
+}
+
+// Make a variant, dropping the padding bases that ref and alt share
+// so that it describes only what changed
+func NewVariant(chr string, start uint, ref string, alt string) *Variant {
+	ref = strings.ToUpper(ref)
+	alt = strings.ToUpper(alt)
+
+	// MAF style empty alleles
+	if ref == "-" {
+		ref = ""
+	}
+
+	if alt == "-" {
+		alt = ""
+	}
+
+	for len(ref) > 0 && len(alt) > 0 && ref[len(ref)-1] == alt[len(alt)-1] {
+		ref = ref[0 : len(ref)-1]
+		alt = alt[0 : len(alt)-1]
+	}
+
+	for len(ref) > 0 && len(alt) > 0 && ref[0] == alt[0] {
+		ref = ref[1:]
+		alt = alt[1:]
+		start++
+	}
+
+	return &Variant{Chr: chr, Start: start, Ref: ref, Alt: alt, Type: variantType(ref, alt)}
+}
+
+func variantType(ref string, alt string) string {
+	switch {
+	case len(ref) == 0:
+		return TYPE_INS
+	case len(alt) == 0:
+		return TYPE_DEL
+	case len(ref) != len(alt):
+		return TYPE_INDEL
+	case len(ref) == 1:
+		return TYPE_SNV
+	default:
+		return TYPE_MNV
+	}
+}
+
+// Last reference base replaced, which is before Start for an
+// insertion
+func (variant *Variant) End() uint {
+	return variant.Start + uint(len(variant.Ref)) - 1
+}
+
+func (variant *Variant) String() string {
+	ref := variant.Ref
+	alt := variant.Alt
+
+	if ref == "" {
+		ref = "-"
+	}
+
+	if alt == "" {
+		alt = "-"
+	}
+
+	return fmt.Sprintf("%s:%d:%s>%s", variant.Chr, variant.Start, ref, alt)
+}
+
+// Apply variants to the + strand reference sequence of a location.
+// Variants outside the location, that overlap an earlier variant,
+// or whose ref does not match the reference, are skipped.
+func Apply(ref string, location *dna.Location, variants []*Variant) *Haplotype {
+	sorted := make([]*Variant, len(variants))
+	copy(sorted, variants)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Start != sorted[j].Start {
+			return sorted[i].Start < sorted[j].Start
+		}
+
+		// insertions go before the base at the same position
+		return sorted[i].Ref == "" && sorted[j].Ref != ""
+	})
+
+	ret := Haplotype{Applied: make([]*Variant, 0, len(sorted)),
+		Skipped: make([]*Skipped, 0, 10),
+		Map:     make([]*Block, 0, 2*len(sorted)+1)}
+
+	var seq strings.Builder
+
+	seq.Grow(len(ref))
+
+	// next 0-based offset into ref to copy from
+	p := 0
+	// the next variant must start at or after this position, so
+	// that an insertion and a change of the base after it can both
+	// be applied
+	next := location.Start
+
+	copyRef := func(end int) {
+		if end <= p {
+			return
+		}
+
+		altStart := seq.Len() + 1
+
+		seq.WriteString(ref[p:end])
+
+		ret.Map = append(ret.Map, &Block{Type: BLOCK_REF,
+			RefStart: location.Start + uint(p),
+			RefEnd:   location.Start + uint(end) - 1,
+			AltStart: altStart,
+			AltEnd:   seq.Len()})
+
+		p = end
+	}
+
+	skip := func(variant *Variant, reason string) {
+		ret.Skipped = append(ret.Skipped, &Skipped{Variant: variant, Reason: reason})
+	}
+
+	for _, variant := range sorted {
+		if variant.Ref == "" && variant.Alt == "" {
+			skip(variant, "no change")
+			continue
+		}
+
+		if variant.Chr != location.Chr ||
+			variant.Start < location.Start ||
+			variant.End() > location.End ||
+			(variant.Ref == "" && variant.Start > location.End) {
+			skip(variant, "outside location")
+			continue
+		}
+
+		if variant.Start < next {
+			skip(variant, "overlaps another variant")
+			continue
+		}
+
+		offset := int(variant.Start - location.Start)
+
+		if offset+len(variant.Ref) > len(ref) ||
+			!strings.EqualFold(ref[offset:offset+len(variant.Ref)], variant.Ref) {
+			skip(variant, "ref does not match the reference")
+			continue
+		}
+
+		copyRef(offset)
+
+		altStart := seq.Len() + 1
+
+		seq.WriteString(variant.Alt)
+
+		ret.Map = append(ret.Map, &Block{Type: BLOCK_VARIANT,
+			RefStart: variant.Start,
+			RefEnd:   variant.End(),
+			AltStart: altStart,
+			AltEnd:   seq.Len(),
+			Variant:  variant.String()})
+
+		p = offset + len(variant.Ref)
+		next = variant.Start + uint(len(variant.Ref))
+
+		ret.Applied = append(ret.Applied, variant)
+	}
+
+	copyRef(len(ref))
+
+	ret.Seq = seq.String()
+
+	return &ret
+}
+
+// Parse a variant written as chr:pos:ref>alt, e.g. chr7:140753336:A>T,
+// using - for an empty allele
+func ParseVariant(s string) (*Variant, error) {
+	tokens := strings.Split(strings.TrimSpace(s), ":")
+
+	if len(tokens) != 3 {
+		return nil, fmt.Errorf("%s is not a variant of the form chr:pos:ref>alt", s)
+	}
+
+	alleles := strings.Split(tokens[2], ">")
+
+	if len(alleles) != 2 {
+		return nil, fmt.Errorf("%s is not a variant of the form chr:pos:ref>alt", s)
+	}
+
+	pos, err := strconv.ParseUint(tokens[1], 10, 0)
+
+	if err != nil || pos < 1 {
+		return nil, fmt.Errorf("%s is an invalid position", tokens[1])
+	}
+
+	for _, allele := range alleles {
+		if strings.Trim(strings.ToUpper(allele), "ACGTN-") != "" {
+			return nil, fmt.Errorf("%s is an invalid allele", allele)
+		}
+	}
+
+	return NewVariant(tokens[0], uint(pos), alleles[0], alleles[1]), nil
+}
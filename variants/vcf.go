@@ -0,0 +1,176 @@
+package variants
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// VCF columns before the samples
+const VCF_FIXED_COLS = 9
+
+// Parse the records of a VCF. Only the first 5 columns are needed
+// and the header is optional. If a sample is given and the VCF has
+// genotypes, only the alt alleles of the sample are returned,
+// otherwise every alt allele is. Symbolic alleles such as <DEL> are
+// not supported.
+func ParseVCF(r io.Reader, sample string) ([]*Variant, error) {
+	ret := make([]*Variant, 0, 100)
+
+	// column of the sample, once the header has been seen
+	col := -1
+
+	scanner := bufio.NewScanner(r)
+
+	// INFO columns can be long
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		if strings.HasPrefix(text, "##") || strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		tokens := strings.Split(text, "\t")
+
+		if strings.HasPrefix(text, "#") {
+			// sites only VCFs have no genotypes to filter by
+			if sample != "" && len(tokens) > VCF_FIXED_COLS {
+				for i := VCF_FIXED_COLS; i < len(tokens); i++ {
+					if tokens[i] == sample {
+						col = i
+					}
+				}
+
+				if col == -1 {
+					return nil, fmt.Errorf("sample %s is not in the VCF", sample)
+				}
+			}
+
+			continue
+		}
+
+		if len(tokens) < 5 {
+			return nil, fmt.Errorf("line %d: a VCF record needs at least 5 columns", line)
+		}
+
+		pos, err := strconv.ParseUint(tokens[1], 10, 0)
+
+		if err != nil || pos < 1 {
+			return nil, fmt.Errorf("line %d: %s is an invalid position", line, tokens[1])
+		}
+
+		alts := strings.Split(tokens[4], ",")
+
+		// 1-based indexes of the alt alleles to apply
+		alleles := make([]int, 0, len(alts))
+
+		if col != -1 {
+			if col >= len(tokens) {
+				return nil, fmt.Errorf("line %d: missing sample column", line)
+			}
+
+			alleles, err = genotypeAlleles(tokens[8], tokens[col])
+
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", line, err)
+			}
+		} else {
+			for i := range alts {
+				alleles = append(alleles, i+1)
+			}
+		}
+
+		for _, allele := range alleles {
+			if allele > len(alts) {
+				return nil, fmt.Errorf("line %d: genotype refers to a missing alt allele", line)
+			}
+
+			alt := alts[allele-1]
+
+			// no call or a symbolic allele
+			if alt == "." || alt == "*" || strings.ContainsAny(alt, "<>[]") {
+				continue
+			}
+
+			variant := NewVariant(tokens[0], uint(pos), tokens[3], alt)
+
+			if tokens[2] != "." {
+				variant.Id = tokens[2]
+			}
+
+			if col != -1 {
+				variant.Sample = sample
+			}
+
+			ret = append(ret, variant)
+		}
+	}
+
+	err := scanner.Err()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Distinct alt alleles in a genotype, e.g. 0|1 gives 1. Phasing is
+// ignored since a haplotype is made from all of a sample's alleles.
+func genotypeAlleles(format string, values string) ([]int, error) {
+	gt := -1
+
+	for i, key := range strings.Split(format, ":") {
+		if key == "GT" {
+			gt = i
+			break
+		}
+	}
+
+	if gt == -1 {
+		return nil, fmt.Errorf("no GT field for the sample")
+	}
+
+	fields := strings.Split(values, ":")
+
+	if gt >= len(fields) {
+		return []int{}, nil
+	}
+
+	ret := make([]int, 0, 2)
+
+	seen := make(map[int]struct{}, 2)
+
+	for _, a := range strings.FieldsFunc(fields[gt], func(r rune) bool { return r == '/' || r == '|' }) {
+		if a == "." {
+			continue
+		}
+
+		allele, err := strconv.Atoi(a)
+
+		if err != nil || allele < 0 {
+			return nil, fmt.Errorf("%s is an invalid genotype", fields[gt])
+		}
+
+		if allele == 0 {
+			continue
+		}
+
+		_, ok := seen[allele]
+
+		if !ok {
+			seen[allele] = struct{}{}
+			ret = append(ret, allele)
+		}
+	}
+
+	return ret, nil
+}